@@ -11,23 +11,93 @@ import (
     "time"
 )
 
+// Template field DB sources (-use-field-db)
+const (
+    FIELD_DB_BUILTIN = "builtin" // Embedded field DB (overridden by site field DB file if it is set)
+    FIELD_DB_FILE    = "file"    // Site field DB file only
+    FIELD_DB_NONE    = "none"    // No field DB (types are detected by template field flags and length)
+)
+
+var FieldDBSources = []string{FIELD_DB_BUILTIN, FIELD_DB_FILE, FIELD_DB_NONE}
+
 type Options struct {
-    RACFFile   string
-    logFile    string
-    DumpFile   string
-    SqlFile    string
-    UseFieldDB bool
+    RACFFile       string
+    logFile        string
+    DumpFile       string
+    SqlFile        string
+    ReportFile     string
+    KeytabFile     string
+    UseFieldDB     string
+    FieldDBFile    string
+    JsonFile       string
+    JsonlFile      string
+    JsonSchemaDir  string
+    CsvPath        string
+    UnloadFile     string
+    CypherFile     string
+    Neo4jURI       string
+    Neo4jUser      string
+    GraphMLFile    string
+    DotFile        string
+    GraphFrom      string
+    GraphHops      int
+    GraphEdges     string
+    BloodHoundFile string
+    HtmlFile       string
+    XlsxFile       string
+    CommandsFile   string
+    ListFile       string
+    ListProfiles   string
+    ElasticFile    string
+    ElasticTmpl    string
+    ElasticIndex   string
+    PostgresFile   string
+    TemplatesFile  string
 }
 
 func (o *Options) Check() error {
     if len(o.RACFFile) == 0 {
         return fmt.Errorf("RACF DB file must be set")
-    } else if len(o.DumpFile) == 0 && len(o.SqlFile) == 0 {
-        return fmt.Errorf("Need to set output format and filename (-dump|-sql)")
+    } else if len(o.DumpFile) == 0 && len(o.SqlFile) == 0 && len(o.ReportFile) == 0 && len(o.KeytabFile) == 0 &&
+        len(o.JsonFile) == 0 && len(o.JsonlFile) == 0 && len(o.JsonSchemaDir) == 0 && len(o.CsvPath) == 0 &&
+        len(o.UnloadFile) == 0 && len(o.CypherFile) == 0 && len(o.Neo4jURI) == 0 &&
+        len(o.GraphMLFile) == 0 && len(o.DotFile) == 0 && len(o.BloodHoundFile) == 0 && len(o.HtmlFile) == 0 &&
+        len(o.XlsxFile) == 0 && len(o.CommandsFile) == 0 && len(o.ListFile) == 0 &&
+        len(o.ElasticFile) == 0 && len(o.ElasticTmpl) == 0 && len(o.PostgresFile) == 0 &&
+        len(o.TemplatesFile) == 0 {
+        return fmt.Errorf("Need to set output format and filename (-dump|-sql|-report|-keytab|-json|-jsonl|-json-schema|-csv|-unload|-cypher|-neo4j|-graphml|-dot|-bloodhound|-html|-xlsx|-commands|-list|-elastic|-elastic-template|-postgres|-templates)")
+    } else if countStdout(o.JsonFile, o.JsonlFile, o.CypherFile, o.GraphMLFile, o.DotFile, o.BloodHoundFile, o.HtmlFile, o.XlsxFile, o.CommandsFile, o.ListFile,
+        o.ElasticFile, o.ElasticTmpl, o.PostgresFile, o.TemplatesFile) > 1 {
+        return fmt.Errorf("Only one output can be written to stdout")
+    } else if o.GraphHops < 0 {
+        return fmt.Errorf("Number of graph hops must not be negative")
+    } else if len(o.ElasticIndex) == 0 || o.ElasticIndex != strings.ToLower(o.ElasticIndex) {
+        return fmt.Errorf("Index prefix must be set in lowercase (-elastic-index)")
+    }
+
+    validSource := false
+    for _, source := range FieldDBSources {
+        validSource = validSource || o.UseFieldDB == source
+    }
+    if !validSource {
+        return fmt.Errorf("Unknown field DB source %q (must be one of %s)", o.UseFieldDB, strings.Join(FieldDBSources, ", "))
+    } else if o.UseFieldDB == FIELD_DB_FILE && len(o.FieldDBFile) == 0 {
+        return fmt.Errorf("Field DB file must be set (-field-db) for field DB source %q", o.UseFieldDB)
     }
     return nil
 }
 
+// Get number of outputs written to stdout ("-")
+func countStdout(fileNames ...string) int {
+    retVal := 0
+    for _, name := range fileNames {
+        if name == "-" {
+            retVal++
+        }
+    }
+    return retVal
+}
+
 func (o *Options) Logger() error {
     var w io.Writer
     var f *os.File
@@ -50,8 +120,14 @@ func (o *Options) Logger() error {
         w = io.Discard
     }
 
-    // Logging INFO and ERROR message both into console and log file
-    mw := io.MultiWriter(os.Stdout, w)
+    // Logging INFO and ERROR message both into console and log file.
+    // Console messages go to stderr if stdout is used as output
+    console := io.Writer(os.Stdout)
+    if countStdout(o.JsonFile, o.JsonlFile, o.CypherFile, o.GraphMLFile, o.DotFile, o.BloodHoundFile, o.HtmlFile, o.XlsxFile, o.CommandsFile, o.ListFile,
+        o.ElasticFile, o.ElasticTmpl, o.PostgresFile, o.TemplatesFile) > 0 {
+        console = os.Stderr
+    }
+    mw := io.MultiWriter(console, w)
 
     Log = &Logger{
         log.New(mw, "INFO: ", 0),
@@ -73,13 +149,46 @@ func ParseFlags() {
         fmt.Fprintf(os.Stderr, "Examples:\n")
         fmt.Fprintf(os.Stderr, "  %s -f <RACF_DB> -sql <sqlite3.db>\n\textract RACF DB content to sqlite3 DB\n", os.Args[0])
         fmt.Fprintf(os.Stderr, "  %s -f <RACF_DB> -log <logfile> -dump <dump.txt> \n\textract RACF DB content to plain text file and save warning and debug info to log file\n", os.Args[0])
+        fmt.Fprintf(os.Stderr, "  %s -f <RACF_DB> -report <report.txt> -keytab <racf.keytab>\n\tsave security analysis report and export Kerberos keys as keytab\n", os.Args[0])
+        fmt.Fprintf(os.Stderr, "  %s -f <RACF_DB> -jsonl - -json-schema <schema_dir> | jq .\n\tprint RACF DB content as JSON Lines and save JSON Schema of templates\n", os.Args[0])
+        fmt.Fprintf(os.Stderr, "  %s -f <RACF_DB> -csv <racfdb.zip>\n\textract RACF DB content to CSV files in zip archive\n", os.Args[0])
+        fmt.Fprintf(os.Stderr, "  %s -f <RACF_DB> -dot - -graph-from <USERID> -graph-hops 2 | dot -Tsvg -o access.svg\n\tdraw groups and resources reachable from the user within 2 hops\n", os.Args[0])
+        fmt.Fprintf(os.Stderr, "  %s -f <RACF_DB> -cypher <racf.cypher> -neo4j bolt://localhost:7687\n\tsave graph of users, groups and resources as Cypher script and push it to Neo4j (password from NEO4J_PASSWORD)\n", os.Args[0])
+        fmt.Fprintf(os.Stderr, "  %s -f <RACF_DB> -list - -list-profiles USER:IBMUSER,SYS1.**\n\tprint LISTUSER output of IBMUSER and LISTDSD output of SYS1 data set profiles\n", os.Args[0])
     }
 
     flag.StringVar(&Opt.RACFFile, "f", "", "input RACF DB file")
     flag.StringVar(&Opt.logFile, "log", "", "save debug and warning info to log file")
     flag.StringVar(&Opt.DumpFile, "dump", "", "dump RACF DB as plain text")
     flag.StringVar(&Opt.SqlFile, "sql", "", "convert RACF DB to sqlite3 DB")
-    flag.BoolVar(&Opt.UseFieldDB, "use-field-db", true, "use template field DB from IBM official site (https://www.ibm.com/docs/en/zos/2.4.0?topic=definitions-group-template-racf-database)")
+    flag.StringVar(&Opt.PostgresFile, "postgres", "", "save RACF DB as PostgreSQL dump with the same tables as sqlite3 DB (- for stdout)")
+    flag.StringVar(&Opt.ReportFile, "report", "", "save security analysis report as plain text")
+    flag.StringVar(&Opt.KeytabFile, "keytab", "", "export Kerberos keys from KERB segments as keytab file (for authorized cross-realm assessment)")
+    flag.StringVar(&Opt.JsonFile, "json", "", "save RACF DB as JSON array of profiles (- for stdout)")
+    flag.StringVar(&Opt.JsonlFile, "jsonl", "", "save RACF DB as JSON Lines, one profile per line (- for stdout)")
+    flag.StringVar(&Opt.JsonSchemaDir, "json-schema", "", "save JSON Schema of each RACF template into directory")
+    flag.StringVar(&Opt.CsvPath, "csv", "", "save RACF DB as CSV files (one per profile type, segment and repeat group) with manifest into directory or zip archive (*.zip)")
+    flag.StringVar(&Opt.UnloadFile, "unload", "", "save RACF DB as IRRDBU00-style unload file (group, user, connect, data set, general resource and access records)")
+    flag.StringVar(&Opt.CypherFile, "cypher", "", "save graph of users, groups, data sets and general resources as Cypher script for Neo4j (- for stdout)")
+    flag.StringVar(&Opt.Neo4jURI, "neo4j", "", "push graph of users, groups, data sets and general resources to Neo4j over Bolt (e.x. bolt://localhost:7687)")
+    flag.StringVar(&Opt.Neo4jUser, "neo4j-user", "neo4j", "Neo4j user (password is taken from NEO4J_PASSWORD environment variable)")
+    flag.StringVar(&Opt.GraphMLFile, "graphml", "", "save graph of users, groups, data sets and general resources as GraphML file (- for stdout)")
+    flag.StringVar(&Opt.DotFile, "dot", "", "save graph of users, groups, data sets and general resources as Graphviz DOT file (- for stdout)")
+    flag.StringVar(&Opt.GraphFrom, "graph-from", "", "keep only the part of GraphML/DOT graph reachable from the user or group ID (see -graph-hops)")
+    flag.IntVar(&Opt.GraphHops, "graph-hops", 3, "maximum number of hops from -graph-from user or group")
+    flag.StringVar(&Opt.GraphEdges, "graph-edges", "", "keep only listed edge types in GraphML/DOT graph (comma separated: MEMBER_OF,OWNS,HAS_ACCESS,SUPGROUP_OF,SURROGATE_FOR)")
+    flag.StringVar(&Opt.BloodHoundFile, "bloodhound", "", "save users, groups, connects, ownership, attributes, SURROGAT permissions and access to sensitive resources as BloodHound OpenGraph JSON (- for stdout)")
+    flag.StringVar(&Opt.HtmlFile, "html", "", "save SETROPTS summary, privileged users and profiles with decoded fields and raw data as self-contained HTML report (credentials are redacted, - for stdout)")
+    flag.StringVar(&Opt.XlsxFile, "xlsx", "", "save runtime DB as XLSX workbook (one worksheet per profile type and segment and per RepeatGroup, - for stdout)")
+    flag.StringVar(&Opt.CommandsFile, "commands", "", "save RACF commands recreating groups, users, connects, profiles, access lists and SETROPTS options (secrets are replaced by placeholders, - for stdout)")
+    flag.StringVar(&Opt.ListFile, "list", "", "save profiles as output of RACF LISTUSER, LISTGRP, LISTDSD and RLIST commands with all segments (- for stdout)")
+    flag.StringVar(&Opt.ListProfiles, "list-profiles", "", "list only selected profiles (comma separated [USER:|GROUP:|DATASET:|<CLASS>:]<NAME>, NAME can be generic, e.x. USER:IBMUSER,FACILITY:BPX.**)")
+    flag.StringVar(&Opt.ElasticFile, "elastic", "", "save users, groups, profiles and access list entries as Elasticsearch/OpenSearch bulk API NDJSON with ECS user.* and group.* fields (- for stdout)")
+    flag.StringVar(&Opt.ElasticTmpl, "elastic-template", "", "save index template of -elastic indices (body of PUT _index_template/<index prefix>, - for stdout)")
+    flag.StringVar(&Opt.ElasticIndex, "elastic-index", "racf", "prefix of -elastic index names (<prefix>-users, <prefix>-groups, <prefix>-resources, <prefix>-access)")
+    flag.StringVar(&Opt.TemplatesFile, "templates", "", "save template, segment and field definitions (FDT) with decoded flags and Go types as JSON (- for stdout; also saved in table TEMPLATE_FIELDS of sqlite3 DB)")
+    flag.StringVar(&Opt.UseFieldDB, "use-field-db", FIELD_DB_BUILTIN, "template field DB source: builtin - DB from IBM official site (https://www.ibm.com/docs/en/zos/2.4.0?topic=definitions-group-template-racf-database) overridden by -field-db file if it is set; file - only -field-db file; none - detect field types by template flags")
+    flag.StringVar(&Opt.FieldDBFile, "field-db", "", "site template field DB file (CSV: template,segment,field,type,release)")
 
     flag.Parse()
 }
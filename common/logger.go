@@ -3,6 +3,7 @@ package common
 import (
     "flag"
     "fmt"
+    "io"
     "log"
     "os"
 )
@@ -15,6 +16,11 @@ type Logger struct {
     f       *os.File
 }
 
+// Create logger writing messages of all levels to w
+func NewLogger(w io.Writer) *Logger {
+    return &Logger{log.New(w, "INFO: ", 0), log.New(w, "DEBUG: ", 0), log.New(w, "WARNING: ", 0), log.New(w, "ERROR: ", 0), nil}
+}
+
 func (l *Logger) Info(format string, a ...any) {
     l.info.Printf(format, a...)
 }
@@ -0,0 +1,194 @@
+package db
+
+import (
+    "fmt"
+    "regexp"
+    "strings"
+)
+
+// RACF access levels in ascending order
+var accessLevels = []string{"NONE", "EXECUTE", "READ", "UPDATE", "CONTROL", "ALTER"}
+
+// Get RACF access level name from access byte (USERACS, UACC, UNIVACS and similar fields)
+func AccessName(b byte) string {
+    switch {
+    case b&0x80 != 0:
+        return "ALTER"
+    case b&0x40 != 0:
+        return "CONTROL"
+    case b&0x20 != 0:
+        return "UPDATE"
+    case b&0x10 != 0:
+        return "READ"
+    case b&0x08 != 0:
+        return "EXECUTE"
+    }
+    return "NONE"
+}
+
+// Get position of access level name in ascending order (-1 for unknown level)
+func AccessLevel(name string) int {
+    for i, l := range accessLevels {
+        if l == name {
+            return i
+        }
+    }
+    return -1
+}
+
+// Get group authority of the member from USERACS of GROUP profile
+func GroupAuthority(b []byte) string {
+    switch {
+    case len(b) == 0:
+        return "USE"
+    case b[0]&0x80 != 0:
+        return "JOIN"
+    case b[0]&0x40 != 0:
+        return "CONNECT"
+    case b[0]&0x20 != 0:
+        return "CREATE"
+    }
+    return "USE"
+}
+
+type ACLEntry struct {
+    ID     string
+    Access string
+    Count  uint64
+}
+
+// Get standard access list of profile (ACLCNT repeat group of BASE segment)
+func (p *Profile) ACL() []ACLEntry {
+    retVal := make([]ACLEntry, 0)
+    s, ok := p.Segment("BASE")
+    if !ok {
+        return retVal
+    }
+    rg, ok := s.Field("ACLCNT_RG")
+    if !ok {
+        return retVal
+    }
+    for i := 0; i < rg.Len(); i++ {
+        item := Segment{Data: rg.Index(i)}
+        var access string
+        if b := item.FieldBytes("USERACS"); len(b) > 0 {
+            access = AccessName(b[0])
+        }
+        retVal = append(retVal, ACLEntry{item.FieldString("USERID"), access, item.FieldUint("ACSCNT")})
+    }
+    return retVal
+}
+
+// Conditional access list entry. Condition is the WHEN operand of PERMIT command (e.g. WHEN(CONSOLE(MASTER)))
+type ConditionalACLEntry struct {
+    ACLEntry
+    EntityClass string // PROGRAM, CONSOLE, TERMINAL, JESINPUT, SYSID, APPCPORT, SERVAUTH or CRITERIA
+    Entity      string
+    Condition   string
+}
+
+// Classes of conditional access entities
+var conditionalClasses = map[string]bool{"PROGRAM": true, "CONSOLE": true, "TERMINAL": true, "JESINPUT": true,
+    "SYSID": true, "APPCPORT": true, "SERVAUTH": true, "CRITERIA": true}
+
+// Get class and name of conditional access entity. The name field (PROGRAM of data set profiles, ACL2NAME of
+// general resource profiles) is a program name unless it is an entity class. Entities of other classes are kept
+// in the variable part of the entry (ACL2VAR): the entity name if the class is in the name field, otherwise
+// 8-byte class followed by the entity name
+func conditionalEntity(name string, variable string) (string, string) {
+    if conditionalClasses[name] && len(variable) > 0 {
+        return name, variable
+    }
+    if len(variable) > 8 && conditionalClasses[strings.TrimSpace(variable[:8])] {
+        return strings.TrimSpace(variable[:8]), strings.TrimSpace(variable[8:])
+    }
+    return "PROGRAM", name
+}
+
+// Get conditional access list of profile (ACL2CNT repeat group of BASE segment)
+func (p *Profile) ConditionalACL() []ConditionalACLEntry {
+    retVal := make([]ConditionalACLEntry, 0)
+    s, ok := p.Segment("BASE")
+    if !ok {
+        return retVal
+    }
+    for _, item := range s.Items("ACL2CNT") {
+        var e ConditionalACLEntry
+        var b []byte
+        var name string
+        if p.Type.Name == "DATASET" {
+            e.ID, e.Count, b = item.FieldString("USER2ACS"), item.FieldUint("PACSCNT"), item.FieldBytes("PROGACS")
+            name = item.FieldString("PROGRAM")
+        } else {
+            e.ID, e.Count, b = item.FieldString("ACL2UID"), item.FieldUint("ACL2ACNT"), item.FieldBytes("ACL2ACC")
+            name = item.FieldString("ACL2NAME")
+        }
+        e.EntityClass, e.Entity = conditionalEntity(name, item.FieldString("ACL2VAR"))
+        e.Condition = fmt.Sprintf("WHEN(%s(%s))", e.EntityClass, e.Entity)
+        if len(b) > 0 {
+            e.Access = AccessName(b[0])
+        }
+        retVal = append(retVal, e)
+    }
+    return retVal
+}
+
+// Get universal access of profile (UACC of general resource profiles and UNIVACS of data set profiles)
+func (p *Profile) UACC() string {
+    s, ok := p.Segment("BASE")
+    if !ok {
+        return ""
+    }
+    for _, name := range []string{"UACC", "UNIVACS"} {
+        if b := s.FieldBytes(name); len(b) > 0 {
+            return AccessName(b[0])
+        }
+    }
+    return ""
+}
+
+// RACF generic profile name compiled to regular expression. Patterns are compiled once,
+// so they can be matched against many names (see GenericMatch)
+type GenericPattern struct {
+    Pattern string
+    generic bool
+    re      *regexp.Regexp // Nil for discrete names and names which can't be compiled (e.g. invalid UTF-8)
+}
+
+// Compile RACF generic profile name ('%' - one character, '*' - characters within a qualifier, '**' - any qualifiers)
+func NewGenericPattern(pattern string) *GenericPattern {
+    retVal := &GenericPattern{Pattern: pattern, generic: strings.ContainsAny(pattern, "*%")}
+    if !retVal.generic {
+        return retVal
+    }
+    var expr string
+    for i := 0; i < len(pattern); i++ {
+        switch {
+        case strings.HasPrefix(pattern[i:], "**"):
+            expr += ".*"
+            i++
+        case pattern[i] == '*':
+            expr += "[^.]*"
+        case pattern[i] == '%':
+            expr += "[^.]"
+        default:
+            expr += regexp.QuoteMeta(pattern[i : i+1])
+        }
+    }
+    retVal.re, _ = regexp.Compile("^" + expr + "$")
+    return retVal
+}
+
+// Check if the pattern matches the name
+func (gp *GenericPattern) Match(name string) bool {
+    if !gp.generic {
+        return gp.Pattern == name
+    }
+    return gp.re != nil && gp.re.MatchString(name)
+}
+
+// Check if RACF generic profile name matches the name. The pattern is compiled on each call,
+// so NewGenericPattern should be used to match one pattern against many names
+func GenericMatch(pattern string, name string) bool {
+    return NewGenericPattern(pattern).Match(name)
+}
@@ -0,0 +1,43 @@
+package db
+
+import (
+    "reflect"
+    "testing"
+
+    "racfudit/decode"
+)
+
+func TestConditionalACL(t *testing.T) {
+    ds := NewProfile("SYS1.PARMLIB", "DATASET", 4)
+    testSegmentOf(ds, "BASE", testFields{{"ACL2CNT", []testFields{
+        {{"PROGRAM", "IEBCOPY"}, {"USER2ACS", "IBMUSER"}, {"PROGACS", decode.Flag{0x20}}, {"PACSCNT", uint16(3)}, {"ACL2VAR", ""}},
+        {{"PROGRAM", "CONSOLE"}, {"USER2ACS", "OPER"}, {"PROGACS", decode.Flag{0x10}}, {"PACSCNT", uint16(0)}, {"ACL2VAR", "MASTER"}},
+        {{"PROGRAM", ""}, {"USER2ACS", "SYS1"}, {"PROGACS", decode.Flag{0x80}}, {"PACSCNT", uint16(0)}, {"ACL2VAR", "TERMINALTERM01"}},
+    }}})
+    res := NewProfile("FACILITYBPX.SUPERUSER", "GENERAL", 5)
+    testSegmentOf(res, "BASE", testFields{{"ACL2CNT", []testFields{
+        {{"ACL2NAME", "BPXROOT"}, {"ACL2UID", "OMVSGRP"}, {"ACL2ACC", decode.Flag{0x10}}, {"ACL2ACNT", uint16(1)}, {"ACL2VAR", ""}},
+        {{"ACL2NAME", "SERVAUTH"}, {"ACL2UID", "WEB"}, {"ACL2ACC", decode.Flag{0x10}}, {"ACL2ACNT", uint16(0)}, {"ACL2VAR", "EZB.NETACCESS.SYS1.TCPIP.INTRANET"}},
+        {{"ACL2NAME", ""}, {"ACL2UID", "*"}, {"ACL2ACC", decode.Flag{0x08}}, {"ACL2ACNT", uint16(0)}, {"ACL2VAR", "JESINPUTRDR1"}},
+    }}})
+
+    for _, tc := range []struct {
+        p    *Profile
+        want []ConditionalACLEntry
+    }{
+        {ds, []ConditionalACLEntry{
+            {ACLEntry{"IBMUSER", "UPDATE", 3}, "PROGRAM", "IEBCOPY", "WHEN(PROGRAM(IEBCOPY))"},
+            {ACLEntry{"OPER", "READ", 0}, "CONSOLE", "MASTER", "WHEN(CONSOLE(MASTER))"},
+            {ACLEntry{"SYS1", "ALTER", 0}, "TERMINAL", "TERM01", "WHEN(TERMINAL(TERM01))"},
+        }},
+        {res, []ConditionalACLEntry{
+            {ACLEntry{"OMVSGRP", "READ", 1}, "PROGRAM", "BPXROOT", "WHEN(PROGRAM(BPXROOT))"},
+            {ACLEntry{"WEB", "READ", 0}, "SERVAUTH", "EZB.NETACCESS.SYS1.TCPIP.INTRANET", "WHEN(SERVAUTH(EZB.NETACCESS.SYS1.TCPIP.INTRANET))"},
+            {ACLEntry{"*", "EXECUTE", 0}, "JESINPUT", "RDR1", "WHEN(JESINPUT(RDR1))"},
+        }},
+    } {
+        if got := tc.p.ConditionalACL(); !reflect.DeepEqual(got, tc.want) {
+            t.Errorf("ConditionalACL() of %s =\n%+v\nwant\n%+v", tc.p.Name, got, tc.want)
+        }
+    }
+}
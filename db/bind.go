@@ -0,0 +1,104 @@
+package db
+
+import (
+    "bytes"
+    "fmt"
+    "sort"
+    "strings"
+
+    "racfudit/decode"
+)
+
+// Bind password protection methods (PROXY segment)
+const (
+    BIND_NONE      = "NONE"      // No bind password
+    BIND_MASKED    = "MASKED"    // Password is masked and can be recovered from RACF DB
+    BIND_ENCRYPTED = "ENCRYPTED" // Password is encrypted with a key (BINDPWKY is set)
+)
+
+type BindCredential struct {
+    Profile     string
+    Type        string
+    Class       string
+    LDAPHost    string
+    BindDN      string
+    Protection  string
+    Password    string // Masked or encrypted password in hex
+    PasswordKey string // Key data of encrypted password in hex
+}
+
+func (bc *BindCredential) String() string {
+    retVal := fmt.Sprintf("%s %s %s\n", bc.Type, bc.Class, bc.Profile)
+    retVal += fmt.Sprintf("\tLDAP host: %s\n\tBind DN: %s\n", bc.LDAPHost, bc.BindDN)
+    retVal += fmt.Sprintf("\tPassword protection: %s\n", bc.Protection)
+    if bc.Protection != BIND_NONE {
+        retVal += fmt.Sprintf("\tPassword: %s\n", bc.Password)
+    }
+    if bc.Protection == BIND_ENCRYPTED {
+        retVal += fmt.Sprintf("\tPassword key: %s\n", bc.PasswordKey)
+    }
+    return retVal
+}
+
+// Convert padded EBCDIC field (e.g. LDAPHOST, BINDDN) to string
+func bindFieldString(s *Segment, name string) string {
+    var str decode.EBCDICStr = s.FieldBytes(name)
+    return str.Trim()
+}
+
+// Extract LDAP bind credentials from USER and general resource (LDAPBIND class, IRR.PROXY.DEFAULTS) PROXY segments
+func ExtractBindCredentials(profiles []*Profile) []*BindCredential {
+    retVal := make([]*BindCredential, 0)
+    for _, p := range profiles {
+        s, ok := p.Segment("PROXY")
+        if !ok {
+            continue
+        }
+
+        bc := &BindCredential{
+            Profile:  p.ResourceName(),
+            Type:     p.Type.Name,
+            Class:    p.Class(),
+            LDAPHost: bindFieldString(s, "LDAPHOST"),
+            BindDN:   bindFieldString(s, "BINDDN"),
+        }
+        password := s.FieldBytes("BINDPW")
+        key := s.FieldBytes("BINDPWKY")
+        switch {
+        case len(bytes.Trim(password, "\x00")) == 0:
+            bc.Protection = BIND_NONE
+        case len(bytes.Trim(key, "\x00")) > 0:
+            bc.Protection = BIND_ENCRYPTED
+        default:
+            bc.Protection = BIND_MASKED
+        }
+        bc.Password = fmt.Sprintf("%x", password)
+        bc.PasswordKey = fmt.Sprintf("%x", key)
+
+        if bc.Protection == BIND_NONE && len(bc.BindDN) == 0 {
+            continue
+        }
+        retVal = append(retVal, bc)
+    }
+    return retVal
+}
+
+// Get plain text report about stored bind credentials
+func bindReport(profiles []*Profile) string {
+    credentials := ExtractBindCredentials(profiles)
+    exposed := make([]string, 0)
+    for _, bc := range credentials {
+        if bc.Protection == BIND_MASKED {
+            exposed = append(exposed, fmt.Sprintf("\t%s %s %s: %s (%s)\n", bc.Type, bc.Class, bc.Profile, bc.BindDN, bc.LDAPHost))
+        }
+    }
+    sort.Strings(exposed)
+
+    retVal := fmt.Sprintf("Profiles with bind credentials: %d\n", len(credentials))
+    retVal += fmt.Sprintf("Masked bind passwords (recoverable from RACF DB copy): %d\n%s", len(exposed), strings.Join(exposed, ""))
+    retVal += "Bind credential details:\n"
+    for _, bc := range credentials {
+        retVal += bc.String()
+    }
+    return retVal
+}
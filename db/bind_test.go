@@ -0,0 +1,100 @@
+package db
+
+import (
+    "path/filepath"
+    "reflect"
+    "strings"
+    "testing"
+
+    "racfudit/decode"
+)
+
+func testBindProfiles() []*Profile {
+    zeros := decode.HexStr(make([]byte, 8))
+    masked := NewProfile("LDAPUSR", "USER", 2)
+    testSegmentOf(masked, "PROXY", testFields{
+        {"LDAPHOST", "ldap://ldap.example.com:389   "},
+        {"BINDDN", "cn=racf,o=example   "},
+        {"BINDPW", decode.HexStr{0x01, 0x02, 0x03, 0x04, 0, 0, 0, 0}},
+        {"BINDPWKY", zeros},
+    })
+    encrypted := NewProfile("LDAPBINDSERVER1", "GENERAL", 5)
+    testSegmentOf(encrypted, "PROXY", testFields{
+        {"LDAPHOST", "ldaps://ldap.example.com"},
+        {"BINDDN", "cn=admin,o=example"},
+        {"BINDPW", decode.HexStr{0xa1, 0xa2, 0, 0, 0, 0, 0, 0}},
+        {"BINDPWKY", decode.HexStr{0, 0, 0, 0, 0, 0, 0, 0x0f}},
+    })
+    // Bind DN without password (anonymous bind isn't used)
+    none := NewProfile("FACILITYIRR.PROXY.DEFAULTS", "GENERAL", 5)
+    testSegmentOf(none, "PROXY", testFields{
+        {"LDAPHOST", "ldap://ldap.example.com"},
+        {"BINDDN", "cn=proxy,o=example"},
+        {"BINDPW", zeros},
+        {"BINDPWKY", zeros},
+    })
+    // Empty PROXY segment and profile without PROXY segment are skipped
+    empty := NewProfile("EMPTY", "USER", 2)
+    testSegmentOf(empty, "PROXY", testFields{{"LDAPHOST", "        "}, {"BINDDN", "        "}, {"BINDPW", zeros}, {"BINDPWKY", zeros}})
+    other := NewProfile("IBMUSER", "USER", 2)
+    testSegmentOf(other, "BASE", testFields{{"AUTHOR", "SYS1"}})
+    return []*Profile{masked, encrypted, none, empty, other}
+}
+
+var bindTestCredentials = []*BindCredential{
+    {"LDAPUSR", "USER", "", "ldap://ldap.example.com:389", "cn=racf,o=example", BIND_MASKED, "0102030400000000", "0000000000000000"},
+    {"SERVER1", "GENERAL", "LDAPBIND", "ldaps://ldap.example.com", "cn=admin,o=example", BIND_ENCRYPTED, "a1a2000000000000", "000000000000000f"},
+    {"IRR.PROXY.DEFAULTS", "GENERAL", "FACILITY", "ldap://ldap.example.com", "cn=proxy,o=example", BIND_NONE, "0000000000000000", "0000000000000000"},
+}
+
+func TestExtractBindCredentials(t *testing.T) {
+    if got := ExtractBindCredentials(testBindProfiles()); !reflect.DeepEqual(got, bindTestCredentials) {
+        t.Errorf("ExtractBindCredentials() =")
+        for _, bc := range got {
+            t.Errorf("\t%+v", *bc)
+        }
+        t.Errorf("want")
+        for _, bc := range bindTestCredentials {
+            t.Errorf("\t%+v", *bc)
+        }
+    }
+
+    // Only masked passwords are reported as recoverable
+    report := bindReport(testBindProfiles())
+    want := "Masked bind passwords (recoverable from RACF DB copy): 1\n\tUSER  LDAPUSR: cn=racf,o=example (ldap://ldap.example.com:389)\n"
+    if !strings.Contains(report, want) {
+        t.Errorf("bindReport() = %s, want masked passwords %s", report, want)
+    }
+}
+
+func TestSQLiteFillBindCredentials(t *testing.T) {
+    d, err := NewDBSQLite(filepath.Join(t.TempDir(), "racf.db"))
+    if err != nil {
+        t.Fatal(err)
+    }
+    defer d.Close()
+    if err := d.FillBindCredentials(testBindProfiles()); err != nil {
+        t.Fatal(err)
+    }
+
+    rows, err := d.db.Query(`SELECT "ProfileName", "ProfileType", "Class", "LDAPHost", "BindDN", "Protection", "BindPassword", "BindPasswordKey"
+        FROM BIND_CREDENTIALS ORDER BY id`)
+    if err != nil {
+        t.Fatal(err)
+    }
+    defer rows.Close()
+    got := make([]*BindCredential, 0)
+    for rows.Next() {
+        bc := &BindCredential{}
+        if err := rows.Scan(&bc.Profile, &bc.Type, &bc.Class, &bc.LDAPHost, &bc.BindDN, &bc.Protection, &bc.Password, &bc.PasswordKey); err != nil {
+            t.Fatal(err)
+        }
+        got = append(got, bc)
+    }
+    if !reflect.DeepEqual(got, bindTestCredentials) {
+        t.Errorf("BIND_CREDENTIALS has %d rows:", len(got))
+        for _, bc := range got {
+            t.Errorf("\t%+v", *bc)
+        }
+    }
+}
@@ -0,0 +1,230 @@
+package db
+
+import (
+    "encoding/json"
+    "fmt"
+    "strings"
+
+    "racfudit/common"
+)
+
+// Node kinds of BloodHound OpenGraph
+var bhNodeKinds = map[string]string{
+    NODE_USER:     "RACFUser",
+    NODE_GROUP:    "RACFGroup",
+    NODE_DATASET:  "RACFDataset",
+    NODE_RESOURCE: "RACFResource",
+}
+
+// Edge kinds of BloodHound OpenGraph (HAS_ACCESS edges are converted to kinds of access levels)
+var bhEdgeKinds = map[string]string{
+    EDGE_MEMBER_OF:     "RACFMemberOf",
+    EDGE_OWNS:          "RACFOwns",
+    EDGE_SUPGROUP_OF:   "RACFSuperiorGroupOf",
+    EDGE_SURROGATE_FOR: "RACFSurrogateFor",
+}
+
+var bhAccessKinds = map[string]string{
+    "EXECUTE": "RACFExecute",
+    "READ":    "RACFRead",
+    "UPDATE":  "RACFUpdate",
+    "CONTROL": "RACFControl",
+    "ALTER":   "RACFAlter",
+}
+
+// Kind of the node representing the whole RACF database (target of RACFSpecial, RACFOperations
+// and RACFAuditor edges of users with system-wide attributes)
+const BH_SYSTEM_KIND = "RACFSystem"
+
+// Kind of the node representing all RACF users. Every user is RACFIncludedIn it, and UACC and ID(*) access
+// to sensitive resources are exported as its access edges
+const BH_ALL_USERS_KIND = "RACFAllUsers"
+
+// Sensitive resources (CLASS:PATTERN). Access to data set and general resource profiles is exported only
+// for profiles covering these resources
+var bhSensitiveResources = []string{
+    "DATASET:SYS1.PARMLIB", "DATASET:SYS1.LINKLIB", "DATASET:SYS1.LPALIB", "DATASET:SYS1.NUCLEUS",
+    "DATASET:SYS1.PROCLIB", "DATASET:SYS1.UADS", "DATASET:SYS1.RACF*", "DATASET:SYS1.SVCLIB",
+    "DATASET:SYS1.CSSLIB", "DATASET:SYS1.SIEALNKE", "DATASET:SYS1.SIEAMIGE",
+    "FACILITY:BPX.SUPERUSER", "FACILITY:BPX.DAEMON", "FACILITY:BPX.SERVER", "FACILITY:BPX.FILEATTR.APF",
+    "FACILITY:BPX.FILEATTR.PROGCTL", "FACILITY:IRR.PASSWORD.RESET", "FACILITY:IRR.DIGTCERT.**",
+    "FACILITY:IRR.RADMIN.**", "FACILITY:STGADMIN.**", "FACILITY:IRR.PWRESET.**",
+    "UNIXPRIV:SUPERUSER.**", "UNIXPRIV:CHOWN.UNRESTRICTED",
+    "SURROGAT:**", "PTKTDATA:**", "OPERCMDS:**", "PROGRAM:IRRDBU00", "PROGRAM:ICHDSM00",
+}
+
+// Compiled patterns of sensitive resources
+type bhSensitivePattern struct {
+    class   string
+    pattern *GenericPattern
+}
+
+var bhSensitivePatterns = newBHSensitivePatterns()
+
+func newBHSensitivePatterns() []bhSensitivePattern {
+    retVal := make([]bhSensitivePattern, 0)
+    for _, r := range bhSensitiveResources {
+        parts := strings.SplitN(r, ":", 2)
+        retVal = append(retVal, bhSensitivePattern{parts[0], NewGenericPattern(parts[1])})
+    }
+    return retVal
+}
+
+// BloodHound OpenGraph JSON
+type BHOpenGraph struct {
+    Metadata BHMetadata `json:"metadata"`
+    Graph    struct {
+        Nodes []BHNode `json:"nodes"`
+        Edges []BHEdge `json:"edges"`
+    } `json:"graph"`
+}
+
+type BHMetadata struct {
+    SourceKind string `json:"source_kind"`
+}
+
+type BHNode struct {
+    ID         string                 `json:"id"`
+    Kinds      []string               `json:"kinds"`
+    Properties map[string]interface{} `json:"properties"`
+}
+
+type BHEdge struct {
+    Kind       string                 `json:"kind"`
+    Start      BHEndpoint             `json:"start"`
+    End        BHEndpoint             `json:"end"`
+    Properties map[string]interface{} `json:"properties"`
+}
+
+type BHEndpoint struct {
+    Value   string `json:"value"`
+    MatchBy string `json:"match_by"`
+}
+
+func bhID(id string) string {
+    return "RACF:" + id
+}
+
+func newBHEdge(kind string, from string, to string, props map[string]interface{}) BHEdge {
+    if props == nil {
+        props = make(map[string]interface{})
+    }
+    return BHEdge{kind, BHEndpoint{bhID(from), "id"}, BHEndpoint{bhID(to), "id"}, props}
+}
+
+// Check if data set or general resource node covers a sensitive resource
+func isSensitive(n *GraphNode) bool {
+    class := "DATASET"
+    if n.Label == NODE_RESOURCE {
+        class, _ = n.Properties["class"].(string)
+    } else if n.Label != NODE_DATASET {
+        return false
+    }
+    name := NewGenericPattern(n.Name)
+    for _, r := range bhSensitivePatterns {
+        if r.class == class && (r.pattern.Match(n.Name) || name.Match(r.pattern.Pattern)) {
+            return true
+        }
+    }
+    return false
+}
+
+// Convert RACF graph to BloodHound OpenGraph. Users and groups are exported with all their relationships,
+// data set and general resource profiles only if they cover sensitive resources. UACC and ID(*) access
+// of these profiles are edges of the node of all RACF users
+func NewBHOpenGraph(g *Graph) *BHOpenGraph {
+    retVal := &BHOpenGraph{Metadata: BHMetadata{SourceKind: "RACF"}}
+    retVal.Graph.Nodes = make([]BHNode, 0)
+    retVal.Graph.Edges = make([]BHEdge, 0)
+
+    systemID := "System:RACF"
+    retVal.Graph.Nodes = append(retVal.Graph.Nodes, BHNode{bhID(systemID), []string{BH_SYSTEM_KIND, "RACFBase"},
+        map[string]interface{}{"name": "RACF", "displayname": "RACF database"}})
+
+    allUsersID := "AllUsers:*"
+    retVal.Graph.Nodes = append(retVal.Graph.Nodes, BHNode{bhID(allUsersID), []string{BH_ALL_USERS_KIND, "RACFBase"},
+        map[string]interface{}{"name": "*", "displayname": "All RACF users"}})
+
+    included := map[string]bool{systemID: true, allUsersID: true}
+    for _, n := range g.Nodes {
+        if (n.Label == NODE_DATASET || n.Label == NODE_RESOURCE) && !isSensitive(n) {
+            continue
+        }
+        props := make(map[string]interface{})
+        for k, v := range n.Properties {
+            props[k] = v
+        }
+        props["displayname"] = n.Name
+        if n.Label == NODE_RESOURCE {
+            props["displayname"] = fmt.Sprintf("%s %s", n.Properties["class"], n.Name)
+        }
+        retVal.Graph.Nodes = append(retVal.Graph.Nodes, BHNode{bhID(n.ID), []string{bhNodeKinds[n.Label], "RACFBase"}, props})
+        included[n.ID] = true
+
+        switch n.Label {
+        case NODE_USER:
+            // System-wide attributes of users
+            for _, attr := range []string{"special", "operations", "auditor"} {
+                if v, ok := n.Properties[attr].(bool); ok && v {
+                    kind := "RACF" + strings.ToUpper(attr[:1]) + attr[1:]
+                    retVal.Graph.Edges = append(retVal.Graph.Edges, newBHEdge(kind, n.ID, systemID, nil))
+                }
+            }
+            retVal.Graph.Edges = append(retVal.Graph.Edges, newBHEdge("RACFIncludedIn", n.ID, allUsersID, nil))
+        case NODE_DATASET, NODE_RESOURCE:
+            // Access of all users by UACC and ID(*) access list entry
+            for _, a := range []struct {
+                source   string
+                property string
+            }{{"UACC", "uacc"}, {"ID(*)", "allUsersAccess"}} {
+                access, _ := n.Properties[a.property].(string)
+                if kind, ok := bhAccessKinds[access]; ok {
+                    retVal.Graph.Edges = append(retVal.Graph.Edges, newBHEdge(kind, allUsersID, n.ID,
+                        map[string]interface{}{"condition": "", "source": a.source}))
+                }
+            }
+        }
+    }
+
+    for _, e := range g.Edges {
+        if !included[e.From] || !included[e.To] {
+            continue
+        }
+        switch e.Type {
+        case EDGE_HAS_ACCESS:
+            access, _ := e.Properties["access"].(string)
+            if kind, ok := bhAccessKinds[access]; ok {
+                retVal.Graph.Edges = append(retVal.Graph.Edges, newBHEdge(kind, e.From, e.To, map[string]interface{}{"condition": e.Properties["condition"]}))
+            }
+        case EDGE_MEMBER_OF:
+            retVal.Graph.Edges = append(retVal.Graph.Edges, newBHEdge(bhEdgeKinds[e.Type], e.From, e.To, e.Properties))
+            // Group-level attributes give control over the group
+            if v, ok := e.Properties["special"].(bool); ok && v {
+                retVal.Graph.Edges = append(retVal.Graph.Edges, newBHEdge("RACFGroupSpecial", e.From, e.To, nil))
+            }
+            if v, ok := e.Properties["operations"].(bool); ok && v {
+                retVal.Graph.Edges = append(retVal.Graph.Edges, newBHEdge("RACFGroupOperations", e.From, e.To, nil))
+            }
+        default:
+            retVal.Graph.Edges = append(retVal.Graph.Edges, newBHEdge(bhEdgeKinds[e.Type], e.From, e.To, e.Properties))
+        }
+    }
+    return retVal
+}
+
+// Save RACF graph as BloodHound OpenGraph JSON file
+func ToBloodHound(g *Graph, fileName string) {
+    f, err := createOutput(fileName)
+    if err != nil {
+        common.Fatal(fmt.Errorf("Can not create BloodHound OpenGraph file: %v", err))
+    }
+    defer f.Close()
+
+    bh := NewBHOpenGraph(g)
+    common.Log.Info("Saving RACF graph (%d nodes, %d edges) as BloodHound OpenGraph file %s", len(bh.Graph.Nodes), len(bh.Graph.Edges), fileName)
+    enc := json.NewEncoder(f)
+    enc.SetIndent("", "  ")
+    if err := enc.Encode(bh); err != nil {
+        common.Fatal(fmt.Errorf("Can not save BloodHound OpenGraph file: %v", err))
+    }
+}
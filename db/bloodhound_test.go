@@ -0,0 +1,54 @@
+package db
+
+import (
+    "testing"
+)
+
+func TestBHOpenGraphAllUsersAccess(t *testing.T) {
+    g := &Graph{Nodes: make([]*GraphNode, 0), Edges: make([]*GraphEdge, 0), nodes: make(map[string]*GraphNode)}
+    g.addNode(NODE_USER, "IBMUSER", map[string]interface{}{})
+    g.addNode(NODE_DATASET, "SYS1.PARMLIB", map[string]interface{}{"uacc": "READ"})
+    g.addNode(NODE_RESOURCE, "BPX.SUPERUSER", map[string]interface{}{"class": "FACILITY", "uacc": "NONE", "allUsersAccess": "UPDATE"})
+    g.addNode(NODE_RESOURCE, "IRR.DIGTCERT.*", map[string]interface{}{"class": "FACILITY", "uacc": "NONE"})
+    // Not sensitive
+    g.addNode(NODE_DATASET, "USER1.**", map[string]interface{}{"uacc": "ALTER"})
+
+    bh := NewBHOpenGraph(g)
+    nodes := make(map[string][]string)
+    for _, n := range bh.Graph.Nodes {
+        nodes[n.ID] = n.Kinds
+    }
+    for id, want := range map[string]bool{
+        "RACF:AllUsers:*": true, "RACF:Dataset:SYS1.PARMLIB": true, "RACF:Resource:FACILITY:BPX.SUPERUSER": true,
+        "RACF:Resource:FACILITY:IRR.DIGTCERT.*": true, "RACF:Dataset:USER1.**": false,
+    } {
+        if _, ok := nodes[id]; ok != want {
+            t.Errorf("Node %s exported = %v, want %v", id, ok, want)
+        }
+    }
+    if kinds := nodes["RACF:AllUsers:*"]; len(kinds) == 0 || kinds[0] != BH_ALL_USERS_KIND {
+        t.Errorf("Kinds of all users node = %v", kinds)
+    }
+
+    got := make(map[string]bool)
+    for _, e := range bh.Graph.Edges {
+        got[e.Kind+" "+e.Start.Value+" -> "+e.End.Value+" "+toString(e.Properties["source"])] = true
+    }
+    for _, want := range []string{
+        "RACFIncludedIn RACF:User:IBMUSER -> RACF:AllUsers:* ",
+        "RACFRead RACF:AllUsers:* -> RACF:Dataset:SYS1.PARMLIB UACC",
+        "RACFUpdate RACF:AllUsers:* -> RACF:Resource:FACILITY:BPX.SUPERUSER ID(*)",
+    } {
+        if !got[want] {
+            t.Errorf("Edge %q is missing in %v", want, got)
+        }
+    }
+    if len(got) != 3 {
+        t.Errorf("Got %d edges, want 3: %v", len(got), got)
+    }
+}
+
+func toString(v interface{}) string {
+    s, _ := v.(string)
+    return s
+}
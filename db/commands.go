@@ -0,0 +1,815 @@
+package db
+
+import (
+    "bufio"
+    "bytes"
+    "fmt"
+    "io"
+    "sort"
+    "strings"
+
+    "racfudit/common"
+    "racfudit/decode"
+    "racfudit/sections"
+)
+
+// Placeholder of secret values (passwords, password phrases and keys) in generated commands
+const COMMAND_SECRET = "<SECRET>"
+
+// RACF command with operands. Positional operands (profile names) are written on the command line,
+// other operands are written one per line with TSO continuation
+type racfCommand struct {
+    name     string
+    args     []string
+    operands []string
+}
+
+func newCommand(name string, args ...string) *racfCommand {
+    return &racfCommand{name: name, args: args}
+}
+
+// Add operands to the command (empty operands are skipped)
+func (c *racfCommand) add(operands ...string) {
+    for _, op := range operands {
+        if len(op) > 0 {
+            c.operands = append(c.operands, op)
+        }
+    }
+}
+
+func (c *racfCommand) String() string {
+    retVal := strings.Join(append([]string{c.name}, c.args...), " ")
+    if len(c.operands) > 0 {
+        retVal += " -\n  " + strings.Join(c.operands, " -\n  ")
+    }
+    return retVal
+}
+
+// Quote value of command operand
+func cmdQuote(s string) string {
+    return "'" + strings.ReplaceAll(s, "'", "''") + "'"
+}
+
+// Quote resource name if it contains characters which aren't allowed in unquoted operand
+func cmdResource(s string) string {
+    if strings.ContainsAny(s, " ()',;") {
+        return cmdQuote(s)
+    }
+    return s
+}
+
+// Operand of segment keyword produced from segment fields (empty string means that the operand is omitted)
+type commandOperand func(s *Segment) string
+
+// Character field as quoted operand value
+func cmdStr(keyword string, field string) commandOperand {
+    return func(s *Segment) string {
+        if v := s.FieldString(field); len(v) > 0 {
+            return fmt.Sprintf("%s(%s)", keyword, cmdQuote(v))
+        }
+        return ""
+    }
+}
+
+// Character field as unquoted operand value (names, classes and numbers saved as characters)
+func cmdName(keyword string, field string) commandOperand {
+    return func(s *Segment) string {
+        if v := s.FieldString(field); len(v) > 0 {
+            return fmt.Sprintf("%s(%s)", keyword, v)
+        }
+        return ""
+    }
+}
+
+// Integer field (omitted if it is zero)
+func cmdNum(keyword string, field string) commandOperand {
+    return func(s *Segment) string {
+        if v := s.FieldUint(field); v != 0 {
+            return fmt.Sprintf("%s(%d)", keyword, v)
+        }
+        return ""
+    }
+}
+
+// Integer field (omitted only if there is no such field, e.g. UID and GID)
+func cmdID(keyword string, field string) commandOperand {
+    return func(s *Segment) string {
+        if _, ok := s.Field(field); ok {
+            return fmt.Sprintf("%s(%d)", keyword, s.FieldUint(field))
+        }
+        return ""
+    }
+}
+
+// Binary field as integer (big-endian)
+func cmdBinNum(keyword string, field string) commandOperand {
+    return func(s *Segment) string {
+        var v uint64
+        for _, b := range s.FieldBytes(field) {
+            v = v<<8 | uint64(b)
+        }
+        if v != 0 {
+            return fmt.Sprintf("%s(%d)", keyword, v)
+        }
+        return ""
+    }
+}
+
+// Binary field in hex
+func cmdHex(keyword string, field string) commandOperand {
+    return func(s *Segment) string {
+        if b := s.FieldBytes(field); len(b) > 0 {
+            return fmt.Sprintf("%s(%X)", keyword, b)
+        }
+        return ""
+    }
+}
+
+// Flag field as YES or NO operand value
+func cmdYesNo(keyword string, field string) commandOperand {
+    return func(s *Segment) string {
+        if _, ok := s.Field(field); !ok {
+            return ""
+        }
+        if isFlagSet(s, field, 0x80) {
+            return keyword + "(YES)"
+        }
+        return keyword + "(NO)"
+    }
+}
+
+// Flag field as one of two keywords
+func cmdChoice(set string, unset string, field string) commandOperand {
+    return func(s *Segment) string {
+        if _, ok := s.Field(field); !ok {
+            return ""
+        }
+        if isFlagSet(s, field, 0x80) {
+            return set
+        }
+        return unset
+    }
+}
+
+// Values of RepeatGroup field as list
+func cmdList(keyword string, rgName string, field string) commandOperand {
+    return func(s *Segment) string {
+        values := make([]string, 0)
+        for _, item := range s.Items(rgName) {
+            if v := item.FieldString(field); len(v) > 0 {
+                values = append(values, v)
+            }
+        }
+        if len(values) > 0 {
+            return fmt.Sprintf("%s(%s)", keyword, strings.Join(values, " "))
+        }
+        return ""
+    }
+}
+
+// Kerberos encryption types
+func cmdKerbEnc(keyword string, field string) commandOperand {
+    return func(s *Segment) string {
+        if v := s.FieldString(field); len(v) > 0 {
+            return fmt.Sprintf("%s(%s)", keyword, strings.ReplaceAll(v, ",", " "))
+        }
+        return ""
+    }
+}
+
+// Secret field is replaced by placeholder (omitted if the field isn't set)
+func cmdSecret(keyword string, field string) commandOperand {
+    return func(s *Segment) string {
+        if b := s.FieldBytes(field); len(bytes.Trim(b, "\x00\x40")) > 0 {
+            return fmt.Sprintf("%s(%s)", keyword, COMMAND_SECRET)
+        }
+        return ""
+    }
+}
+
+// Operands of segment keywords (profile type -> segment -> operands). BASE and CSDATA segments
+// are converted separately, segments which aren't listed here are reported in comments
+var commandSegments = map[string]map[string][]commandOperand{
+    "USER": {
+        "TSO": {
+            cmdStr("ACCTNUM", "TACCNT"), cmdStr("COMMAND", "TCOMMAND"), cmdName("DEST", "TDEST"),
+            cmdName("HOLDCLASS", "THCLASS"), cmdName("JOBCLASS", "TJCLASS"), cmdName("MSGCLASS", "TMCLASS"),
+            cmdName("SYSOUTCLASS", "TSCLASS"), cmdName("PROC", "TLPROC"), cmdNum("SIZE", "TLSIZE"),
+            cmdNum("MAXSIZE", "TMSIZE"), cmdNum("PERFORM", "TPERFORM"), cmdName("UNIT", "TUNIT"),
+            cmdHex("USERDATA", "TUDATA"), cmdName("SECLABEL", "TSOSLABL"),
+        },
+        "OMVS": {
+            cmdID("UID", "UID"), cmdStr("HOME", "HOME"), cmdStr("PROGRAM", "PROGRAM"),
+            cmdNum("CPUTIMEMAX", "CPUTIME"), cmdNum("ASSIZEMAX", "ASSIZE"), cmdNum("FILEPROCMAX", "FILEPROC"),
+            cmdNum("PROCUSERMAX", "PROCUSER"), cmdNum("THREADSMAX", "THREADS"), cmdNum("MMAPAREAMAX", "MMAPAREA"),
+            cmdName("MEMLIMIT", "MEMLIMIT"), cmdName("SHMEMMAX", "SHMEMMAX"),
+        },
+        "DFP": {
+            cmdName("DATAAPPL", "DATAAPPL"), cmdName("DATACLAS", "DATACLAS"), cmdName("MGMTCLAS", "MGMTCLAS"),
+            cmdName("STORCLAS", "STORCLAS"),
+        },
+        "WORKATTR": {
+            cmdStr("WANAME", "WANAME"), cmdStr("WABLDG", "WABLDG"), cmdStr("WADEPT", "WADEPT"), cmdStr("WAROOM", "WAROOM"),
+            cmdStr("WAADDR1", "WAADDR1"), cmdStr("WAADDR2", "WAADDR2"), cmdStr("WAADDR3", "WAADDR3"),
+            cmdStr("WAADDR4", "WAADDR4"), cmdStr("WAACCNT", "WAACCNT"), cmdStr("WAEMAIL", "WAEMAIL"),
+        },
+        "LANGUAGE": {cmdName("PRIMARY", "USERNL1"), cmdName("SECONDARY", "USERNL2")},
+        "CICS": {
+            cmdName("OPIDENT", "OPIDENT"), cmdNum("OPPRTY", "OPPRTY"), cmdList("OPCLASS", "OPCLASSN", "OPCLASS"),
+            cmdList("RSLKEY", "RSLKEYN", "RSLKEY"), cmdList("TSLKEY", "TSLKEYN", "TSLKEY"),
+            cmdChoice("XRFSOFF(FORCE)", "XRFSOFF(NOFORCE)", "XRFSOFF"),
+        },
+        "NETVIEW": {
+            cmdStr("IC", "IC"), cmdName("CONSNAME", "CONSNAME"), cmdYesNo("MSGRECVR", "MSGRECVR"),
+            cmdList("OPCLASS", "OPCLASSN", "OPCLASS"), cmdList("DOMAINS", "DOMAINSN", "DOMAINS"),
+            cmdYesNo("NGMFADMN", "NGMFADMN"),
+        },
+        "OPERPARM": {cmdBinNum("STORAGE", "OPERSTOR"), cmdList("MSCOPE", "OPERMCNT", "OPERMSCP")},
+        "DCE": {
+            cmdName("UUID", "UUID"), cmdStr("DCENAME", "DCENAME"), cmdStr("HOMECELL", "HOMECELL"),
+            cmdName("HOMEUUID", "HOMEUUID"), cmdYesNo("AUTOLOGIN", "DCEFLAGS"),
+        },
+        "OVM": {cmdID("UID", "UID"), cmdStr("HOME", "HOME"), cmdStr("PROGRAM", "PROGRAM"), cmdStr("FSROOT", "FSROOT")},
+        "LNOTES": {cmdStr("SNAME", "SNAME")},
+        "NDS": {cmdStr("UNAME", "UNAME")},
+        "KERB": {cmdStr("KERBNAME", "KERBNAME"), cmdName("MAXTKTLFE", "MAXTKTLF"), cmdKerbEnc("ENCRYPT", "ENCRYPT")},
+        "PROXY": {cmdStr("LDAPHOST", "LDAPHOST"), cmdStr("BINDDN", "BINDDN"), cmdSecret("BINDPW", "BINDPW")},
+        "EIM": {cmdStr("LDAPPROF", "LDAPPROF")},
+    },
+    "GROUP": {
+        "OMVS": {cmdID("GID", "GID")},
+        "OVM":  {cmdID("GID", "GID")},
+        "DFP": {
+            cmdName("DATAAPPL", "DATAAPPL"), cmdName("DATACLAS", "DATACLAS"), cmdName("MGMTCLAS", "MGMTCLAS"),
+            cmdName("STORCLAS", "STORCLAS"),
+        },
+        "TME": {cmdList("ROLES", "ROLEN", "ROLES")},
+    },
+    "DATASET": {
+        "DFP": {cmdName("RESOWNER", "RESOWNER"), cmdStr("DATAKEY", "DATAKEY")},
+        "TME": {cmdList("ROLES", "ROLEN", "ROLES")},
+    },
+    "GENERAL": {
+        "SESSION": {cmdSecret("SESSKEY", "SESSKEY"), cmdNum("INTERVAL", "KEYINTVL")},
+        "DLFDATA": {cmdYesNo("RETAIN", "RETAIN"), cmdList("JOBNAMES", "JOBNMCNT", "JOBNAMES")},
+        "SSIGNON": {
+            func(s *Segment) string {
+                if label := s.FieldString("PTKEYLAB"); len(label) > 0 {
+                    return fmt.Sprintf("KEYLABEL(%s)", label)
+                }
+                return cmdSecret("KEYMASKED", "SSKEY")(s)
+            },
+            cmdName("TYPE", "PTTYPE"), cmdNum("TIMEOUT", "PTTIMEO"),
+        },
+        "STDATA": {
+            cmdName("USER", "STUSER"), cmdName("GROUP", "STGROUP"), cmdYesNo("TRUSTED", "FLAGTRUS"),
+            cmdYesNo("PRIVILEGED", "FLAGPRIV"), cmdYesNo("TRACE", "FLAGTRAC"),
+        },
+        "SVFMR": {cmdName("SCRIPTNAME", "SCRIPTN"), cmdName("PARMNAME", "PARMN")},
+        "TME": {
+            cmdName("PARENT", "PARENT"), cmdList("CHILDREN", "CHILDN", "CHILDREN"), cmdList("RESOURCE", "RESN", "RESOURCE"),
+            cmdList("GROUPS", "GROUPN", "GROUPS"), cmdList("ROLES", "ROLEN", "ROLES"),
+        },
+        "KERB": {
+            cmdStr("KERBNAME", "KERBNAME"), cmdName("MINTKTLFE", "MINTKTLF"), cmdName("MAXTKTLFE", "MAXTKTLF"),
+            cmdName("DEFTKTLFE", "DEFTKTLF"), cmdKerbEnc("ENCRYPT", "ENCRYPT"), cmdName("CHKADDRS", "CHKADDRS"),
+            cmdSecret("PASSWORD", "CURKEY"),
+        },
+        "PROXY": {cmdStr("LDAPHOST", "LDAPHOST"), cmdStr("BINDDN", "BINDDN"), cmdSecret("BINDPW", "BINDPW")},
+        "EIM": {
+            cmdStr("DOMAINDN", "DOMAINDN"), cmdName("OPTIONS", "OPTIONS"), cmdStr("LOCALREGISTRY", "LOCALREG"),
+            cmdStr("KERBREGISTRY", "KERBREG"), cmdStr("X509REGISTRY", "X509REG"),
+        },
+        "ALIAS": {cmdName("IPLOOKUP", "IPLOOK")},
+        "CDTINFO": {
+            cmdNum("POSIT", "CDTPOSIT"), cmdNum("MAXLENGTH", "CDTMAXLN"), cmdNum("MAXLENX", "CDTMAXLX"),
+            cmdNum("DEFAULTRC", "CDTDFTRC"), cmdNum("KEYQUALIFIERS", "CDTKEYQL"), cmdName("GROUP", "CDTGROUP"),
+            cmdName("MEMBER", "CDTMEMBR"),
+        },
+        "ICTX": {
+            cmdYesNo("USEMAP", "USEMAP"), cmdYesNo("DOMAP", "DOMAP"), cmdYesNo("MAPREQUIRED", "MAPREQ"),
+            cmdNum("MAPPINGTIMEOUT", "MAPTIMEO"),
+        },
+        "CFDEF": {
+            func(s *Segment) string { return "TYPE(" + customFieldType(s, "CFDTYPE") + ")" },
+            cmdNum("MAXLENGTH", "CFMXLEN"), cmdStr("HELP", "CFHELP"), cmdStr("LISTHEAD", "CFLIST"),
+            cmdName("VALREXX", "CFVALRX"), cmdChoice("MIXED(YES)", "", "CFMIXED"),
+            func(s *Segment) string {
+                if b := s.FieldBytes("CFFIRST"); len(b) > 0 && len(customFieldChars[b[0]]) > 0 {
+                    return "FIRST(" + customFieldChars[b[0]] + ")"
+                }
+                return ""
+            },
+            func(s *Segment) string {
+                if b := s.FieldBytes("CFOTHER"); len(b) > 0 && len(customFieldChars[b[0]]) > 0 {
+                    return "OTHER(" + customFieldChars[b[0]] + ")"
+                }
+                return ""
+            },
+        },
+        "SIGVER":   {cmdYesNo("SIGREQUIRED", "SIGREQD")},
+        "ICSF":     {cmdList("SYMEXPORTKEYS", "CSFSKLCT", "CSFSKLBS"), cmdList("SYMEXPORTCERTS", "CSFSCLCT", "CSFSCLBS")},
+        "MFPOLICY": {cmdList("FACTORS", "MFFCTRN", "MFFCTRS"), cmdNum("TOKENTIMEOUT", "MFTIMEO")},
+        "IDTPARMS": {
+            cmdName("SIGTOKEN", "IDTTOKN"), cmdName("SIGSEQNUM", "IDTSEQN"), cmdName("SIGCAT", "IDTCAT"),
+            cmdName("SIGALG", "IDTSALG"), cmdNum("IDTTIMEOUT", "IDTTIMEO"), cmdName("ANYAPPL", "IDTANYAP"),
+        },
+    },
+}
+
+// Get audit operand value of AUDIT or GAUDIT flag byte with access levels of successful and failed attempts
+func cmdAudit(s *Segment, field string, success string, failure string) string {
+    b := s.FieldBytes(field)
+    level := func(name string) string {
+        if l := s.FieldBytes(name); len(l) > 0 && l[0] != 0 {
+            return "(" + AccessName(l[0]) + ")"
+        }
+        return ""
+    }
+    switch {
+    case len(b) == 0:
+        return ""
+    case b[0]&0x80 != 0:
+        return "ALL" + level(success)
+    case b[0]&0x60 == 0x60:
+        return "SUCCESS" + level(success) + " FAILURES" + level(failure)
+    case b[0]&0x40 != 0:
+        return "SUCCESS" + level(success)
+    case b[0]&0x20 != 0:
+        return "FAILURES" + level(failure)
+    }
+    return "NONE"
+}
+
+// Generator of RACF commands recreating runtime DB
+type commandGenerator struct {
+    w        io.Writer
+    schema   map[string][]*CustomField
+    known    map[string]bool // Users and groups of runtime DB
+    defined  map[string]bool // Defined users and groups
+    deferred []*racfCommand  // Owners which are defined after owned profiles
+}
+
+func (g *commandGenerator) comment(format string, args ...interface{}) {
+    fmt.Fprintf(g.w, "/* %s */\n", fmt.Sprintf(format, args...))
+}
+
+func (g *commandGenerator) command(c *racfCommand) {
+    fmt.Fprintln(g.w, c.String())
+}
+
+// Get OWNER operand if the owner is already defined (or isn't a user or group of runtime DB), otherwise
+// the owner is set later by the alter command (ALTUSER, ALTGROUP or RALTER) with the profile arguments
+func (g *commandGenerator) owner(owner string, alter string, args ...string) string {
+    if len(owner) == 0 {
+        return ""
+    }
+    if g.defined[owner] || !g.known[owner] {
+        return fmt.Sprintf("OWNER(%s)", owner)
+    }
+    c := newCommand(alter, args...)
+    c.add(fmt.Sprintf("OWNER(%s)", owner))
+    g.deferred = append(g.deferred, c)
+    return ""
+}
+
+// Get segment operands of the profile. Segments which can't be converted are reported in comments
+func (g *commandGenerator) segments(p *Profile, name string) []string {
+    retVal := make([]string, 0)
+    for i := range p.Segments {
+        s := &p.Segments[i]
+        switch s.Name {
+        case "BASE":
+            continue
+        case "CERTDATA":
+            g.comment("%s %s: digital certificate must be added by RACDCERT", p.Class(), name)
+            continue
+        case "CSDATA":
+            fields := DecodeCustomFields(p, g.schema)
+            keys := make([]string, 0)
+            for k := range fields {
+                keys = append(keys, k)
+            }
+            sort.Strings(keys)
+            values := make([]string, 0)
+            for _, k := range keys {
+                v := fields[k].Value
+                if fields[k].Field.Type == "CHAR" {
+                    v = cmdQuote(v)
+                }
+                values = append(values, fmt.Sprintf("%s(%s)", k, v))
+            }
+            if len(values) > 0 {
+                retVal = append(retVal, fmt.Sprintf("CSDATA(%s)", strings.Join(values, " ")))
+            }
+            continue
+        }
+        operands, ok := commandSegments[p.Type.Name][s.Name]
+        if !ok {
+            g.comment("%s %s: segment %s isn't converted", p.Type.Name, name, s.Name)
+            continue
+        }
+        values := make([]string, 0)
+        for _, op := range operands {
+            if v := op(s); len(v) > 0 {
+                values = append(values, v)
+            }
+        }
+        // Segment without operands is added with defaults (e.g. TSO instead of TSO())
+        if len(values) == 0 {
+            retVal = append(retVal, s.Name)
+            continue
+        }
+        retVal = append(retVal, fmt.Sprintf("%s(%s)", s.Name, strings.Join(values, " ")))
+    }
+    return retVal
+}
+
+func onOff(set bool, on string, off string) string {
+    if set {
+        return on
+    }
+    return off
+}
+
+// SETROPTS commands of system-wide options from ICB
+func (g *commandGenerator) setropts(icb *sections.ICB) {
+    g.comment("SETROPTS options")
+    password := []string{fmt.Sprintf("INTERVAL(%d)", icb.ICBPINV), fmt.Sprintf("HISTORY(%d)", icb.ICBPHIST),
+        fmt.Sprintf("WARNING(%d)", icb.ICBPWARN), fmt.Sprintf("MINCHANGE(%d)", icb.ICBPMIN)}
+    if icb.ICBPRVOK > 0 {
+        password = append(password, fmt.Sprintf("REVOKE(%d)", icb.ICBPRVOK))
+    } else {
+        password = append(password, "NOREVOKE")
+    }
+    password = append(password, onOff(icb.ICBPLC, "MIXEDCASE", "NOMIXEDCASE"), onOff(icb.ICBPSC, "SPECIALCHARS", "NOSPECIALCHARS"))
+    if icb.ICBPALG == 1 {
+        password = append(password, "ALGORITHM(KDFAES)")
+    }
+    c := newCommand("SETROPTS")
+    c.add(fmt.Sprintf("PASSWORD(%s)", strings.Join(password, " ")))
+    g.command(c)
+
+    c = newCommand("SETROPTS")
+    if icb.ICBINACT > 0 {
+        c.add(fmt.Sprintf("INACTIVE(%d)", icb.ICBINACT))
+    } else {
+        c.add("NOINACTIVE")
+    }
+    switch {
+    case icb.ICBPRO && icb.ICBPROF:
+        c.add("PROTECTALL(WARNING)")
+    case icb.ICBPRO:
+        c.add("PROTECTALL(FAILURES)")
+    default:
+        c.add("NOPROTECTALL")
+    }
+    c.add(onOff(icb.ICBDGEN, "GENERIC(DATASET)", "NOGENERIC(DATASET)"), onOff(icb.ICBEGN, "EGN", "NOEGN"),
+        onOff(icb.ICBEOS, "ERASE", "NOERASE"), onOff(icb.ICBTDSN, "TAPEDSN", "NOTAPEDSN"), onOff(!icb.ICBNADS, "ADSP", "NOADSP"),
+        onOff(icb.ICBLGRP, "GRPLIST", "NOGRPLIST"), onOff(!icb.ICBSAUD, "SAUDIT", "NOSAUDIT"),
+        onOff(icb.ICBAOPR, "OPERAUDIT", "NOOPERAUDIT"), onOff(icb.ICBJALL, "JES(BATCHALLRACF)", "JES(NOBATCHALLRACF)"),
+        onOff(icb.ICBJXAL, "JES(XBMALLRACF)", "JES(NOXBMALLRACF)"))
+    audit := make([]string, 0)
+    for _, a := range []struct {
+        set   bool
+        class string
+    }{{icb.ICBAUSE, "USER"}, {icb.ICBAGRO, "GROUP"}, {icb.ICBADAT, "DATASET"}} {
+        if a.set {
+            audit = append(audit, a.class)
+        }
+    }
+    if len(audit) > 0 {
+        c.add(fmt.Sprintf("AUDIT(%s)", strings.Join(audit, " ")))
+    }
+    if prefix := strings.TrimSuffix(strings.TrimSpace(icb.ICBQUAL.String()), "."); len(prefix) > 0 && icb.ICBQLLN > 0 {
+        c.add(fmt.Sprintf("PREFIX(%s)", prefix))
+    }
+    c.add(fmt.Sprintf("RETPD(%d)", icb.ICBRETP), fmt.Sprintf("KERBLVL(%d)", icb.ICBKRBLV))
+    g.command(c)
+    g.comment("Class options (CLASSACT, RACLIST, GENERIC, AUDIT) and password syntax rules aren't converted")
+}
+
+// Get groups ordered by superior groups (superior group is defined before its subgroups)
+func commandGroupOrder(profiles []*Profile) []*Profile {
+    groups := make(map[string]*Profile)
+    subgroups := make(map[string][]*Profile)
+    for _, p := range profiles {
+        if p.Type.Name == "GROUP" {
+            groups[strings.TrimSpace(p.Name)] = p
+        }
+    }
+    roots := make([]*Profile, 0)
+    for _, p := range profiles {
+        if p.Type.Name != "GROUP" {
+            continue
+        }
+        name := strings.TrimSpace(p.Name)
+        var sup string
+        if s, ok := p.Segment("BASE"); ok {
+            sup = s.FieldString("SUPGROUP")
+        }
+        if _, ok := groups[sup]; ok && sup != name {
+            subgroups[sup] = append(subgroups[sup], p)
+        } else {
+            roots = append(roots, p)
+        }
+    }
+
+    retVal := make([]*Profile, 0)
+    added := make(map[*Profile]bool)
+    var add func(p *Profile)
+    add = func(p *Profile) {
+        if added[p] {
+            return
+        }
+        added[p] = true
+        retVal = append(retVal, p)
+        for _, sub := range subgroups[strings.TrimSpace(p.Name)] {
+            add(sub)
+        }
+    }
+    for _, p := range roots {
+        add(p)
+    }
+    // Groups in superior group loops
+    for _, p := range profiles {
+        if p.Type.Name == "GROUP" {
+            add(p)
+        }
+    }
+    return retVal
+}
+
+func (g *commandGenerator) addGroup(p *Profile) {
+    name := strings.TrimSpace(p.Name)
+    c := newCommand("ADDGROUP", name)
+    if s, ok := p.Segment("BASE"); ok {
+        if sup := s.FieldString("SUPGROUP"); len(sup) > 0 && sup != name {
+            c.add(fmt.Sprintf("SUPGROUP(%s)", sup))
+        }
+        c.add(g.owner(s.FieldString("AUTHOR"), "ALTGROUP", name))
+        c.add(cmdStr("DATA", "INSTDATA")(s), cmdName("MODEL", "MODELNAM")(s))
+        c.add(cmdChoice("NOTERMUACC", "TERMUACC", "NOTRMUAC")(s), cmdChoice("UNIVERSAL", "", "UNVFLG")(s))
+    }
+    c.add(g.segments(p, name)...)
+    g.command(c)
+    g.defined[name] = true
+}
+
+func (g *commandGenerator) addUser(p *Profile) {
+    name := strings.TrimSpace(p.Name)
+    c := newCommand("ADDUSER", name)
+    alter := newCommand("ALTUSER", name)
+    if s, ok := p.Segment("BASE"); ok {
+        c.add(cmdName("DFLTGRP", "DFLTGRP")(s), g.owner(s.FieldString("AUTHOR"), "ALTUSER", name))
+        c.add(cmdStr("NAME", "PGMRNAME")(s), cmdStr("DATA", "INSTDATA")(s), cmdName("MODEL", "MODELNAM")(s))
+        c.add(cmdList("CLAUTH", "CLCNT", "CLNAME")(s), cmdName("SECLABEL", "SECLABEL")(s))
+        for _, a := range []struct {
+            keyword string
+            field   string
+        }{{"ADSP", "FLAG1"}, {"SPECIAL", "FLAG2"}, {"OPERATIONS", "FLAG3"}, {"GRPACC", "FLAG5"}, {"AUDITOR", "FLAG6"}, {"ROAUDIT", "FLAGROA"}} {
+            c.add(cmdChoice(a.keyword, "", a.field)(s))
+        }
+
+        // Password and password phrase are replaced by placeholders
+        password := cmdSecret("PASSWORD", "PASSWORD")(s)
+        if len(password) == 0 {
+            password = cmdSecret("PASSWORD", "PWDX")(s)
+        }
+        if len(password) == 0 || isFlagSet(s, "FLAG7", 0x80) {
+            password = "NOPASSWORD"
+        }
+        phrase := cmdSecret("PHRASE", "PHRASE")(s)
+        if len(phrase) == 0 {
+            phrase = cmdSecret("PHRASE", "PHRASEX")(s)
+        }
+        c.add(password, strings.Replace(phrase, COMMAND_SECRET, cmdQuote(COMMAND_SECRET), 1))
+
+        alter.add(cmdChoice("UAUDIT", "", "UAUDIT")(s), cmdChoice("REVOKE", "", "FLAG4")(s))
+    }
+    c.add(g.segments(p, name)...)
+    g.command(c)
+    if len(alter.operands) > 0 {
+        g.command(alter)
+    }
+    g.defined[name] = true
+}
+
+// CONNECT commands of user connects (group authority is taken from group access list)
+func (g *commandGenerator) connects(p *Profile, authorities map[string]map[string]string) {
+    name := strings.TrimSpace(p.Name)
+    s, ok := p.Segment("BASE")
+    if !ok {
+        return
+    }
+    for _, item := range s.Items("CGGRPCT") {
+        group := item.FieldString("CGGRPNM")
+        c := newCommand("CONNECT", name)
+        c.add(fmt.Sprintf("GROUP(%s)", group))
+        if owner := item.FieldString("CGAUTHOR"); len(owner) > 0 {
+            c.add(fmt.Sprintf("OWNER(%s)", owner))
+        }
+        if authority, ok := authorities[group][name]; ok {
+            c.add(fmt.Sprintf("AUTHORITY(%s)", authority))
+        }
+        if b := item.FieldBytes("CGUACC"); len(b) > 0 {
+            c.add(fmt.Sprintf("UACC(%s)", AccessName(b[0])))
+        }
+        for _, a := range []struct {
+            keyword string
+            field   string
+        }{{"ADSP", "CGFLAG1"}, {"SPECIAL", "CGFLAG2"}, {"OPERATIONS", "CGFLAG3"}, {"REVOKE", "CGFLAG4"}, {"GRPACC", "CGFLAG5"}, {"AUDITOR", "CGGRPAUD"}} {
+            c.add(cmdChoice(a.keyword, "", a.field)(item))
+        }
+        g.command(c)
+    }
+}
+
+// Common operands of data set and general resource profiles
+func commandResourceOperands(s *Segment) []string {
+    retVal := make([]string, 0)
+    if audit := cmdAudit(s, "AUDIT", "AUDITQS", "AUDITQF"); len(audit) > 0 {
+        retVal = append(retVal, fmt.Sprintf("AUDIT(%s)", audit))
+    }
+    if audit := cmdAudit(s, "GAUDIT", "GAUDITQS", "GAUDITQF"); len(audit) > 0 && audit != "NONE" {
+        retVal = append(retVal, fmt.Sprintf("GLOBALAUDIT(%s)", audit))
+    }
+    for _, op := range []commandOperand{cmdStr("DATA", "INSTDATA"), cmdNum("LEVEL", "LEVEL"), cmdName("NOTIFY", "NOTIFY"),
+        cmdChoice("WARNING", "", "WARNING"), cmdName("SECLABEL", "SECLABEL")} {
+        if v := op(s); len(v) > 0 {
+            retVal = append(retVal, v)
+        }
+    }
+    return retVal
+}
+
+func (g *commandGenerator) addDataset(p *Profile) {
+    name := strings.TrimSpace(p.Name)
+    c := newCommand("ADDSD", cmdQuote(name))
+    if s, ok := p.Segment("BASE"); ok {
+        if !isGenericName(name) {
+            if volumes := s.Items("VOLCNT"); len(volumes) > 0 {
+                c.add(fmt.Sprintf("VOLUME(%s)", volumes[0].FieldString("VOLSER")))
+            }
+            c.add("NOSET")
+        }
+        c.add(cmdName("OWNER", "AUTHOR")(s))
+        if uacc := p.UACC(); len(uacc) > 0 {
+            c.add(fmt.Sprintf("UACC(%s)", uacc))
+        }
+        c.add(commandResourceOperands(s)...)
+        c.add(cmdNum("RETPD", "RETPD")(s))
+    }
+    c.add(g.segments(p, name)...)
+    g.command(c)
+}
+
+func (g *commandGenerator) addResource(p *Profile) {
+    name, class := p.ResourceName(), p.Class()
+    c := newCommand("RDEFINE", class, cmdResource(name))
+    if s, ok := p.Segment("BASE"); ok {
+        // Class descriptors and custom fields are defined before their owners
+        c.add(g.owner(s.FieldString("OWNER"), "RALTER", class, cmdResource(name)))
+        if uacc := p.UACC(); len(uacc) > 0 {
+            c.add(fmt.Sprintf("UACC(%s)", uacc))
+        }
+        c.add(commandResourceOperands(s)...)
+        c.add(cmdStr("APPLDATA", "APPLDATA")(s))
+
+        members := make([]string, 0)
+        for _, item := range s.Items("MEMCNT") {
+            // Only members saved as names are converted (e.g. GLOBAL and PROGRAM members have binary parts)
+            if m := decode.EBCDICStr(item.FieldBytes("MEMLST")); len(m) > 0 && m.IsPrint() {
+                members = append(members, cmdResource(m.Trim()))
+            }
+        }
+        if len(members) > 0 {
+            c.add(fmt.Sprintf("ADDMEM(%s)", strings.Join(members, " ")))
+        }
+    }
+    c.add(g.segments(p, name)...)
+    g.command(c)
+}
+
+// PERMIT commands of standard and conditional access lists
+func (g *commandGenerator) permits(p *Profile) {
+    name, class := cmdQuote(strings.TrimSpace(p.Name)), ""
+    if p.Type.Name == "GENERAL" {
+        name, class = cmdResource(p.ResourceName()), fmt.Sprintf("CLASS(%s)", p.Class())
+    }
+    for _, e := range p.ACL() {
+        c := newCommand("PERMIT", name)
+        c.add(class, fmt.Sprintf("ID(%s)", e.ID), fmt.Sprintf("ACCESS(%s)", e.Access))
+        g.command(c)
+    }
+    for _, e := range p.ConditionalACL() {
+        c := newCommand("PERMIT", name)
+        c.add(class, fmt.Sprintf("ID(%s)", e.ID), fmt.Sprintf("ACCESS(%s)", e.Access), e.Condition)
+        g.command(c)
+    }
+}
+
+// Write RACF commands recreating runtime DB: SETROPTS options, class descriptors and custom fields,
+// groups (superior groups first), users, owners of users and groups, connects, data set and general resource
+// profiles and their access lists. Secrets are replaced by COMMAND_SECRET placeholder
+func WriteCommands(w io.Writer, profiles []*Profile, icb *sections.ICB) {
+    g := &commandGenerator{w: w, schema: BuildCustomFieldSchema(profiles), known: make(map[string]bool), defined: make(map[string]bool)}
+    for _, p := range profiles {
+        if p.Type.Name == "USER" || p.Type.Name == "GROUP" {
+            g.known[strings.TrimSpace(p.Name)] = true
+        }
+    }
+    if icb != nil {
+        g.setropts(icb)
+    }
+
+    // Classes and custom fields must be defined before they are used
+    g.comment("Class descriptors and custom fields")
+    for _, class := range []struct {
+        name     string
+        activate []string
+    }{{"CDT", []string{"CLASSACT(CDT)", "RACLIST(CDT)"}}, {"CFIELD", []string{"RACLIST(CFIELD)", "REFRESH"}}} {
+        found := false
+        for _, p := range profiles {
+            if p.Type.Name == "GENERAL" && p.Class() == class.name {
+                g.addResource(p)
+                found = true
+            }
+        }
+        if found {
+            c := newCommand("SETROPTS")
+            c.add(class.activate...)
+            g.command(c)
+        }
+    }
+
+    g.comment("Groups")
+    authorities := make(map[string]map[string]string)
+    for _, p := range commandGroupOrder(profiles) {
+        g.addGroup(p)
+        group := strings.TrimSpace(p.Name)
+        authorities[group] = make(map[string]string)
+        if s, ok := p.Segment("BASE"); ok {
+            for _, item := range s.Items("ACLCNT") {
+                authorities[group][item.FieldString("USERID")] = GroupAuthority(item.FieldBytes("USERACS"))
+            }
+        }
+    }
+
+    g.comment("Users")
+    for _, p := range profiles {
+        if p.Type.Name == "USER" {
+            g.addUser(p)
+        }
+    }
+    if len(g.deferred) > 0 {
+        g.comment("Owners of groups, users, class descriptors and custom fields")
+        for _, c := range g.deferred {
+            g.command(c)
+        }
+    }
+
+    g.comment("Connects")
+    for _, p := range profiles {
+        if p.Type.Name == "USER" {
+            g.connects(p, authorities)
+        }
+    }
+
+    g.comment("Data set profiles")
+    for _, p := range profiles {
+        if p.Type.Name == "DATASET" {
+            g.addDataset(p)
+        }
+    }
+
+    g.comment("General resource profiles")
+    for _, p := range profiles {
+        if p.Type.Name == "GENERAL" && p.Class() != "CDT" && p.Class() != "CFIELD" {
+            g.addResource(p)
+        }
+    }
+
+    g.comment("Access lists")
+    for _, p := range profiles {
+        if p.Type.Name == "DATASET" || p.Type.Name == "GENERAL" {
+            g.permits(p)
+        }
+    }
+}
+
+// Save RACF commands recreating runtime DB as TSO command script
+func ToCommands(profiles []*Profile, fileName string, icb *sections.ICB) {
+    f, err := createOutput(fileName)
+    if err != nil {
+        common.Fatal(fmt.Errorf("Can not create command file: %v", err))
+    }
+    defer f.Close()
+
+    common.Log.Info("Saving RACF commands as file %s", fileName)
+    w := bufio.NewWriter(f)
+    WriteCommands(w, profiles, icb)
+    if err := w.Flush(); err != nil {
+        common.Fatal(fmt.Errorf("Can not save command file: %v", err))
+    }
+}
@@ -0,0 +1,147 @@
+package db
+
+import (
+    "reflect"
+    "strings"
+    "testing"
+
+    "racfudit/decode"
+)
+
+func TestCommandSegmentsWithoutOperands(t *testing.T) {
+    tsoT := testSegmentType("TACCNT", "TLPROC")
+    p := NewProfile("IBMUSER", "USER", 2)
+    testSegment(p, "TSO", tsoT, map[string]string{})
+    testSegment(p, "WORKATTR", testSegmentType("WANAME"), map[string]string{"WANAME": "JOHN"})
+
+    var sb strings.Builder
+    g := &commandGenerator{w: &sb}
+    if got, want := g.segments(p, "IBMUSER"), []string{"TSO", "WORKATTR(WANAME('JOHN'))"}; !reflect.DeepEqual(got, want) {
+        t.Errorf("segments() = %q, want %q", got, want)
+    }
+}
+
+// Create GROUP profile with superior group and owner
+func testGroupProfile(name string, supgroup string, owner string) *Profile {
+    p := NewProfile(name, "GROUP", 1)
+    testSegmentOf(p, "BASE", testFields{{"SUPGROUP", supgroup}, {"AUTHOR", owner}})
+    return p
+}
+
+func TestCommandGroupOrder(t *testing.T) {
+    profiles := []*Profile{
+        testGroupProfile("TEAM", "DEPT", ""),
+        testGroupProfile("LOOPA", "LOOPB", ""),
+        testGroupProfile("DEPT", "SYS1", ""),
+        NewProfile("IBMUSER", "USER", 2),
+        testGroupProfile("SYS1", "", ""),
+        testGroupProfile("LOOPB", "LOOPA", ""),
+        testGroupProfile("SELF", "SELF", ""),
+        testGroupProfile("ORPHAN", "NOGROUP", ""),
+    }
+    names := make([]string, 0)
+    for _, p := range commandGroupOrder(profiles) {
+        names = append(names, p.Name)
+    }
+    // Superior groups are defined first, groups in loops are defined in profile order
+    if want := []string{"SYS1", "DEPT", "TEAM", "SELF", "ORPHAN", "LOOPA", "LOOPB"}; !reflect.DeepEqual(names, want) {
+        t.Errorf("commandGroupOrder() = %v, want %v", names, want)
+    }
+}
+
+// Check that the lines are written in this order
+func testOrder(t *testing.T, out string, lines ...string) {
+    t.Helper()
+    pos := 0
+    for _, l := range lines {
+        i := strings.Index(out[pos:], l)
+        if i < 0 {
+            t.Errorf("%q isn't found after position %d in:\n%s", l, pos, out)
+            return
+        }
+        pos += i + len(l)
+    }
+}
+
+func TestWriteCommandsDeferredOwners(t *testing.T) {
+    cdt := NewProfile("CDT     $MYCLASS", "GENERAL", 5)
+    testSegmentOf(cdt, "BASE", testFields{{"OWNER", "IBMUSER"}})
+    cfield := NewProfile("CFIELD  USER.CSDATA.EMPSER", "GENERAL", 5)
+    testSegmentOf(cfield, "BASE", testFields{{"OWNER", "NOBODY"}})
+    user := NewProfile("IBMUSER", "USER", 2)
+    testSegmentOf(user, "BASE", testFields{{"DFLTGRP", "SYS1"}, {"AUTHOR", "SYS1"}})
+    profiles := []*Profile{cdt, cfield, testGroupProfile("SYS1", "", "IBMUSER"), testGroupProfile("DEPT", "SYS1", "SYS1"), user}
+
+    var sb strings.Builder
+    WriteCommands(&sb, profiles, nil)
+    out := sb.String()
+    testOrder(t, out,
+        "RDEFINE CDT $MYCLASS\n",
+        // Owner which isn't a user or group of RACF DB is set by RDEFINE
+        "RDEFINE CFIELD USER.CSDATA.EMPSER -\n  OWNER(NOBODY)\n",
+        "ADDGROUP SYS1\n",
+        "ADDGROUP DEPT -\n  SUPGROUP(SYS1) -\n  OWNER(SYS1)\n",
+        "ADDUSER IBMUSER -\n  DFLTGRP(SYS1) -\n  OWNER(SYS1)",
+        "/* Owners of groups, users, class descriptors and custom fields */\n",
+        "RALTER CDT $MYCLASS -\n  OWNER(IBMUSER)\n",
+        "ALTGROUP SYS1 -\n  OWNER(IBMUSER)\n",
+        "/* Connects */",
+    )
+}
+
+func TestWriteCommandsSecrets(t *testing.T) {
+    user := NewProfile("IBMUSER", "USER", 2)
+    testSegmentOf(user, "BASE", testFields{{"PASSWORD", decode.HexStr{0x12, 0x34}}, {"PHRASE", decode.HexStr{0x56}}})
+    testSegmentOf(user, "PROXY", testFields{{"LDAPHOST", "LDAP://HOST"}, {"BINDPW", decode.HexStr{0x78}}})
+    noPassword := NewProfile("BATCH", "USER", 2)
+    testSegmentOf(noPassword, "BASE", testFields{{"PASSWORD", decode.HexStr{0, 0}}, {"PHRASE", decode.HexStr{0x40}}})
+    session := NewProfile("APPCLU  NET1.LU1.LU2", "GENERAL", 5)
+    testSegmentOf(session, "SESSION", testFields{{"SESSKEY", decode.HexStr{0x9a}}, {"KEYINTVL", uint16(30)}})
+
+    var sb strings.Builder
+    WriteCommands(&sb, []*Profile{user, noPassword, session}, nil)
+    out := sb.String()
+    testOrder(t, out,
+        "ADDUSER IBMUSER -\n  PASSWORD(<SECRET>) -\n  PHRASE('<SECRET>') -\n  PROXY(LDAPHOST('LDAP://HOST') BINDPW(<SECRET>))\n",
+        "ADDUSER BATCH -\n  NOPASSWORD\n",
+        "RDEFINE APPCLU NET1.LU1.LU2 -\n  SESSION(SESSKEY(<SECRET>) INTERVAL(30))\n",
+    )
+    for _, secret := range []string{"1234", "56", "78", "9A", "9a"} {
+        if strings.Contains(out, secret) {
+            t.Errorf("Secret %s is written in:\n%s", secret, out)
+        }
+    }
+}
+
+func TestWriteCommandsPermits(t *testing.T) {
+    ds := NewProfile("SYS1.PARMLIB", "DATASET", 4)
+    testSegmentOf(ds, "BASE", testFields{
+        {"ACLCNT", []testFields{
+            {{"USERID", "SYS1"}, {"USERACS", decode.Flag{0x10}}, {"ACSCNT", uint16(0)}},
+            {{"USERID", "*"}, {"USERACS", decode.Flag{0x00}}, {"ACSCNT", uint16(0)}},
+        }},
+        {"ACL2CNT", []testFields{
+            {{"PROGRAM", "IEBCOPY"}, {"USER2ACS", "IBMUSER"}, {"PROGACS", decode.Flag{0x20}}, {"PACSCNT", uint16(0)}, {"ACL2VAR", ""}},
+        }},
+    })
+    res := NewProfile("FACILITYBPX.SUPERUSER", "GENERAL", 5)
+    testSegmentOf(res, "BASE", testFields{
+        {"ACLCNT", []testFields{{{"USERID", "IBMUSER"}, {"USERACS", decode.Flag{0x20}}, {"ACSCNT", uint16(0)}}}},
+        {"ACL2CNT", []testFields{
+            {{"ACL2NAME", "BPXROOT"}, {"ACL2UID", "OMVSGRP"}, {"ACL2ACC", decode.Flag{0x10}}, {"ACL2ACNT", uint16(0)}, {"ACL2VAR", ""}},
+            {{"ACL2NAME", "CONSOLE"}, {"ACL2UID", "OPER"}, {"ACL2ACC", decode.Flag{0x10}}, {"ACL2ACNT", uint16(0)}, {"ACL2VAR", "MASTER"}},
+        }},
+    })
+
+    var sb strings.Builder
+    WriteCommands(&sb, []*Profile{ds, res}, nil)
+    testOrder(t, sb.String(),
+        "/* Access lists */\n",
+        "PERMIT 'SYS1.PARMLIB' -\n  ID(SYS1) -\n  ACCESS(READ)\n",
+        "PERMIT 'SYS1.PARMLIB' -\n  ID(*) -\n  ACCESS(NONE)\n",
+        "PERMIT 'SYS1.PARMLIB' -\n  ID(IBMUSER) -\n  ACCESS(UPDATE) -\n  WHEN(PROGRAM(IEBCOPY))\n",
+        "PERMIT BPX.SUPERUSER -\n  CLASS(FACILITY) -\n  ID(IBMUSER) -\n  ACCESS(UPDATE)\n",
+        "PERMIT BPX.SUPERUSER -\n  CLASS(FACILITY) -\n  ID(OMVSGRP) -\n  ACCESS(READ) -\n  WHEN(PROGRAM(BPXROOT))\n",
+        "PERMIT BPX.SUPERUSER -\n  CLASS(FACILITY) -\n  ID(OPER) -\n  ACCESS(READ) -\n  WHEN(CONSOLE(MASTER))\n",
+    )
+}
@@ -0,0 +1,271 @@
+package db
+
+import (
+    "encoding/hex"
+    "fmt"
+    "sort"
+    "strconv"
+    "strings"
+    "unicode"
+
+    "racfudit/decode"
+)
+
+// Custom field types (CFDTYPE of CFDEF segment and CSTYPE of CSDATA segment)
+var customFieldTypes = map[byte]string{0x01: "CHAR", 0x02: "FLAG", 0x03: "HEX", 0x04: "NUM"}
+
+// Character restrictions for the first and other characters of custom field value (CFFIRST and CFOTHER)
+var customFieldChars = map[byte]string{0x01: "ALPHA", 0x02: "ALPHANUM", 0x03: "ANY", 0x04: "NONATABC", 0x05: "NONATNUM", 0x06: "NUMERIC"}
+
+// Custom field definition from CFDEF segment of CFIELD class profile (e.g. USER.CSDATA.EMPSER)
+type CustomField struct {
+    Name        string
+    ProfileType string
+    Type        string
+    MaxLen      uint64
+    MaxVal      int64
+    MinVal      int64
+    First       string
+    Other       string
+    Mixed       bool
+    Help        string
+    ListHead    string
+    ValRexx     string
+}
+
+type CustomValue struct {
+    Field  *CustomField
+    Value  string
+    Errors []string
+}
+
+func customFieldType(s *Segment, name string) string {
+    if b := s.FieldBytes(name); len(b) > 0 {
+        if t, ok := customFieldTypes[b[0]]; ok {
+            return t
+        }
+    }
+    return "CHAR"
+}
+
+// Build custom field schema from CFDEF segments of CFIELD class profiles (profile type -> field definitions)
+func BuildCustomFieldSchema(profiles []*Profile) map[string][]*CustomField {
+    retVal := make(map[string][]*CustomField)
+    for _, p := range profiles {
+        if p.Class() != "CFIELD" {
+            continue
+        }
+        s, ok := p.Segment("CFDEF")
+        if !ok {
+            continue
+        }
+        parts := strings.Split(p.ResourceName(), ".")
+        if len(parts) != 3 || parts[1] != "CSDATA" {
+            continue
+        }
+
+        cf := &CustomField{
+            Name:        parts[2],
+            ProfileType: parts[0],
+            Type:        customFieldType(s, "CFDTYPE"),
+            MaxLen:      s.FieldUint("CFMXLEN"),
+            MaxVal:      int64(int32(s.FieldUint("CFMXVAL"))),
+            MinVal:      int64(int32(s.FieldUint("CFMNVAL"))),
+            Help:        s.FieldString("CFHELP"),
+            ListHead:    s.FieldString("CFLIST"),
+            ValRexx:     s.FieldString("CFVALRX"),
+        }
+        if b := s.FieldBytes("CFFIRST"); len(b) > 0 {
+            cf.First = customFieldChars[b[0]]
+        }
+        if b := s.FieldBytes("CFOTHER"); len(b) > 0 {
+            cf.Other = customFieldChars[b[0]]
+        }
+        if b := s.FieldBytes("CFMIXED"); len(b) > 0 {
+            cf.Mixed = b[0]&0x80 != 0
+        }
+        retVal[cf.ProfileType] = append(retVal[cf.ProfileType], cf)
+    }
+    for _, fields := range retVal {
+        sort.Slice(fields, func(i, j int) bool { return fields[i].Name < fields[j].Name })
+    }
+    return retVal
+}
+
+// Decode CSVALUE according to the custom field type
+func decodeCustomValue(t string, data []byte) string {
+    str := decode.EBCDICStr(data)
+    switch t {
+    case "NUM":
+        if !str.IsPrint() {
+            var n uint64
+            for _, b := range data {
+                n = n<<8 | uint64(b)
+            }
+            return fmt.Sprintf("%d", n)
+        }
+    case "FLAG":
+        // One byte flag (X'80' is a printable EBCDIC character, so it isn't checked with IsPrint)
+        if len(data) == 1 || !str.IsPrint() {
+            if len(data) > 0 && data[0] != 0 {
+                return "YES"
+            }
+            return "NO"
+        }
+    case "HEX":
+        if !str.IsPrint() {
+            return strings.ToUpper(hex.EncodeToString(data))
+        }
+    }
+    return str.Trim()
+}
+
+// Check character of custom field value against the character restriction
+func customCharValid(c rune, restriction string) bool {
+    national := strings.ContainsRune("#$@", c)
+    switch restriction {
+    case "ALPHA":
+        return unicode.IsLetter(c)
+    case "ALPHANUM":
+        return unicode.IsLetter(c) || unicode.IsDigit(c)
+    case "NONATABC":
+        return unicode.IsLetter(c) || national
+    case "NONATNUM":
+        return unicode.IsLetter(c) || unicode.IsDigit(c) || national
+    case "NUMERIC":
+        return unicode.IsDigit(c)
+    }
+    return true
+}
+
+// Validate custom field value against its definition
+func (cf *CustomField) Validate(value string) []string {
+    retVal := make([]string, 0)
+    switch cf.Type {
+    case "NUM":
+        n, err := strconv.ParseInt(value, 10, 64)
+        if err != nil {
+            retVal = append(retVal, fmt.Sprintf("%q is not a number", value))
+        } else if (cf.MaxVal != 0 || cf.MinVal != 0) && (n < cf.MinVal || n > cf.MaxVal) {
+            retVal = append(retVal, fmt.Sprintf("%d is out of range %d..%d", n, cf.MinVal, cf.MaxVal))
+        }
+    case "FLAG":
+        if value != "YES" && value != "NO" {
+            retVal = append(retVal, fmt.Sprintf("%q is not YES or NO", value))
+        }
+    case "HEX":
+        if _, err := hex.DecodeString(strings.Repeat("0", len(value)%2) + value); err != nil {
+            retVal = append(retVal, fmt.Sprintf("%q is not a hex value", value))
+        }
+    case "CHAR":
+        for i, c := range value {
+            restriction := cf.Other
+            if i == 0 {
+                restriction = cf.First
+            }
+            if !customCharValid(c, restriction) {
+                retVal = append(retVal, fmt.Sprintf("character %q at position %d is not %s", c, i+1, restriction))
+                break
+            }
+        }
+        if !cf.Mixed && value != strings.ToUpper(value) {
+            retVal = append(retVal, "mixed case value is not allowed")
+        }
+    }
+    if cf.Type != "NUM" && cf.MaxLen > 0 && uint64(len(value)) > cf.MaxLen {
+        retVal = append(retVal, fmt.Sprintf("length %d exceeds maximum length %d", len(value), cf.MaxLen))
+    }
+    return retVal
+}
+
+// Decode custom fields of CSDATA segment using the schema (field name -> value)
+func DecodeCustomFields(p *Profile, schema map[string][]*CustomField) map[string]*CustomValue {
+    retVal := make(map[string]*CustomValue)
+    s, ok := p.Segment("CSDATA")
+    if !ok {
+        return retVal
+    }
+    rg, ok := s.Field("CSCNT_RG")
+    if !ok {
+        return retVal
+    }
+    for i := 0; i < rg.Len(); i++ {
+        item := Segment{Data: rg.Index(i)}
+        key := item.FieldString("CSKEY")
+
+        var cf *CustomField
+        for _, f := range schema[p.Type.Name] {
+            if f.Name == key {
+                cf = f
+            }
+        }
+        cv := &CustomValue{Field: cf}
+        if cf == nil {
+            // There is no CFDEF for the field, so use the type saved in CSDATA
+            cf = &CustomField{Name: key, ProfileType: p.Type.Name, Type: customFieldType(&item, "CSTYPE")}
+            cv.Field = cf
+            cv.Errors = append(cv.Errors, "custom field is not defined in CFIELD class")
+        }
+        cv.Value = decodeCustomValue(cf.Type, item.FieldBytes("CSVALUE"))
+        cv.Errors = append(cv.Errors, cf.Validate(cv.Value)...)
+        retVal[key] = cv
+    }
+    return retVal
+}
+
+// Get custom fields of the profile type: defined in CFIELD class and found in CSDATA segments without definition
+// (values are decoded custom fields of the profiles, see DecodeCustomFields)
+func customFieldColumns(profiles []*Profile, values map[*Profile]map[string]*CustomValue, schema map[string][]*CustomField, profileType string) []*CustomField {
+    retVal := append([]*CustomField{}, schema[profileType]...)
+    known := make(map[string]bool)
+    for _, cf := range retVal {
+        known[cf.Name] = true
+    }
+    undefined := make([]*CustomField, 0)
+    for _, p := range profiles {
+        if p.Type.Name != profileType {
+            continue
+        }
+        for name, cv := range values[p] {
+            if !known[name] {
+                known[name] = true
+                undefined = append(undefined, cv.Field)
+            }
+        }
+    }
+    sort.Slice(undefined, func(i, j int) bool { return undefined[i].Name < undefined[j].Name })
+    return append(retVal, undefined...)
+}
+
+// Get plain text report about custom fields and their values which don't match definitions
+func csdataReport(profiles []*Profile) string {
+    schema := BuildCustomFieldSchema(profiles)
+    retVal := "Custom field definitions:\n"
+    types := make([]string, 0)
+    for t := range schema {
+        types = append(types, t)
+    }
+    sort.Strings(types)
+    for _, t := range types {
+        for _, cf := range schema[t] {
+            retVal += fmt.Sprintf("\t%s.CSDATA.%s: %s (max length %d; range %d..%d; first %s; other %s; mixed %v; list heading %q; exec %q)\n",
+                t, cf.Name, cf.Type, cf.MaxLen, cf.MinVal, cf.MaxVal, cf.First, cf.Other, cf.Mixed, cf.ListHead, cf.ValRexx)
+        }
+    }
+
+    retVal += "Invalid custom field values:\n"
+    for _, p := range profiles {
+        values := DecodeCustomFields(p, schema)
+        names := make([]string, 0)
+        for name := range values {
+            names = append(names, name)
+        }
+        sort.Strings(names)
+        for _, name := range names {
+            if cv := values[name]; len(cv.Errors) > 0 {
+                retVal += fmt.Sprintf("\t%s %s %s=%q: %s\n", p.Type.Name, p.Name, name, cv.Value, strings.Join(cv.Errors, "; "))
+            }
+        }
+    }
+    return retVal
+}
@@ -0,0 +1,110 @@
+package db
+
+import (
+    "reflect"
+    "testing"
+
+    "racfudit/decode"
+)
+
+func TestDecodeCustomValue(t *testing.T) {
+    for _, tc := range []struct {
+        cfdtype string
+        data    []byte
+        want    string
+    }{
+        {"CHAR", testEBCDIC("JOHN    "), "JOHN"},
+        {"NUM", []byte{0x00, 0x00, 0x30, 0x39}, "12345"},
+        {"NUM", []byte{}, ""},
+        {"NUM", testEBCDIC("42"), "42"},
+        {"FLAG", []byte{0x80}, "YES"},
+        {"FLAG", []byte{0x00}, "NO"},
+        {"FLAG", []byte{0x00, 0x00}, "NO"},
+        {"FLAG", testEBCDIC("YES"), "YES"},
+        {"HEX", []byte{0x00, 0xab, 0x01}, "00AB01"},
+        {"HEX", testEBCDIC("C1F0"), "C1F0"},
+    } {
+        if got := decodeCustomValue(tc.cfdtype, tc.data); got != tc.want {
+            t.Errorf("decodeCustomValue(%s, %x) = %q, want %q", tc.cfdtype, tc.data, got, tc.want)
+        }
+    }
+}
+
+func TestCustomFieldValidate(t *testing.T) {
+    for _, tc := range []struct {
+        cf    CustomField
+        value string
+        want  []string
+    }{
+        // MAXLENGTH is the number of characters (digits of NUM fields are limited by MINVALUE and MAXVALUE)
+        {CustomField{Type: "CHAR", MaxLen: 5}, "ABCDE", []string{}},
+        {CustomField{Type: "CHAR", MaxLen: 5}, "ABCDEF", []string{"length 6 exceeds maximum length 5"}},
+        {CustomField{Type: "HEX", MaxLen: 4}, "ABCDEF", []string{"length 6 exceeds maximum length 4"}},
+        {CustomField{Type: "NUM", MaxLen: 2}, "12345", []string{}},
+
+        // FIRST and OTHER character restrictions
+        {CustomField{Type: "CHAR", First: "ALPHA", Other: "NUMERIC"}, "A123", []string{}},
+        {CustomField{Type: "CHAR", First: "ALPHA", Other: "NUMERIC"}, "1234", []string{"character '1' at position 1 is not ALPHA"}},
+        {CustomField{Type: "CHAR", First: "ALPHA", Other: "NUMERIC"}, "A12B", []string{"character 'B' at position 4 is not NUMERIC"}},
+        {CustomField{Type: "CHAR", First: "NONATABC", Other: "NONATNUM"}, "#A1@", []string{}},
+        {CustomField{Type: "CHAR", First: "ALPHA", Other: "ALPHANUM"}, "A1#", []string{"character '#' at position 3 is not ALPHANUM"}},
+        {CustomField{Type: "CHAR", First: "ANY", Other: "ANY"}, "-X Y", []string{}},
+
+        // MIXED allows lower case characters
+        {CustomField{Type: "CHAR"}, "John", []string{"mixed case value is not allowed"}},
+        {CustomField{Type: "CHAR", Mixed: true}, "John", []string{}},
+
+        // Type and range of NUM, FLAG and HEX values
+        {CustomField{Type: "NUM", MinVal: -10, MaxVal: 100}, "-10", []string{}},
+        {CustomField{Type: "NUM", MinVal: -10, MaxVal: 100}, "101", []string{"101 is out of range -10..100"}},
+        {CustomField{Type: "NUM"}, "12A", []string{`"12A" is not a number`}},
+        {CustomField{Type: "FLAG"}, "NO", []string{}},
+        {CustomField{Type: "FLAG"}, "Y", []string{`"Y" is not YES or NO`}},
+        {CustomField{Type: "HEX"}, "ABC", []string{}},
+        {CustomField{Type: "HEX"}, "XYZ", []string{`"XYZ" is not a hex value`}},
+    } {
+        if got := tc.cf.Validate(tc.value); !reflect.DeepEqual(got, tc.want) {
+            t.Errorf("Validate(%q) of %+v = %q, want %q", tc.value, tc.cf, got, tc.want)
+        }
+    }
+}
+
+func TestDecodeCustomFields(t *testing.T) {
+    cfield := NewProfile("CFIELD  USER.CSDATA.EMPSER", "GENERAL", 5)
+    testSegmentOf(cfield, "CFDEF", testFields{
+        {"CFDTYPE", decode.Flag{0x04}},
+        {"CFMXLEN", uint32(8)},
+        {"CFMXVAL", uint32(99999)},
+        {"CFMNVAL", uint32(1)},
+    })
+    schema := BuildCustomFieldSchema([]*Profile{cfield})
+    if want := []*CustomField{{Name: "EMPSER", ProfileType: "USER", Type: "NUM", MaxLen: 8, MaxVal: 99999, MinVal: 1}}; !reflect.DeepEqual(schema["USER"], want) {
+        t.Errorf("Schema of USER custom fields = %+v, want %+v", schema["USER"], want)
+    }
+
+    user := NewProfile("IBMUSER", "USER", 2)
+    testSegmentOf(user, "CSDATA", testFields{{"CSCNT", []testFields{
+        {{"CSKEY", "EMPSER"}, {"CSTYPE", decode.Flag{0x01}}, {"CSVALUE", decode.HexStr{0x00, 0x01, 0x86, 0xa0}}},
+        {{"CSKEY", "BADGE"}, {"CSTYPE", decode.Flag{0x03}}, {"CSVALUE", decode.HexStr{0x00, 0x0f}}},
+    }}})
+    values := DecodeCustomFields(user, schema)
+
+    // Type of CFDEF is used for defined field (the value is out of range), type of CSDATA for undefined one
+    for _, tc := range []struct {
+        name   string
+        value  string
+        errors []string
+    }{
+        {"EMPSER", "100000", []string{"100000 is out of range 1..99999"}},
+        {"BADGE", "000F", []string{"custom field is not defined in CFIELD class"}},
+    } {
+        cv, ok := values[tc.name]
+        if !ok {
+            t.Errorf("Custom field %s is missing", tc.name)
+            continue
+        }
+        if cv.Value != tc.value || !reflect.DeepEqual(cv.Errors, tc.errors) {
+            t.Errorf("Custom field %s = %q, %q; want %q, %q", tc.name, cv.Value, cv.Errors, tc.value, tc.errors)
+        }
+    }
+}
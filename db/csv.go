@@ -0,0 +1,191 @@
+package db
+
+import (
+    "archive/zip"
+    "encoding/csv"
+    "encoding/json"
+    "fmt"
+    "io"
+    "os"
+    "path/filepath"
+    "reflect"
+    "strings"
+    "time"
+
+    "racfudit/common"
+)
+
+// CSV files output: directory or zip archive
+type csvOutput interface {
+    Create(name string) (io.Writer, error)
+    Close() error
+}
+
+type csvDirOutput struct {
+    dir string
+    f   *os.File
+}
+
+func (o *csvDirOutput) Create(name string) (io.Writer, error) {
+    if o.f != nil {
+        o.f.Close()
+    }
+    var err error
+    o.f, err = os.Create(filepath.Join(o.dir, name))
+    return o.f, err
+}
+
+func (o *csvDirOutput) Close() error {
+    if o.f != nil {
+        return o.f.Close()
+    }
+    return nil
+}
+
+type csvZipOutput struct {
+    f *os.File
+    w *zip.Writer
+}
+
+func (o *csvZipOutput) Create(name string) (io.Writer, error) {
+    return o.w.CreateHeader(&zip.FileHeader{Name: name, Method: zip.Deflate, Modified: time.Now()})
+}
+
+func (o *csvZipOutput) Close() error {
+    if err := o.w.Close(); err != nil {
+        o.f.Close()
+        return err
+    }
+    return o.f.Close()
+}
+
+// Create CSV output. If the name has .zip extension then zip archive is created, otherwise directory
+func newCSVOutput(name string) (csvOutput, error) {
+    if strings.EqualFold(filepath.Ext(name), ".zip") {
+        f, err := os.Create(name)
+        if err != nil {
+            return nil, err
+        }
+        return &csvZipOutput{f, zip.NewWriter(f)}, nil
+    }
+    if err := os.MkdirAll(name, 0755); err != nil {
+        return nil, err
+    }
+    return &csvDirOutput{dir: name}, nil
+}
+
+// Manifest entry describing CSV file
+type CSVManifestFile struct {
+    File        string              `json:"file"`
+    Table       string              `json:"table"`
+    ProfileType string              `json:"profileType"`
+    Segment     string              `json:"segment"`
+    RepeatGroup string              `json:"repeatGroup,omitempty"`
+    Parent      string              `json:"parent,omitempty"`
+    Key         []string            `json:"key"`
+    Rows        int                 `json:"rows"`
+    Columns     []CSVManifestColumn `json:"columns"`
+}
+
+type CSVManifestColumn struct {
+    Name   string `json:"name"`
+    Kind   string `json:"kind"`
+    GoType string `json:"goType"`
+}
+
+// Write CSV file of the table and get its manifest entry
+func writeCSVTable(out csvOutput, t *Table, profiles []*Profile) (*CSVManifestFile, error) {
+    mf := &CSVManifestFile{
+        File:        t.Name + ".csv",
+        Table:       t.Name,
+        ProfileType: t.ProfileType,
+        Segment:     t.Segment,
+        RepeatGroup: t.RepeatGroup,
+        Parent:      t.Parent,
+    }
+    // Profile name isn't unique (e.g. discrete data set profiles on several volumes), so segment rows are keyed
+    // by the segment offset too and RepeatGroup rows refer to the parent segment row by its offset
+    if len(t.RepeatGroup) == 0 {
+        mf.Key = []string{"ProfileName", "Offset"}
+        mf.Columns = []CSVManifestColumn{{"ProfileName", "string", "string"}, {"Offset", "string", "string"}, {"RawData", "hex", "string"}}
+    } else {
+        mf.Key = []string{"ProfileName", "Offset", "ItemIndex"}
+        mf.Columns = []CSVManifestColumn{{"ProfileName", "string", "string"}, {"Offset", "string", "string"}, {"ItemIndex", "integer", "int"}}
+    }
+    for _, c := range t.Columns {
+        mf.Columns = append(mf.Columns, CSVManifestColumn{c.Name, c.Kind(), c.Type.String()})
+    }
+
+    f, err := out.Create(mf.File)
+    if err != nil {
+        return nil, err
+    }
+    w := csv.NewWriter(f)
+    header := make([]string, len(mf.Columns))
+    for i, c := range mf.Columns {
+        header[i] = c.Name
+    }
+    w.Write(header)
+
+    for _, p := range profiles {
+        for _, row := range t.Rows(p) {
+            record := []string{strings.TrimSpace(p.Name), row.Segment.Address.String()}
+            if len(t.RepeatGroup) == 0 {
+                record = append(record, row.Segment.Raw)
+            } else {
+                record = append(record, fmt.Sprintf("%d", row.Index))
+            }
+            for _, v := range row.Values {
+                record = append(record, DumpField(v))
+            }
+            w.Write(record)
+            mf.Rows++
+        }
+    }
+    w.Flush()
+    return mf, w.Error()
+}
+
+// Write runtime DB as CSV files (one per profile type and segment and one per RepeatGroup)
+// into directory or zip archive with manifest.json describing the columns
+func WriteCSV(profiles []*Profile, name string, profileStructs map[string]map[string]reflect.Type) error {
+    out, err := newCSVOutput(name)
+    if err != nil {
+        return fmt.Errorf("Can not create CSV output: %v", err)
+    }
+
+    manifest := make([]*CSVManifestFile, 0)
+    for _, t := range NewTables(profileStructs) {
+        common.Log.Debug("Saving table %s as CSV file", t.Name)
+        mf, err := writeCSVTable(out, t, profiles)
+        if err != nil {
+            out.Close()
+            return fmt.Errorf("Can not save CSV file of table %s: %v", t.Name, err)
+        }
+        manifest = append(manifest, mf)
+    }
+
+    f, err := out.Create("manifest.json")
+    if err != nil {
+        out.Close()
+        return fmt.Errorf("Can not create CSV manifest: %v", err)
+    }
+    enc := json.NewEncoder(f)
+    enc.SetIndent("", "  ")
+    if err := enc.Encode(map[string]interface{}{"files": manifest}); err != nil {
+        out.Close()
+        return fmt.Errorf("Can not save CSV manifest: %v", err)
+    }
+    if err := out.Close(); err != nil {
+        return fmt.Errorf("Can not save CSV output: %v", err)
+    }
+    return nil
+}
+
+// Save runtime DB as CSV files into directory or zip archive (see WriteCSV)
+func ToCSV(profiles []*Profile, name string, profileStructs map[string]map[string]reflect.Type) {
+    common.Log.Info("Saving RACF profiles as CSV files in %s", name)
+    if err := WriteCSV(profiles, name, profileStructs); err != nil {
+        common.Fatal(err)
+    }
+}
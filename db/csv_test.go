@@ -0,0 +1,129 @@
+package db
+
+import (
+    "encoding/csv"
+    "encoding/json"
+    "os"
+    "path/filepath"
+    "reflect"
+    "strings"
+    "testing"
+
+    "racfudit/decode"
+)
+
+// Read files of CSV output directory
+func testReadDir(t *testing.T, name string) map[string]string {
+    entries, err := os.ReadDir(name)
+    if err != nil {
+        t.Fatal(err)
+    }
+    retVal := make(map[string]string)
+    for _, e := range entries {
+        b, err := os.ReadFile(filepath.Join(name, e.Name()))
+        if err != nil {
+            t.Fatal(err)
+        }
+        retVal[e.Name()] = string(b)
+    }
+    return retVal
+}
+
+func TestWriteCSV(t *testing.T) {
+    // Discrete profiles of the same data set on two volumes have the same name
+    var baseT reflect.Type
+    profiles := make([]*Profile, 0)
+    for _, tc := range []struct {
+        volser string
+        offset decode.Address
+    }{
+        {"VOL001", 0x1000},
+        {"VOL002", 0x2000},
+    } {
+        p := NewProfile("SYS1.DATA", "DATASET", 4)
+        baseT = testSegmentOf(p, "BASE", testFields{
+            {"AUTHOR", "IBMUSER"},
+            {"VOLCNT", []testFields{{{"VOLSER", tc.volser}}}},
+        })
+        p.Segments[0].Address = tc.offset
+        profiles = append(profiles, p)
+    }
+    profileStructs := map[string]map[string]reflect.Type{"DATASET": {"BASE": baseT}}
+
+    wantFiles := map[string][][]string{
+        "DATASET_BASE.csv": {
+            {"ProfileName", "Offset", "RawData", "AUTHOR", "VOLCNT"},
+            {"SYS1.DATA", "0x00001000", "", "IBMUSER", "1"},
+            {"SYS1.DATA", "0x00002000", "", "IBMUSER", "1"},
+        },
+        "DATASET_BASE_VOLCNT.csv": {
+            {"ProfileName", "Offset", "ItemIndex", "VOLSER"},
+            {"SYS1.DATA", "0x00001000", "0", "VOL001"},
+            {"SYS1.DATA", "0x00002000", "0", "VOL002"},
+        },
+    }
+    wantManifest := []CSVManifestFile{
+        {File: "DATASET_BASE.csv", Table: "DATASET_BASE", ProfileType: "DATASET", Segment: "BASE",
+            Key: []string{"ProfileName", "Offset"}, Rows: 2},
+        {File: "DATASET_BASE_VOLCNT.csv", Table: "DATASET_BASE_VOLCNT", ProfileType: "DATASET", Segment: "BASE",
+            RepeatGroup: "VOLCNT", Parent: "DATASET_BASE", Key: []string{"ProfileName", "Offset", "ItemIndex"}, Rows: 2},
+    }
+
+    dir := t.TempDir()
+    for _, tc := range []struct {
+        name  string
+        files func(name string) map[string]string
+    }{
+        {filepath.Join(dir, "racfdb"), func(name string) map[string]string { return testReadDir(t, name) }},
+        {filepath.Join(dir, "racfdb.zip"), func(name string) map[string]string {
+            data, err := os.ReadFile(name)
+            if err != nil {
+                t.Fatal(err)
+            }
+            return testUnzip(t, data)
+        }},
+    } {
+        if err := WriteCSV(profiles, tc.name, profileStructs); err != nil {
+            t.Fatal(err)
+        }
+        files := tc.files(tc.name)
+        if len(files) != len(wantFiles)+1 {
+            t.Errorf("%s: %d files, want %d", tc.name, len(files), len(wantFiles)+1)
+        }
+        for file, want := range wantFiles {
+            got, err := csv.NewReader(strings.NewReader(files[file])).ReadAll()
+            if err != nil {
+                t.Errorf("%s: %s: %v", tc.name, file, err)
+                continue
+            }
+            if !reflect.DeepEqual(got, want) {
+                t.Errorf("%s: %s = %q, want %q", tc.name, file, got, want)
+            }
+        }
+
+        var manifest struct {
+            Files []CSVManifestFile `json:"files"`
+        }
+        if err := json.Unmarshal([]byte(files["manifest.json"]), &manifest); err != nil {
+            t.Fatalf("%s: manifest.json: %v", tc.name, err)
+        }
+        if len(manifest.Files) != len(wantManifest) {
+            t.Fatalf("%s: manifest has %d files, want %d", tc.name, len(manifest.Files), len(wantManifest))
+        }
+        for i, want := range wantManifest {
+            // Manifest columns match the CSV header
+            header := make([]string, 0)
+            for _, c := range manifest.Files[i].Columns {
+                header = append(header, c.Name)
+            }
+            if wantHeader := wantFiles[want.File][0]; !reflect.DeepEqual(header, wantHeader) {
+                t.Errorf("%s: manifest columns of %s = %q, want %q", tc.name, want.File, header, wantHeader)
+            }
+            got := manifest.Files[i]
+            got.Columns = nil
+            if !reflect.DeepEqual(got, want) {
+                t.Errorf("%s: manifest file %d = %+v, want %+v", tc.name, i, got, want)
+            }
+        }
+    }
+}
@@ -0,0 +1,397 @@
+package db
+
+import (
+    "bufio"
+    "encoding/json"
+    "fmt"
+    "io"
+    "strings"
+    "time"
+
+    "racfudit/common"
+)
+
+// Action line of bulk API request
+type ESBulkAction struct {
+    Index struct {
+        Index string `json:"_index"`
+        ID    string `json:"_id"`
+    } `json:"index"`
+}
+
+// Document of bulk API request with ECS fields (event.*, user.*, group.*) and RACF specific fields (racf.*)
+type ESDocument struct {
+    Timestamp string                 `json:"@timestamp"`
+    Event     ESEvent                `json:"event"`
+    User      *ESUser                `json:"user,omitempty"`
+    Group     *ESGroup               `json:"group,omitempty"`
+    RACF      map[string]interface{} `json:"racf"`
+}
+
+type ESEvent struct {
+    Kind     string   `json:"kind"`
+    Category []string `json:"category"`
+    Type     []string `json:"type"`
+    Module   string   `json:"module"`
+    Dataset  string   `json:"dataset"`
+}
+
+type ESUser struct {
+    ID       string   `json:"id,omitempty"`
+    Name     string   `json:"name"`
+    FullName string   `json:"full_name,omitempty"`
+    Email    string   `json:"email,omitempty"`
+    Roles    []string `json:"roles,omitempty"`
+    Group    *ESGroup `json:"group,omitempty"`
+}
+
+type ESGroup struct {
+    ID   string `json:"id,omitempty"`
+    Name string `json:"name"`
+}
+
+// Writer of bulk API NDJSON
+type esBulkWriter struct {
+    enc       *json.Encoder
+    prefix    string
+    timestamp string
+    count     map[string]int
+}
+
+func (b *esBulkWriter) write(index string, id string, doc *ESDocument) {
+    var action ESBulkAction
+    action.Index.Index, action.Index.ID = b.prefix+"-"+index, id
+    doc.Timestamp = b.timestamp
+    doc.Event.Kind, doc.Event.Module, doc.Event.Dataset = "state", "racf", "racf."+index
+    doc.Event.Category = []string{"iam"}
+    if err := b.enc.Encode(&action); err != nil {
+        common.Fatal(fmt.Errorf("Can not save bulk action of %s: %v", id, err))
+    }
+    if err := b.enc.Encode(doc); err != nil {
+        common.Fatal(fmt.Errorf("Can not save document %s: %v", id, err))
+    }
+    b.count[index]++
+}
+
+// Date fields of racf.* objects (mapped as dates by index template)
+var esDateFields = map[string][]string{
+    "user":     {"created", "last_access", "password_date", "revoke_date", "resume_date"},
+    "connects": {"created", "last_connect"},
+    "group":    {"created"},
+    "resource": {"created", "last_reference", "last_change"},
+}
+
+// Format date field as YYYY-MM-DD (nil if the date isn't set, so the field is null in the document)
+func esDate(s *Segment, name string) interface{} {
+    if d := unloadDate(s, name); len(d) > 0 {
+        return d
+    }
+    return nil
+}
+
+// Get user attributes (SPECIAL, OPERATIONS, AUDITOR, ROAUDIT) as ECS roles
+func esUserRoles(s *Segment) []string {
+    retVal := make([]string, 0)
+    for _, a := range []struct {
+        name  string
+        field string
+    }{{"SPECIAL", "FLAG2"}, {"OPERATIONS", "FLAG3"}, {"AUDITOR", "FLAG6"}, {"ROAUDIT", "FLAGROA"}} {
+        if isFlagSet(s, a.field, 0x80) {
+            retVal = append(retVal, a.name)
+        }
+    }
+    return retVal
+}
+
+func esUserDocument(p *Profile, authorities map[string]map[string]string) *ESDocument {
+    name := strings.TrimSpace(p.Name)
+    doc := &ESDocument{User: &ESUser{Name: name}, RACF: make(map[string]interface{})}
+    doc.Event.Type = []string{"user", "info"}
+    racf := make(map[string]interface{})
+    if s, ok := p.Segment("BASE"); ok {
+        doc.User.FullName, doc.User.Roles = s.FieldString("PGMRNAME"), esUserRoles(s)
+        if group := s.FieldString("DFLTGRP"); len(group) > 0 {
+            doc.User.Group = &ESGroup{Name: group}
+        }
+        racf["owner"] = s.FieldString("AUTHOR")
+        racf["created"] = esDate(s, "AUTHDATE")
+        racf["last_access"] = esDate(s, "LJDATE")
+        racf["password_date"] = esDate(s, "PASSDATE")
+        racf["password_interval"] = s.FieldUint("PASSINT")
+        racf["revoked"] = isFlagSet(s, "FLAG4", 0x80)
+        racf["protected"] = isFlagSet(s, "FLAG7", 0x80)
+        racf["uaudit"] = isFlagSet(s, "UAUDIT", 0x80)
+        racf["installation_data"] = s.FieldString("INSTDATA")
+        racf["revoke_date"] = esDate(s, "REVOKEDT")
+        racf["resume_date"] = esDate(s, "RESUMEDT")
+
+        connects := make([]map[string]interface{}, 0)
+        for _, item := range s.Items("CGGRPCT") {
+            group := item.FieldString("CGGRPNM")
+            if len(group) == 0 {
+                continue
+            }
+            authority := "USE"
+            if a, ok := authorities[group][name]; ok {
+                authority = a
+            }
+            connects = append(connects, map[string]interface{}{
+                "group":        group,
+                "authority":    authority,
+                "owner":        item.FieldString("CGAUTHOR"),
+                "created":      esDate(item, "CGAUTHDA"),
+                "last_connect": esDate(item, "CGLJDATE"),
+                "count":        item.FieldUint("CGINITCT"),
+                "uacc":         unloadAccess(item, "CGUACC"),
+                "special":      isFlagSet(item, "CGFLAG2", 0x80),
+                "operations":   isFlagSet(item, "CGFLAG3", 0x80),
+                "auditor":      isFlagSet(item, "CGGRPAUD", 0x80),
+                "revoked":      isFlagSet(item, "CGFLAG4", 0x80),
+            })
+        }
+        racf["connects"] = connects
+    }
+    if s, ok := p.Segment("OMVS"); ok {
+        if _, ok := s.Field("UID"); ok {
+            doc.User.ID = fmt.Sprintf("%d", s.FieldUint("UID"))
+        }
+        racf["omvs"] = map[string]interface{}{"home": s.FieldString("HOME"), "program": s.FieldString("PROGRAM")}
+    }
+    if s, ok := p.Segment("WORKATTR"); ok {
+        doc.User.Email = s.FieldString("WAEMAIL")
+    }
+    segments := make([]string, 0)
+    for _, s := range p.Segments {
+        segments = append(segments, s.Name)
+    }
+    racf["segments"] = segments
+    doc.RACF["user"] = racf
+    return doc
+}
+
+func esGroupDocument(p *Profile) *ESDocument {
+    name := strings.TrimSpace(p.Name)
+    doc := &ESDocument{Group: &ESGroup{Name: name}, RACF: make(map[string]interface{})}
+    doc.Event.Type = []string{"group", "info"}
+    racf := make(map[string]interface{})
+    if s, ok := p.Segment("BASE"); ok {
+        racf["superior_group"] = s.FieldString("SUPGROUP")
+        racf["owner"] = s.FieldString("AUTHOR")
+        racf["created"] = esDate(s, "AUTHDATE")
+        racf["installation_data"] = s.FieldString("INSTDATA")
+        racf["universal"] = isFlagSet(s, "UNVFLG", 0x80)
+        subgroups := make([]string, 0)
+        for _, item := range s.Items("SUBGRPCT") {
+            if v := item.FieldString("SUBGRPNM"); len(v) > 0 {
+                subgroups = append(subgroups, v)
+            }
+        }
+        racf["subgroups"] = subgroups
+        members := make([]map[string]interface{}, 0)
+        for _, item := range s.Items("ACLCNT") {
+            if v := item.FieldString("USERID"); len(v) > 0 {
+                members = append(members, map[string]interface{}{"user": v, "authority": GroupAuthority(item.FieldBytes("USERACS"))})
+            }
+        }
+        racf["members"] = members
+    }
+    if s, ok := p.Segment("OMVS"); ok {
+        if _, ok := s.Field("GID"); ok {
+            doc.Group.ID = fmt.Sprintf("%d", s.FieldUint("GID"))
+        }
+    }
+    doc.RACF["group"] = racf
+    return doc
+}
+
+// Get fields of data set or general resource profile
+func esResource(p *Profile) map[string]interface{} {
+    name, class := strings.TrimSpace(p.Name), p.Class()
+    if p.Type.Name == "GENERAL" {
+        name = p.ResourceName()
+    }
+    return map[string]interface{}{"name": name, "class": class, "type": p.Type.Name, "generic": isGenericName(name)}
+}
+
+func esResourceDocument(p *Profile) *ESDocument {
+    doc := &ESDocument{RACF: make(map[string]interface{})}
+    doc.Event.Type = []string{"info"}
+    racf := esResource(p)
+    if s, ok := p.Segment("BASE"); ok {
+        owner, created := "AUTHOR", "CREADATE"
+        if p.Type.Name == "GENERAL" {
+            owner, created = "OWNER", "DEFDATE"
+        }
+        racf["owner"] = s.FieldString(owner)
+        racf["created"] = esDate(s, created)
+        racf["last_reference"] = esDate(s, "LREFDAT")
+        racf["last_change"] = esDate(s, "LCHGDAT")
+        racf["uacc"] = p.UACC()
+        racf["warning"] = isFlagSet(s, "WARNING", 0x80)
+        racf["level"] = s.FieldUint("LEVEL")
+        racf["audit"] = listAudit(s, "AUDIT", "AUDITQS", "AUDITQF")
+        racf["global_audit"] = listAudit(s, "GAUDIT", "GAUDITQS", "GAUDITQF")
+        racf["notify"] = s.FieldString("NOTIFY")
+        racf["installation_data"] = s.FieldString("INSTDATA")
+        racf["access_list_size"] = len(p.ACL())
+        racf["conditional_access_list_size"] = len(p.ConditionalACL())
+    }
+    doc.RACF["resource"] = racf
+    return doc
+}
+
+// Get document of access list entry. The entry ID is set as user.name or group.name
+func esAccessDocument(p *Profile, e ACLEntry, condition string, groups map[string]bool) *ESDocument {
+    doc := &ESDocument{RACF: make(map[string]interface{})}
+    doc.Event.Type = []string{"access", "info"}
+    idType := "user"
+    switch {
+    case e.ID == "*":
+        idType = "all"
+    case groups[e.ID]:
+        idType = "group"
+        doc.Group = &ESGroup{Name: e.ID}
+    default:
+        doc.User = &ESUser{Name: e.ID}
+    }
+    doc.RACF["resource"] = esResource(p)
+    doc.RACF["access"] = map[string]interface{}{
+        "id":          e.ID,
+        "id_type":     idType,
+        "level":       e.Access,
+        "count":       e.Count,
+        "conditional": len(condition) > 0,
+        "condition":   condition,
+    }
+    return doc
+}
+
+// Write profiles as bulk API NDJSON: users, groups, data set and general resource profiles and their access list entries
+// are indexed into <prefix>-users, <prefix>-groups, <prefix>-resources and <prefix>-access. Returns number of documents per index
+func WriteElasticBulk(w io.Writer, profiles []*Profile, prefix string) map[string]int {
+    b := &esBulkWriter{enc: json.NewEncoder(w), prefix: prefix, timestamp: time.Now().UTC().Format(time.RFC3339), count: make(map[string]int)}
+    authorities := make(map[string]map[string]string)
+    groups := make(map[string]bool)
+    for _, p := range profiles {
+        if p.Type.Name != "GROUP" {
+            continue
+        }
+        name := strings.TrimSpace(p.Name)
+        groups[name] = true
+        authorities[name] = make(map[string]string)
+        if s, ok := p.Segment("BASE"); ok {
+            for _, item := range s.Items("ACLCNT") {
+                authorities[name][item.FieldString("USERID")] = GroupAuthority(item.FieldBytes("USERACS"))
+            }
+        }
+    }
+
+    for _, p := range profiles {
+        name := strings.TrimSpace(p.Name)
+        switch p.Type.Name {
+        case "USER":
+            b.write("users", name, esUserDocument(p, authorities))
+        case "GROUP":
+            b.write("groups", name, esGroupDocument(p))
+        case "DATASET", "GENERAL":
+            id := p.Class() + ":" + name
+            if p.Type.Name == "GENERAL" {
+                id = p.Class() + ":" + p.ResourceName()
+            }
+            b.write("resources", id, esResourceDocument(p))
+            for _, e := range p.ACL() {
+                if len(e.ID) > 0 {
+                    b.write("access", id+":"+e.ID, esAccessDocument(p, e, "", groups))
+                }
+            }
+            for _, e := range p.ConditionalACL() {
+                if len(e.ID) > 0 {
+                    b.write("access", id+":"+e.ID+":"+e.Condition, esAccessDocument(p, e.ACLEntry, e.Condition, groups))
+                }
+            }
+        }
+    }
+    return b.count
+}
+
+// Get index template (body of PUT _index_template/<prefix>) for indices of bulk NDJSON.
+// Strings are mapped as keywords, user.full_name has text subfield as in ECS. Date detection is disabled
+// (otherwise a string like installation data "2024-01-01" in the first document would map the field as date),
+// so date fields are mapped explicitly. Connects of a user are nested to query group and attributes of the same connect
+func NewElasticTemplate(prefix string) map[string]interface{} {
+    keyword := map[string]interface{}{"type": "keyword", "ignore_above": 1024}
+    text := map[string]interface{}{"type": "keyword", "ignore_above": 1024, "fields": map[string]interface{}{"text": map[string]interface{}{"type": "text"}}}
+    object := func(props map[string]interface{}) map[string]interface{} {
+        return map[string]interface{}{"properties": props}
+    }
+    dates := func(name string) map[string]interface{} {
+        retVal := make(map[string]interface{})
+        for _, field := range esDateFields[name] {
+            retVal[field] = map[string]interface{}{"type": "date", "format": "strict_date_optional_time||yyyy-MM-dd"}
+        }
+        return retVal
+    }
+    group := object(map[string]interface{}{"id": keyword, "name": keyword})
+    user := object(dates("user"))
+    user["properties"].(map[string]interface{})["connects"] = map[string]interface{}{"type": "nested", "properties": dates("connects")}
+    return map[string]interface{}{
+        "index_patterns": []string{prefix + "-*"},
+        "priority":       200,
+        "template": map[string]interface{}{
+            "settings": map[string]interface{}{"number_of_shards": 1},
+            "mappings": map[string]interface{}{
+                "date_detection": false,
+                "dynamic_templates": []interface{}{
+                    map[string]interface{}{"strings_as_keywords": map[string]interface{}{"match_mapping_type": "string", "mapping": keyword}},
+                },
+                "properties": map[string]interface{}{
+                    "@timestamp": map[string]interface{}{"type": "date"},
+                    "event": object(map[string]interface{}{
+                        "kind": keyword, "category": keyword, "type": keyword, "module": keyword, "dataset": keyword,
+                    }),
+                    "user": object(map[string]interface{}{
+                        "id": keyword, "name": keyword, "full_name": text, "email": keyword, "roles": keyword, "group": group,
+                    }),
+                    "group": group,
+                    "racf": object(map[string]interface{}{
+                        "user": user, "group": object(dates("group")), "resource": object(dates("resource")),
+                    }),
+                },
+            },
+        },
+    }
+}
+
+// Save profiles as Elasticsearch/OpenSearch bulk API NDJSON
+func ToElasticBulk(profiles []*Profile, fileName string, prefix string) {
+    f, err := createOutput(fileName)
+    if err != nil {
+        common.Fatal(fmt.Errorf("Can not create bulk NDJSON file: %v", err))
+    }
+    defer f.Close()
+
+    common.Log.Info("Saving RACF profiles as bulk NDJSON file %s (indices %s-*)", fileName, prefix)
+    w := bufio.NewWriter(f)
+    count := WriteElasticBulk(w, profiles, prefix)
+    if err := w.Flush(); err != nil {
+        common.Fatal(fmt.Errorf("Can not save bulk NDJSON file: %v", err))
+    }
+    common.Log.Info("Saved %d users, %d groups, %d resources and %d access list entries",
+        count["users"], count["groups"], count["resources"], count["access"])
+}
+
+// Save index template of bulk NDJSON indices
+func ToElasticTemplate(fileName string, prefix string) {
+    f, err := createOutput(fileName)
+    if err != nil {
+        common.Fatal(fmt.Errorf("Can not create index template file: %v", err))
+    }
+    defer f.Close()
+
+    common.Log.Info("Saving index template of %s-* indices as file %s", prefix, fileName)
+    enc := json.NewEncoder(f)
+    enc.SetIndent("", "  ")
+    if err := enc.Encode(NewElasticTemplate(prefix)); err != nil {
+        common.Fatal(fmt.Errorf("Can not save index template file: %v", err))
+    }
+}
@@ -0,0 +1,212 @@
+package db
+
+import (
+    "bufio"
+    "bytes"
+    "encoding/json"
+    "reflect"
+    "strings"
+    "testing"
+
+    "racfudit/decode"
+)
+
+func TestElasticTemplateMappings(t *testing.T) {
+    data, err := json.Marshal(NewElasticTemplate("racf"))
+    if err != nil {
+        t.Fatal(err)
+    }
+    var tmpl struct {
+        Template struct {
+            Mappings struct {
+                DateDetection *bool `json:"date_detection"`
+                Properties    struct {
+                    RACF struct {
+                        Properties map[string]struct {
+                            Properties map[string]struct {
+                                Type       string                            `json:"type"`
+                                Properties map[string]map[string]interface{} `json:"properties"`
+                            } `json:"properties"`
+                        } `json:"properties"`
+                    } `json:"racf"`
+                } `json:"properties"`
+            } `json:"mappings"`
+        } `json:"template"`
+    }
+    if err := json.Unmarshal(data, &tmpl); err != nil {
+        t.Fatal(err)
+    }
+    mappings := tmpl.Template.Mappings
+    if mappings.DateDetection == nil || *mappings.DateDetection {
+        t.Errorf("date_detection must be disabled")
+    }
+    racf := mappings.Properties.RACF.Properties
+    for _, object := range []string{"user", "group", "resource"} {
+        for _, field := range esDateFields[object] {
+            if typ := racf[object].Properties[field].Type; typ != "date" {
+                t.Errorf("racf.%s.%s is mapped as %q, want date", object, field, typ)
+            }
+        }
+    }
+    connects := racf["user"].Properties["connects"]
+    if connects.Type != "nested" {
+        t.Errorf("racf.user.connects is mapped as %q, want nested", connects.Type)
+    }
+    for _, field := range esDateFields["connects"] {
+        if typ := connects.Properties[field]["type"]; typ != "date" {
+            t.Errorf("racf.user.connects.%s is mapped as %v, want date", field, typ)
+        }
+    }
+}
+
+func TestWriteElasticBulk(t *testing.T) {
+    date := decode.Date{0x24, 0x00, 0x2f}
+
+    group := NewProfile("SYS1", "GROUP", 1)
+    testSegmentOf(group, "BASE", testFields{
+        {"SUPGROUP", "SYS"},
+        {"AUTHOR", "IBMUSER"},
+        {"AUTHDATE", date},
+        {"ACLCNT", []testFields{{{"USERID", "IBMUSER"}, {"USERACS", decode.Flag{0x40}}}}},
+    })
+    testSegmentOf(group, "OMVS", testFields{{"GID", uint32(0)}})
+
+    user := NewProfile("IBMUSER", "USER", 2)
+    testSegmentOf(user, "BASE", testFields{
+        {"AUTHDATE", date},
+        {"AUTHOR", "SYS1"},
+        {"LJDATE", decode.Date{0x00, 0x00, 0x00}},
+        {"FLAG2", decode.Flag{0x80}},
+        {"PGMRNAME", "JOHN SMITH"},
+        {"DFLTGRP", "SYS1"},
+        {"CGGRPCT", []testFields{{{"CGGRPNM", "SYS1"}, {"CGAUTHDA", date}, {"CGLJDATE", decode.Date{0x00, 0x00, 0x00}}}}},
+    })
+    testSegmentOf(user, "OMVS", testFields{{"UID", uint32(0)}, {"HOME", "/u/ibmuser"}})
+    testSegmentOf(user, "WORKATTR", testFields{{"WAEMAIL", "ibmuser@example.com"}})
+
+    dataset := NewProfile("SYS1.**", "DATASET", 4)
+    testSegmentOf(dataset, "BASE", testFields{
+        {"CREADATE", date},
+        {"AUTHOR", "IBMUSER"},
+        {"UNIVACS", decode.Flag{0x10}},
+        {"ACLCNT", []testFields{
+            {{"USERID", "SYS1"}, {"USERACS", decode.Flag{0x80}}, {"ACSCNT", uint32(1)}},
+            {{"USERID", "IBMUSER"}, {"USERACS", decode.Flag{0x20}}, {"ACSCNT", uint32(2)}},
+            {{"USERID", "*"}, {"USERACS", decode.Flag{0x10}}, {"ACSCNT", uint32(3)}},
+        }},
+    })
+
+    general := NewProfile("FACILITYBPX.SUPERUSER", "GENERAL", 5)
+    testSegmentOf(general, "BASE", testFields{
+        {"DEFDATE", date},
+        {"OWNER", "SYS1"},
+        {"ACLCNT", []testFields{{{"USERID", "IBMUSER"}, {"USERACS", decode.Flag{0x10}}, {"ACSCNT", uint32(4)}}}},
+        {"ACL2CNT", []testFields{{{"ACL2UID", "SYS1"}, {"ACL2NAME", "CONSOLE"}, {"ACL2VAR", "MASTER"}, {"ACL2ACC", decode.Flag{0x10}}}}},
+    })
+
+    var buf bytes.Buffer
+    count := WriteElasticBulk(&buf, []*Profile{group, user, dataset, general}, "racf")
+    if want := map[string]int{"users": 1, "groups": 1, "resources": 2, "access": 5}; !reflect.DeepEqual(count, want) {
+        t.Errorf("WriteElasticBulk() = %v, want %v", count, want)
+    }
+
+    // Lines go in pairs: action line followed by the document
+    type bulkDocument struct {
+        index string
+        doc   map[string]interface{}
+    }
+    docs := make(map[string]bulkDocument)
+    ids := make([]string, 0)
+    scanner := bufio.NewScanner(&buf)
+    for scanner.Scan() {
+        var action ESBulkAction
+        if err := json.Unmarshal(scanner.Bytes(), &action); err != nil || len(action.Index.ID) == 0 {
+            t.Fatalf("Line %s isn't a bulk action (%v)", scanner.Text(), err)
+        }
+        if !scanner.Scan() {
+            t.Fatalf("Document of action %s is missing", action.Index.ID)
+        }
+        var doc map[string]interface{}
+        if err := json.Unmarshal(scanner.Bytes(), &doc); err != nil {
+            t.Fatalf("Document of action %s: %v", action.Index.ID, err)
+        }
+        ids = append(ids, action.Index.ID)
+        docs[action.Index.ID] = bulkDocument{action.Index.Index, doc}
+    }
+    wantIDs := []string{"SYS1", "IBMUSER", "DATASET:SYS1.**", "DATASET:SYS1.**:SYS1", "DATASET:SYS1.**:IBMUSER",
+        "DATASET:SYS1.**:*", "FACILITY:BPX.SUPERUSER", "FACILITY:BPX.SUPERUSER:IBMUSER",
+        "FACILITY:BPX.SUPERUSER:SYS1:WHEN(CONSOLE(MASTER))"}
+    if !reflect.DeepEqual(ids, wantIDs) {
+        t.Fatalf("Document IDs = %q, want %q", ids, wantIDs)
+    }
+
+    for _, tc := range []struct {
+        id     string
+        index  string
+        fields map[string]interface{} // Dot separated path of document field -> value (nil if the field is null)
+    }{
+        {"SYS1", "racf-groups", map[string]interface{}{
+            "event.dataset": "racf.groups", "group.name": "SYS1", "group.id": "0",
+            "racf.group.created": "2024-01-02", "racf.group.superior_group": "SYS"}},
+        {"IBMUSER", "racf-users", map[string]interface{}{
+            "event.dataset": "racf.users", "user.name": "IBMUSER", "user.id": "0", "user.full_name": "JOHN SMITH",
+            "user.email": "ibmuser@example.com", "user.roles": []interface{}{"SPECIAL"}, "user.group.name": "SYS1",
+            "racf.user.created": "2024-01-02", "racf.user.last_access": nil, "racf.user.password_date": nil}},
+        {"DATASET:SYS1.**", "racf-resources", map[string]interface{}{
+            "racf.resource.name": "SYS1.**", "racf.resource.class": "DATASET", "racf.resource.generic": true,
+            "racf.resource.created": "2024-01-02", "racf.resource.last_reference": nil, "racf.resource.uacc": "READ",
+            "racf.resource.access_list_size": 3.0}},
+        {"DATASET:SYS1.**:SYS1", "racf-access", map[string]interface{}{
+            "group.name": "SYS1", "racf.access.id_type": "group", "racf.access.level": "ALTER", "racf.access.count": 1.0}},
+        {"DATASET:SYS1.**:IBMUSER", "racf-access", map[string]interface{}{
+            "user.name": "IBMUSER", "racf.access.id_type": "user", "racf.access.level": "UPDATE"}},
+        {"DATASET:SYS1.**:*", "racf-access", map[string]interface{}{
+            "racf.access.id": "*", "racf.access.id_type": "all", "racf.access.level": "READ"}},
+        {"FACILITY:BPX.SUPERUSER", "racf-resources", map[string]interface{}{
+            "racf.resource.name": "BPX.SUPERUSER", "racf.resource.class": "FACILITY", "racf.resource.owner": "SYS1",
+            "racf.resource.created": "2024-01-02", "racf.resource.conditional_access_list_size": 1.0}},
+        {"FACILITY:BPX.SUPERUSER:SYS1:WHEN(CONSOLE(MASTER))", "racf-access", map[string]interface{}{
+            "group.name": "SYS1", "racf.access.id_type": "group", "racf.access.conditional": true,
+            "racf.access.condition": "WHEN(CONSOLE(MASTER))"}},
+    } {
+        d := docs[tc.id]
+        if d.index != tc.index {
+            t.Errorf("Document %s: index = %s, want %s", tc.id, d.index, tc.index)
+        }
+        for path, want := range tc.fields {
+            if got, ok := testDocumentField(d.doc, path); !ok || !reflect.DeepEqual(got, want) {
+                t.Errorf("Document %s: %s = %#v (found %v), want %#v", tc.id, path, got, ok, want)
+            }
+        }
+    }
+
+    // Access documents have either user or group set (none for *)
+    for id, ecs := range map[string][]string{
+        "DATASET:SYS1.**:SYS1":    {"group"},
+        "DATASET:SYS1.**:IBMUSER": {"user"},
+        "DATASET:SYS1.**:*":       {},
+    } {
+        for _, object := range []string{"user", "group"} {
+            _, ok := docs[id].doc[object]
+            want := len(ecs) > 0 && ecs[0] == object
+            if ok != want {
+                t.Errorf("Document %s: %s is set = %v, want %v", id, object, ok, want)
+            }
+        }
+    }
+}
+
+// Get field of decoded JSON document by dot separated path
+func testDocumentField(doc map[string]interface{}, path string) (interface{}, bool) {
+    var v interface{} = doc
+    for _, name := range strings.Split(path, ".") {
+        m, ok := v.(map[string]interface{})
+        if !ok {
+            return nil, false
+        }
+        if v, ok = m[name]; !ok {
+            return nil, false
+        }
+    }
+    return v, true
+}
@@ -0,0 +1,383 @@
+package db
+
+import (
+    "fmt"
+    "strings"
+
+    "racfudit/common"
+)
+
+// Node labels of RACF graph
+const (
+    NODE_USER     = "User"
+    NODE_GROUP    = "Group"
+    NODE_DATASET  = "Dataset"
+    NODE_RESOURCE = "Resource"
+)
+
+// Edge types of RACF graph
+const (
+    EDGE_MEMBER_OF     = "MEMBER_OF"     // User -> Group (connect with group authority)
+    EDGE_OWNS          = "OWNS"          // User or Group -> any node (owner of the profile)
+    EDGE_HAS_ACCESS    = "HAS_ACCESS"    // User or Group -> Dataset or Resource (access list entry)
+    EDGE_SUPGROUP_OF   = "SUPGROUP_OF"   // Group -> Group (superior group)
+    EDGE_SURROGATE_FOR = "SURROGATE_FOR" // User or Group -> User (SURROGAT class access)
+)
+
+var GraphEdgeTypes = []string{EDGE_MEMBER_OF, EDGE_OWNS, EDGE_HAS_ACCESS, EDGE_SUPGROUP_OF, EDGE_SURROGATE_FOR}
+
+// Node of RACF graph. ID is unique in the graph (<LABEL>:<NAME> or Resource:<CLASS>:<NAME>)
+type GraphNode struct {
+    ID         string
+    Label      string
+    Name       string
+    Properties map[string]interface{}
+}
+
+type GraphEdge struct {
+    Type       string
+    From       string // ID of source node
+    To         string // ID of target node
+    Properties map[string]interface{}
+}
+
+// Graph of users, groups, data set profiles and general resource profiles
+type Graph struct {
+    Nodes []*GraphNode
+    Edges []*GraphEdge
+    nodes map[string]*GraphNode
+}
+
+func graphNodeID(label string, name string) string {
+    return fmt.Sprintf("%s:%s", label, name)
+}
+
+func (g *Graph) addNode(label string, name string, props map[string]interface{}) *GraphNode {
+    id := graphNodeID(label, name)
+    if label == NODE_RESOURCE {
+        id = graphNodeID(label, fmt.Sprintf("%s:%s", props["class"], name))
+    }
+    if n, ok := g.nodes[id]; ok {
+        return n
+    }
+    n := &GraphNode{id, label, name, props}
+    n.Properties["name"] = name
+    g.Nodes = append(g.Nodes, n)
+    g.nodes[id] = n
+    return n
+}
+
+func (g *Graph) addEdge(edgeType string, from string, to string, props map[string]interface{}) {
+    if _, ok := g.nodes[from]; !ok {
+        return
+    }
+    if _, ok := g.nodes[to]; !ok {
+        return
+    }
+    g.Edges = append(g.Edges, &GraphEdge{edgeType, from, to, props})
+}
+
+// Get node by ID
+func (g *Graph) Node(id string) (*GraphNode, bool) {
+    n, ok := g.nodes[id]
+    return n, ok
+}
+
+// Get ID of user or group node by RACF ID (empty string if there is no such user or group)
+func (g *Graph) principal(name string) string {
+    for _, label := range []string{NODE_USER, NODE_GROUP} {
+        if _, ok := g.nodes[graphNodeID(label, name)]; ok {
+            return graphNodeID(label, name)
+        }
+    }
+    return ""
+}
+
+func isFlagSet(s *Segment, name string, mask byte) bool {
+    b := s.FieldBytes(name)
+    return len(b) > 0 && b[0]&mask != 0
+}
+
+func isGenericName(name string) bool {
+    return strings.ContainsAny(name, "*%")
+}
+
+// Create RACF graph from runtime DB profiles
+func NewGraph(profiles []*Profile) *Graph {
+    g := &Graph{Nodes: make([]*GraphNode, 0), Edges: make([]*GraphEdge, 0), nodes: make(map[string]*GraphNode)}
+    owners := make(map[string]string)                 // Node ID -> owner
+    authorities := make(map[string]map[string]string) // Group -> member -> group authority (from group ACL)
+    profileNodes := make(map[*Profile]string)
+
+    // Nodes
+    for _, p := range profiles {
+        s, ok := p.Segment("BASE")
+        if !ok {
+            continue
+        }
+        name := strings.TrimSpace(p.Name)
+        var n *GraphNode
+        switch p.Type.Name {
+        case "USER":
+            n = g.addNode(NODE_USER, name, map[string]interface{}{
+                "programmer":   s.FieldString("PGMRNAME"),
+                "defaultGroup": s.FieldString("DFLTGRP"),
+                "special":      isFlagSet(s, "FLAG2", 0x80),
+                "operations":   isFlagSet(s, "FLAG3", 0x80),
+                "revoked":      isFlagSet(s, "FLAG4", 0x80),
+                "auditor":      isFlagSet(s, "FLAG6", 0x80),
+                "protected":    isFlagSet(s, "FLAG7", 0x80),
+                "roaudit":      isFlagSet(s, "FLAGROA", 0x80),
+            })
+            if omvs, ok := p.Segment("OMVS"); ok {
+                if _, ok := omvs.Field("UID"); ok {
+                    n.Properties["uid"] = omvs.FieldUint("UID")
+                }
+            }
+            owners[n.ID] = s.FieldString("AUTHOR")
+        case "GROUP":
+            n = g.addNode(NODE_GROUP, name, map[string]interface{}{
+                "universal": isFlagSet(s, "UNVFLG", 0x80),
+            })
+            if omvs, ok := p.Segment("OMVS"); ok {
+                if _, ok := omvs.Field("GID"); ok {
+                    n.Properties["gid"] = omvs.FieldUint("GID")
+                }
+            }
+            owners[n.ID] = s.FieldString("AUTHOR")
+            authorities[name] = make(map[string]string)
+            for _, item := range s.Items("ACLCNT") {
+                authorities[name][item.FieldString("USERID")] = GroupAuthority(item.FieldBytes("USERACS"))
+            }
+        case "DATASET":
+            n = g.addNode(NODE_DATASET, name, map[string]interface{}{
+                "uacc":    p.UACC(),
+                "generic": isGenericName(name),
+            })
+            owners[n.ID] = s.FieldString("AUTHOR")
+        case "GENERAL":
+            resource := p.ResourceName()
+            n = g.addNode(NODE_RESOURCE, resource, map[string]interface{}{
+                "class":   p.Class(),
+                "uacc":    p.UACC(),
+                "generic": isGenericName(resource),
+            })
+            owners[n.ID] = s.FieldString("OWNER")
+        default:
+            continue
+        }
+        profileNodes[p] = n.ID
+    }
+
+    // Edges
+    for _, p := range profiles {
+        id, ok := profileNodes[p]
+        if !ok {
+            continue
+        }
+        s, _ := p.Segment("BASE")
+        switch p.Type.Name {
+        case "USER":
+            for _, item := range s.Items("CGGRPCT") {
+                group := item.FieldString("CGGRPNM")
+                authority, ok := authorities[group][g.nodes[id].Name]
+                if !ok {
+                    authority = "USE"
+                }
+                props := map[string]interface{}{
+                    "authority":  authority,
+                    "special":    isFlagSet(item, "CGFLAG2", 0x80),
+                    "operations": isFlagSet(item, "CGFLAG3", 0x80),
+                    "revoked":    isFlagSet(item, "CGFLAG4", 0x80),
+                    "auditor":    isFlagSet(item, "CGGRPAUD", 0x80),
+                }
+                g.addEdge(EDGE_MEMBER_OF, id, graphNodeID(NODE_GROUP, group), props)
+            }
+        case "GROUP":
+            if sup := s.FieldString("SUPGROUP"); len(sup) > 0 && sup != g.nodes[id].Name {
+                g.addEdge(EDGE_SUPGROUP_OF, graphNodeID(NODE_GROUP, sup), id, map[string]interface{}{})
+            }
+        case "DATASET", "GENERAL":
+            for _, e := range p.ACL() {
+                // ID(*) grants access to all RACF users, it is kept as node property (there is no node of all users)
+                if e.ID == "*" {
+                    g.nodes[id].Properties["allUsersAccess"] = e.Access
+                    continue
+                }
+                if from := g.principal(e.ID); len(from) > 0 {
+                    g.addEdge(EDGE_HAS_ACCESS, from, id, map[string]interface{}{"access": e.Access, "condition": ""})
+                }
+            }
+            for _, e := range p.ConditionalACL() {
+                if from := g.principal(e.ID); len(from) > 0 {
+                    g.addEdge(EDGE_HAS_ACCESS, from, id, map[string]interface{}{"access": e.Access, "condition": e.Condition})
+                }
+            }
+        }
+    }
+
+    // Owners
+    for _, n := range g.Nodes {
+        if owner := g.principal(owners[n.ID]); len(owner) > 0 && owner != n.ID {
+            g.addEdge(EDGE_OWNS, owner, n.ID, map[string]interface{}{})
+        }
+    }
+
+    g.addSurrogates(profiles)
+    return g
+}
+
+// Add SURROGATE_FOR edges from users and groups with READ access to SURROGAT class profiles <USERID>.SUBMIT
+// and BPX.SRV.<USERID> (the most specific profile is used for each user)
+func (g *Graph) addSurrogates(profiles []*Profile) {
+    surrogates := newResourceProfiles()
+    for _, p := range profiles {
+        if p.Type.Name == "GENERAL" && p.Class() == "SURROGAT" {
+            surrogates.add(p)
+        }
+    }
+    if surrogates.Len() == 0 {
+        return
+    }
+
+    for _, n := range g.Nodes {
+        if n.Label != NODE_USER {
+            continue
+        }
+        for _, resource := range []string{n.Name + ".SUBMIT", "BPX.SRV." + n.Name} {
+            p := surrogates.BestMatch(resource)
+            if p == nil {
+                continue
+            }
+            common.Log.Debug("SURROGAT profile %s protects %s", p.ResourceName(), resource)
+            for _, e := range p.ACL() {
+                from := g.principal(e.ID)
+                if len(from) == 0 || from == n.ID || AccessLevel(e.Access) < AccessLevel("READ") {
+                    continue
+                }
+                g.addEdge(EDGE_SURROGATE_FOR, from, n.ID, map[string]interface{}{"resource": resource, "profile": p.ResourceName()})
+            }
+        }
+    }
+}
+
+// General resource profiles of one class: discrete profiles by name and generic profiles with compiled patterns
+type resourceProfiles struct {
+    discrete map[string]*Profile
+    generic  []*Profile
+    patterns []*GenericPattern
+}
+
+func newResourceProfiles() *resourceProfiles {
+    return &resourceProfiles{discrete: make(map[string]*Profile), generic: make([]*Profile, 0), patterns: make([]*GenericPattern, 0)}
+}
+
+func (rp *resourceProfiles) add(p *Profile) {
+    name := p.ResourceName()
+    if !isGenericName(name) {
+        rp.discrete[name] = p
+        return
+    }
+    rp.generic = append(rp.generic, p)
+    rp.patterns = append(rp.patterns, NewGenericPattern(name))
+}
+
+func (rp *resourceProfiles) Len() int {
+    return len(rp.discrete) + len(rp.generic)
+}
+
+// Get the most specific profile protecting the resource: discrete profile or generic profile with the longest name
+func (rp *resourceProfiles) BestMatch(resource string) *Profile {
+    if p, ok := rp.discrete[resource]; ok {
+        return p
+    }
+    var retVal *Profile
+    for i, gp := range rp.patterns {
+        if gp.Match(resource) && (retVal == nil || len(gp.Pattern) > len(retVal.ResourceName())) {
+            retVal = rp.generic[i]
+        }
+    }
+    return retVal
+}
+
+// Get subgraph of nodes reachable from the start node (user or group ID) by outgoing edges within hops.
+// All edges between the reachable nodes are kept
+func (g *Graph) Subgraph(start string, hops int) (*Graph, error) {
+    startID := g.principal(start)
+    if len(startID) == 0 {
+        return nil, fmt.Errorf("user or group %q is not found in the graph (or it has no edges of the selected types)", start)
+    }
+    outgoing := make(map[string][]*GraphEdge)
+    for _, e := range g.Edges {
+        outgoing[e.From] = append(outgoing[e.From], e)
+    }
+
+    visited := map[string]bool{startID: true}
+    front := []string{startID}
+    for i := 0; i < hops && len(front) > 0; i++ {
+        next := make([]string, 0)
+        for _, id := range front {
+            for _, e := range outgoing[id] {
+                if !visited[e.To] {
+                    visited[e.To] = true
+                    next = append(next, e.To)
+                }
+            }
+        }
+        front = next
+    }
+
+    retVal := &Graph{Nodes: make([]*GraphNode, 0), Edges: make([]*GraphEdge, 0), nodes: make(map[string]*GraphNode)}
+    for _, n := range g.Nodes {
+        if visited[n.ID] {
+            retVal.Nodes = append(retVal.Nodes, n)
+            retVal.nodes[n.ID] = n
+        }
+    }
+    for _, e := range g.Edges {
+        if visited[e.From] && visited[e.To] {
+            retVal.Edges = append(retVal.Edges, e)
+        }
+    }
+    return retVal, nil
+}
+
+// Parse comma separated list of edge types (case insensitive). Unknown types are rejected,
+// otherwise a misspelled type would silently produce an empty graph
+func ParseEdgeTypes(s string) ([]string, error) {
+    retVal := make([]string, 0)
+    for _, name := range strings.Split(s, ",") {
+        name = strings.ToUpper(strings.TrimSpace(name))
+        known := false
+        for _, t := range GraphEdgeTypes {
+            known = known || name == t
+        }
+        if !known {
+            return nil, fmt.Errorf("Unknown graph edge type %q (must be one of %s)", name, strings.Join(GraphEdgeTypes, ", "))
+        }
+        retVal = append(retVal, name)
+    }
+    return retVal, nil
+}
+
+// Get graph with edges of the listed types only (nodes without edges are dropped)
+func (g *Graph) FilterEdges(types []string) *Graph {
+    retVal := &Graph{Nodes: make([]*GraphNode, 0), Edges: make([]*GraphEdge, 0), nodes: make(map[string]*GraphNode)}
+    linked := make(map[string]bool)
+    for _, e := range g.Edges {
+        for _, t := range types {
+            if e.Type == t {
+                retVal.Edges = append(retVal.Edges, e)
+                linked[e.From], linked[e.To] = true, true
+                break
+            }
+        }
+    }
+    for _, n := range g.Nodes {
+        if linked[n.ID] {
+            retVal.Nodes = append(retVal.Nodes, n)
+            retVal.nodes[n.ID] = n
+        }
+    }
+    return retVal
+}
@@ -0,0 +1,76 @@
+package db
+
+import (
+    "fmt"
+    "reflect"
+    "testing"
+)
+
+func TestParseEdgeTypes(t *testing.T) {
+    got, err := ParseEdgeTypes("member_of, HAS_ACCESS")
+    if err != nil {
+        t.Fatal(err)
+    }
+    if want := []string{EDGE_MEMBER_OF, EDGE_HAS_ACCESS}; !reflect.DeepEqual(got, want) {
+        t.Errorf("ParseEdgeTypes() = %v, want %v", got, want)
+    }
+    for _, s := range []string{"HAS_ACESS", "MEMBER_OF,", ""} {
+        if _, err := ParseEdgeTypes(s); err == nil {
+            t.Errorf("ParseEdgeTypes(%q) must fail", s)
+        }
+    }
+}
+
+func TestSubgraphOfFilteredEdges(t *testing.T) {
+    g := &Graph{Nodes: make([]*GraphNode, 0), Edges: make([]*GraphEdge, 0), nodes: make(map[string]*GraphNode)}
+    g.addNode(NODE_USER, "U", map[string]interface{}{})
+    g.addNode(NODE_GROUP, "G", map[string]interface{}{})
+    g.addNode(NODE_DATASET, "G.DATA", map[string]interface{}{})
+    g.addNode(NODE_DATASET, "U.DATA", map[string]interface{}{})
+    g.addEdge(EDGE_MEMBER_OF, "User:U", "Group:G", map[string]interface{}{})
+    g.addEdge(EDGE_HAS_ACCESS, "Group:G", "Dataset:G.DATA", map[string]interface{}{})
+    g.addEdge(EDGE_HAS_ACCESS, "User:U", "Dataset:U.DATA", map[string]interface{}{})
+
+    // Access of the group isn't reachable from the user by HAS_ACCESS edges
+    sub, err := g.FilterEdges([]string{EDGE_HAS_ACCESS}).Subgraph("U", 3)
+    if err != nil {
+        t.Fatal(err)
+    }
+    ids := make([]string, 0)
+    for _, n := range sub.Nodes {
+        ids = append(ids, n.ID)
+    }
+    if want := []string{"User:U", "Dataset:U.DATA"}; !reflect.DeepEqual(ids, want) {
+        t.Errorf("Subgraph nodes = %v, want %v", ids, want)
+    }
+    if len(sub.Edges) != 1 || sub.Edges[0].To != "Dataset:U.DATA" {
+        t.Errorf("Subgraph edges = %v, want HAS_ACCESS to U.DATA", sub.Edges)
+    }
+}
+
+func TestResourceProfilesBestMatch(t *testing.T) {
+    rp := newResourceProfiles()
+    for _, name := range []string{"**", "IBMUSER.*", "IBMUSER.SUBMIT", "BPX.SRV.*", "BPX.SRV.U%%%"} {
+        rp.add(NewProfile(fmt.Sprintf("%-8s%s", "SURROGAT", name), "GENERAL", 5))
+    }
+    if rp.Len() != 5 {
+        t.Errorf("Len() = %d, want 5", rp.Len())
+    }
+    for _, tc := range []struct {
+        resource string
+        want     string
+    }{
+        {"IBMUSER.SUBMIT", "IBMUSER.SUBMIT"},
+        {"IBMUSER.OTHER", "IBMUSER.*"},
+        {"USER1.SUBMIT", "**"},
+        {"BPX.SRV.USR1", "BPX.SRV.U%%%"},
+        {"BPX.SRV.IBMUSER", "BPX.SRV.*"},
+    } {
+        if p := rp.BestMatch(tc.resource); p == nil || p.ResourceName() != tc.want {
+            t.Errorf("BestMatch(%s) = %v, want %s", tc.resource, p, tc.want)
+        }
+    }
+    if p := newResourceProfiles().BestMatch("IBMUSER.SUBMIT"); p != nil {
+        t.Errorf("BestMatch of no profiles = %v", p)
+    }
+}
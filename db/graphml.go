@@ -0,0 +1,190 @@
+package db
+
+import (
+    "bytes"
+    "encoding/xml"
+    "fmt"
+    "io"
+    "sort"
+    "strings"
+
+    "racfudit/common"
+)
+
+// GraphML attribute key (node or edge property)
+type graphMLKey struct {
+    ID   string
+    For  string // node or edge
+    Name string
+    Type string // string, boolean or long
+}
+
+// Get GraphML type of property value
+func graphMLType(v interface{}) string {
+    switch v.(type) {
+    case bool:
+        return "boolean"
+    case int, int64, uint8, uint16, uint32, uint64:
+        return "long"
+    }
+    return "string"
+}
+
+// Get GraphML keys of node or edge properties (keys with values of different types are strings)
+func graphMLKeys(domain string, props []map[string]interface{}) []*graphMLKey {
+    keys := make(map[string]*graphMLKey)
+    for _, p := range props {
+        for name, v := range p {
+            t := graphMLType(v)
+            if k, ok := keys[name]; !ok {
+                keys[name] = &graphMLKey{fmt.Sprintf("%s_%s", domain, name), domain, name, t}
+            } else if k.Type != t {
+                k.Type = "string"
+            }
+        }
+    }
+    retVal := make([]*graphMLKey, 0)
+    for _, k := range keys {
+        retVal = append(retVal, k)
+    }
+    sort.Slice(retVal, func(i, j int) bool { return retVal[i].ID < retVal[j].ID })
+    return retVal
+}
+
+func xmlEscape(s string) string {
+    var b bytes.Buffer
+    xml.EscapeText(&b, []byte(s))
+    return b.String()
+}
+
+func writeGraphMLData(w io.Writer, keys []*graphMLKey, props map[string]interface{}) {
+    for _, k := range keys {
+        if v, ok := props[k.Name]; ok {
+            fmt.Fprintf(w, "      <data key=\"%s\">%s</data>\n", k.ID, xmlEscape(fmt.Sprintf("%v", v)))
+        }
+    }
+}
+
+// Write RACF graph as GraphML. Node label and edge type are saved as "label" and "type" attributes
+func WriteGraphML(w io.Writer, g *Graph) {
+    nodeProps := make([]map[string]interface{}, 0)
+    for _, n := range g.Nodes {
+        props := map[string]interface{}{"label": n.Label}
+        for k, v := range n.Properties {
+            props[k] = v
+        }
+        nodeProps = append(nodeProps, props)
+    }
+    edgeProps := make([]map[string]interface{}, 0)
+    for _, e := range g.Edges {
+        props := map[string]interface{}{"type": e.Type}
+        for k, v := range e.Properties {
+            props[k] = v
+        }
+        edgeProps = append(edgeProps, props)
+    }
+    nodeKeys := graphMLKeys("node", nodeProps)
+    edgeKeys := graphMLKeys("edge", edgeProps)
+
+    fmt.Fprint(w, xml.Header)
+    fmt.Fprint(w, "<graphml xmlns=\"http://graphml.graphdrawing.org/xmlns\" xmlns:xsi=\"http://www.w3.org/2001/XMLSchema-instance\" "+
+        "xsi:schemaLocation=\"http://graphml.graphdrawing.org/xmlns http://graphml.graphdrawing.org/xmlns/1.0/graphml.xsd\">\n")
+    for _, k := range append(nodeKeys, edgeKeys...) {
+        fmt.Fprintf(w, "  <key id=\"%s\" for=\"%s\" attr.name=\"%s\" attr.type=\"%s\"/>\n", k.ID, k.For, xmlEscape(k.Name), k.Type)
+    }
+    fmt.Fprint(w, "  <graph id=\"RACF\" edgedefault=\"directed\">\n")
+    for i, n := range g.Nodes {
+        fmt.Fprintf(w, "    <node id=\"%s\">\n", xmlEscape(n.ID))
+        writeGraphMLData(w, nodeKeys, nodeProps[i])
+        fmt.Fprint(w, "    </node>\n")
+    }
+    for i, e := range g.Edges {
+        fmt.Fprintf(w, "    <edge id=\"e%d\" source=\"%s\" target=\"%s\">\n", i, xmlEscape(e.From), xmlEscape(e.To))
+        writeGraphMLData(w, edgeKeys, edgeProps[i])
+        fmt.Fprint(w, "    </edge>\n")
+    }
+    fmt.Fprint(w, "  </graph>\n</graphml>\n")
+}
+
+// Save RACF graph as GraphML file (for yEd, Gephi and similar tools)
+func ToGraphML(g *Graph, fileName string) {
+    f, err := createOutput(fileName)
+    if err != nil {
+        common.Fatal(fmt.Errorf("Can not create GraphML file: %v", err))
+    }
+    defer f.Close()
+
+    common.Log.Info("Saving RACF graph (%d nodes, %d edges) as GraphML file %s", len(g.Nodes), len(g.Edges), fileName)
+    WriteGraphML(f, g)
+}
+
+// Node shapes of DOT graph
+var dotShapes = map[string]string{
+    NODE_USER:     "ellipse",
+    NODE_GROUP:    "box",
+    NODE_DATASET:  "folder",
+    NODE_RESOURCE: "note",
+}
+
+// Quote string as DOT ID
+func dotQuote(s string) string {
+    s = strings.ReplaceAll(s, `\`, `\\`)
+    s = strings.ReplaceAll(s, `"`, `\"`)
+    s = strings.ReplaceAll(s, "\n", `\n`)
+    return `"` + s + `"`
+}
+
+// Get DOT label of node: name with class for general resources and attributes for users
+func dotNodeLabel(n *GraphNode) string {
+    label := n.Name
+    switch n.Label {
+    case NODE_RESOURCE:
+        label = fmt.Sprintf("%s\n%s", n.Properties["class"], n.Name)
+    case NODE_USER:
+        attrs := make([]string, 0)
+        for _, a := range []string{"special", "operations", "auditor", "revoked"} {
+            if v, ok := n.Properties[a].(bool); ok && v {
+                attrs = append(attrs, strings.ToUpper(a))
+            }
+        }
+        if len(attrs) > 0 {
+            label += "\n" + strings.Join(attrs, ",")
+        }
+    }
+    return label
+}
+
+// Get DOT label of edge: type with access level, group authority or condition
+func dotEdgeLabel(e *GraphEdge) string {
+    label := e.Type
+    for _, k := range []string{"authority", "access", "condition"} {
+        if v, ok := e.Properties[k].(string); ok && len(v) > 0 {
+            label += "\n" + v
+        }
+    }
+    return label
+}
+
+// Write RACF graph as Graphviz DOT
+func WriteDOT(w io.Writer, g *Graph) {
+    fmt.Fprint(w, "digraph RACF {\n  rankdir=LR;\n  node [fontname=\"Helvetica\", fontsize=10];\n  edge [fontname=\"Helvetica\", fontsize=8];\n")
+    for _, n := range g.Nodes {
+        fmt.Fprintf(w, "  %s [label=%s, shape=%s];\n", dotQuote(n.ID), dotQuote(dotNodeLabel(n)), dotShapes[n.Label])
+    }
+    for _, e := range g.Edges {
+        fmt.Fprintf(w, "  %s -> %s [label=%s];\n", dotQuote(e.From), dotQuote(e.To), dotQuote(dotEdgeLabel(e)))
+    }
+    fmt.Fprint(w, "}\n")
+}
+
+// Save RACF graph as Graphviz DOT file
+func ToDOT(g *Graph, fileName string) {
+    f, err := createOutput(fileName)
+    if err != nil {
+        common.Fatal(fmt.Errorf("Can not create DOT file: %v", err))
+    }
+    defer f.Close()
+
+    common.Log.Info("Saving RACF graph (%d nodes, %d edges) as DOT file %s", len(g.Nodes), len(g.Edges), fileName)
+    WriteDOT(f, g)
+}
@@ -0,0 +1,113 @@
+package db
+
+import (
+    "bytes"
+    "encoding/xml"
+    "reflect"
+    "strings"
+    "testing"
+)
+
+// Graph with IDs containing XML and DOT special characters and property with values of different types
+func testGraphMLGraph() *Graph {
+    g := &Graph{Nodes: make([]*GraphNode, 0), Edges: make([]*GraphEdge, 0), nodes: make(map[string]*GraphNode)}
+    g.addNode(NODE_USER, "IBMUSER", map[string]interface{}{"special": true, "level": uint8(3)})
+    g.addNode(NODE_DATASET, `A&B.<DATA>`, map[string]interface{}{"level": "HIGH"})
+    g.addNode(NODE_RESOURCE, `PATH\"X"`, map[string]interface{}{"class": "UNIXPRIV"})
+    g.addEdge(EDGE_HAS_ACCESS, "User:IBMUSER", "Dataset:A&B.<DATA>", map[string]interface{}{"access": "READ", "count": uint32(1)})
+    g.addEdge(EDGE_HAS_ACCESS, "User:IBMUSER", `Resource:UNIXPRIV:PATH\"X"`, map[string]interface{}{"access": "UPDATE", "count": uint32(2)})
+    return g
+}
+
+func TestWriteGraphML(t *testing.T) {
+    var buf bytes.Buffer
+    WriteGraphML(&buf, testGraphMLGraph())
+
+    var doc struct {
+        Keys []struct {
+            ID   string `xml:"id,attr"`
+            For  string `xml:"for,attr"`
+            Name string `xml:"attr.name,attr"`
+            Type string `xml:"attr.type,attr"`
+        } `xml:"key"`
+        Nodes []struct {
+            ID   string `xml:"id,attr"`
+            Data []struct {
+                Key   string `xml:"key,attr"`
+                Value string `xml:",chardata"`
+            } `xml:"data"`
+        } `xml:"graph>node"`
+        Edges []struct {
+            Source string `xml:"source,attr"`
+            Target string `xml:"target,attr"`
+        } `xml:"graph>edge"`
+    }
+    if err := xml.Unmarshal(buf.Bytes(), &doc); err != nil {
+        t.Fatalf("GraphML isn't well-formed XML: %v\n%s", err, buf.String())
+    }
+
+    types := make(map[string]string)
+    for _, k := range doc.Keys {
+        types[k.ID] = k.Type
+    }
+    for id, want := range map[string]string{
+        "node_label":   "string",
+        "node_special": "boolean",
+        "node_level":   "string", // long and string values
+        "node_class":   "string",
+        "edge_count":   "long",
+        "edge_access":  "string",
+    } {
+        if got := types[id]; got != want {
+            t.Errorf("Type of key %s = %q, want %q", id, got, want)
+        }
+    }
+
+    ids := make([]string, 0)
+    for _, n := range doc.Nodes {
+        ids = append(ids, n.ID)
+    }
+    if want := []string{"User:IBMUSER", "Dataset:A&B.<DATA>", `Resource:UNIXPRIV:PATH\"X"`}; !reflect.DeepEqual(ids, want) {
+        t.Errorf("Node IDs = %q, want %q", ids, want)
+    }
+    for _, d := range doc.Nodes[1].Data {
+        if d.Key == "node_name" && d.Value != "A&B.<DATA>" {
+            t.Errorf("Name of node %s = %q, want %q", doc.Nodes[1].ID, d.Value, "A&B.<DATA>")
+        }
+    }
+    if len(doc.Edges) != 2 || doc.Edges[0].Target != "Dataset:A&B.<DATA>" || doc.Edges[1].Target != `Resource:UNIXPRIV:PATH\"X"` {
+        t.Errorf("Edges = %+v, want targets A&B.<DATA> and PATH\\\"X\"", doc.Edges)
+    }
+}
+
+func TestWriteDOT(t *testing.T) {
+    var buf bytes.Buffer
+    WriteDOT(&buf, testGraphMLGraph())
+    for _, want := range []string{
+        `  "User:IBMUSER" [label="IBMUSER\nSPECIAL", shape=ellipse];`,
+        `  "Dataset:A&B.<DATA>" [label="A&B.<DATA>", shape=folder];`,
+        `  "Resource:UNIXPRIV:PATH\\\"X\"" [label="UNIXPRIV\nPATH\\\"X\"", shape=note];`,
+        `  "User:IBMUSER" -> "Resource:UNIXPRIV:PATH\\\"X\"" [label="HAS_ACCESS\nUPDATE"];`,
+    } {
+        if !strings.Contains(buf.String(), want+"\n") {
+            t.Errorf("DOT graph doesn't contain line %s:\n%s", want, buf.String())
+        }
+    }
+}
+
+func TestDOTQuote(t *testing.T) {
+    for _, tc := range []struct {
+        s    string
+        want string
+    }{
+        {"SYS1", `"SYS1"`},
+        {`A"B`, `"A\"B"`},
+        {`A\B`, `"A\\B"`},
+        {`\"`, `"\\\""`},
+        {"A\nB", `"A\nB"`},
+    } {
+        if got := dotQuote(tc.s); got != tc.want {
+            t.Errorf("dotQuote(%q) = %s, want %s", tc.s, got, tc.want)
+        }
+    }
+}
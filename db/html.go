@@ -0,0 +1,424 @@
+package db
+
+import (
+    "bytes"
+    "encoding/hex"
+    "fmt"
+    "html/template"
+    "reflect"
+    "strings"
+    "time"
+
+    "racfudit/common"
+    "racfudit/sections"
+)
+
+// Data of HTML audit report
+type htmlReport struct {
+    Source     string
+    Generated  string
+    Settings   []htmlSetting
+    ICB        string
+    Privileged []htmlPrivileged
+    Types      []*htmlProfileType
+}
+
+// SETROPTS setting from ICB
+type htmlSetting struct {
+    Name        string
+    Value       string
+    Description string
+}
+
+// User with system-wide or group-level attributes
+type htmlPrivileged struct {
+    Name         string
+    Programmer   string
+    DefaultGroup string
+    Attributes   []string
+    Groups       []string // <GROUP>:<ATTRIBUTE>
+    UID          string
+    Revoked      bool
+    LastAccess   string
+}
+
+type htmlProfileType struct {
+    Name     string
+    Profiles []htmlProfile
+}
+
+type htmlProfile struct {
+    Name     string
+    Class    string
+    Segments []htmlSegment
+}
+
+type htmlSegment struct {
+    Name         string
+    Offset       string
+    Size         uint32
+    Raw          string
+    Fields       []htmlField
+    RepeatGroups []htmlRepeatGroup
+}
+
+// Field value: decoded value and raw data in hex
+type htmlField struct {
+    Name  string
+    Value string
+    Hex   string
+}
+
+type htmlRepeatGroup struct {
+    Name    string
+    Columns []string
+    Rows    [][]htmlField
+}
+
+func yesNo(b bool) string {
+    if b {
+        return "Yes"
+    }
+    return "No"
+}
+
+// Get SETROPTS summary from ICB
+func htmlSettings(icb *sections.ICB) []htmlSetting {
+    if icb == nil {
+        return nil
+    }
+    protectAll := "Inactive"
+    if icb.ICBPRO && icb.ICBPROF {
+        protectAll = "Warning"
+    } else if icb.ICBPRO {
+        protectAll = "Failures"
+    }
+    algorithm := "DES"
+    if icb.ICBPALG == 1 {
+        algorithm = fmt.Sprintf("KDFAES (PBKDF2, repetition factor %d, memory factor %d)", icb.ICBPREP, icb.ICBPMEM)
+    }
+    return []htmlSetting{
+        {"RACF version", icb.ICBVRMN.String(), "Version/release/modification number"},
+        {"Template level", icb.ICBTMPLV.String(), "Template level filled in by IRRMIN00"},
+        {"PASSWORD(INTERVAL)", fmt.Sprintf("%d", icb.ICBPINV), "Maximum password interval (days)"},
+        {"PASSWORD(MINCHANGE)", fmt.Sprintf("%d", icb.ICBPMIN), "Minimum password change interval (days)"},
+        {"PASSWORD(HISTORY)", fmt.Sprintf("%d", icb.ICBPHIST), "Number of previous passwords kept"},
+        {"PASSWORD(REVOKE)", fmt.Sprintf("%d", icb.ICBPRVOK), "Number of failed logons before revoke (0 - no revoke)"},
+        {"PASSWORD(WARNING)", fmt.Sprintf("%d", icb.ICBPWARN), "Days of warning before password expiration"},
+        {"PASSWORD(MIXEDCASE)", yesNo(icb.ICBPLC), "Mixed case passwords"},
+        {"PASSWORD(SPECIALCHARS)", yesNo(icb.ICBPSC), "Special characters in passwords"},
+        {"PASSWORD(ALGORITHM)", algorithm, "Password algorithm in effect"},
+        {"INACTIVE", fmt.Sprintf("%d", icb.ICBINACT), "Inactivity interval (days) before revoke (0 - no revoke)"},
+        {"PROTECTALL", protectAll, "Protection of data sets without profiles"},
+        {"GENERIC(DATASET)", yesNo(icb.ICBDGEN), "Generic profiles for data set class"},
+        {"EGN", yesNo(icb.ICBEGN), "Enhanced generic naming"},
+        {"ERASE", yesNo(icb.ICBEOS), "Erase-on-scratch"},
+        {"PROGRAM CONTROL", yesNo(icb.ICBPROG), "Program control"},
+        {"TAPEDSN", yesNo(icb.ICBTDSN), "Tape data set protection"},
+        {"TAPE VOLUME PROTECTION", yesNo(icb.ICBTAPE), "Tape volume protection"},
+        {"ADSP", yesNo(!icb.ICBNADS), "Automatic data set protection"},
+        {"TERMINAL", yesNo(icb.ICBTERP), "Terminal authorization checking"},
+        {"JES(BATCHALLRACF)", yesNo(icb.ICBJALL), "Batch jobs must have a user ID"},
+        {"JES(XBMALLRACF)", yesNo(icb.ICBJXAL), "Execution batch monitor jobs must have a user ID"},
+        {"SAUDIT", yesNo(!icb.ICBSAUD), "Audit commands of SPECIAL users"},
+        {"OPERAUDIT", yesNo(icb.ICBAOPR), "Audit accesses granted by OPERATIONS attribute"},
+        {"AUDIT(USER GROUP DATASET)", fmt.Sprintf("%s %s %s", yesNo(icb.ICBAUSE), yesNo(icb.ICBAGRO), yesNo(icb.ICBADAT)),
+            "Audit changes of USER, GROUP and DATASET profiles"},
+        {"GRPLIST", yesNo(icb.ICBLGRP), "List of groups access checking"},
+        {"PREFIX", strings.TrimSpace(icb.ICBQUAL.String()), "Prefix of single level data set names"},
+        {"RETPD", fmt.Sprintf("%d", icb.ICBRETP), "Security retention period (days)"},
+        {"KERBLVL", fmt.Sprintf("%d", icb.ICBKRBLV), "Kerberos encryption level"},
+    }
+}
+
+// Get users with system-wide attributes, group-level attributes or UID 0
+func htmlPrivilegedUsers(profiles []*Profile) []htmlPrivileged {
+    retVal := make([]htmlPrivileged, 0)
+    for _, p := range profiles {
+        if p.Type.Name != "USER" {
+            continue
+        }
+        s, ok := p.Segment("BASE")
+        if !ok {
+            continue
+        }
+        u := htmlPrivileged{
+            Name:         strings.TrimSpace(p.Name),
+            Programmer:   s.FieldString("PGMRNAME"),
+            DefaultGroup: s.FieldString("DFLTGRP"),
+            Revoked:      isFlagSet(s, "FLAG4", 0x80),
+            Attributes:   make([]string, 0),
+            Groups:       make([]string, 0),
+        }
+        for _, a := range []struct {
+            name  string
+            field string
+        }{{"SPECIAL", "FLAG2"}, {"OPERATIONS", "FLAG3"}, {"AUDITOR", "FLAG6"}, {"ROAUDIT", "FLAGROA"}} {
+            if isFlagSet(s, a.field, 0x80) {
+                u.Attributes = append(u.Attributes, a.name)
+            }
+        }
+        for _, item := range s.Items("CGGRPCT") {
+            for _, a := range []struct {
+                name  string
+                field string
+            }{{"SPECIAL", "CGFLAG2"}, {"OPERATIONS", "CGFLAG3"}, {"AUDITOR", "CGGRPAUD"}} {
+                if isFlagSet(item, a.field, 0x80) {
+                    u.Groups = append(u.Groups, fmt.Sprintf("%s:%s", item.FieldString("CGGRPNM"), a.name))
+                }
+            }
+        }
+        if omvs, ok := p.Segment("OMVS"); ok {
+            if _, ok := omvs.Field("UID"); ok && omvs.FieldUint("UID") == 0 {
+                u.UID = "0"
+            }
+        }
+        if len(u.Attributes) == 0 && len(u.Groups) == 0 && len(u.UID) == 0 {
+            continue
+        }
+        if v, ok := s.Field("LJDATE"); ok {
+            u.LastAccess = DumpField(v)
+        }
+        retVal = append(retVal, u)
+    }
+    return retVal
+}
+
+// Credential fields (segment -> field names) which are redacted in HTML report: password and password phrase hashes
+// with their history, Kerberos keys, PassTicket and session keys, LDAP bind and DCE passwords
+var htmlSecretFields = map[string][]string{
+    "BASE":    {"PASSWORD", "PWDENV", "OLDPWD", "PWDX", "OPWDX", "PHRASE", "OLDPHR", "PHRASEX", "OLDPHRX"},
+    "KERB":    {"CURKEY", "PREVKEY"},
+    "SSIGNON": {"SSKEY"},
+    "SESSION": {"SESSKEY"},
+    "PROXY":   {"BINDPW", "BINDPWKY"},
+    "DCE":     {"DPASSWDS"},
+}
+
+// Check if the field is a credential field which is set (not empty, zero or blank)
+func isHTMLSecret(sName string, name string, val reflect.Value) bool {
+    for _, secret := range htmlSecretFields[sName] {
+        if name == secret {
+            b, _ := hex.DecodeString(FieldHex(val))
+            return len(bytes.Trim(b, "\x00\x40")) > 0
+        }
+    }
+    return false
+}
+
+// Get HTML representation of the field. Values of credential fields are replaced by COMMAND_SECRET placeholder
+func htmlFieldValue(sName string, name string, val reflect.Value) (htmlField, bool) {
+    if isHTMLSecret(sName, name, val) {
+        return htmlField{name, COMMAND_SECRET, COMMAND_SECRET}, true
+    }
+    return htmlField{name, DumpField(val), FieldHex(val)}, false
+}
+
+// Convert segment of runtime DB profile to its HTML representation. Raw data of the segment
+// with credential fields is redacted as a whole
+func newHTMLSegment(s *Segment) htmlSegment {
+    retVal := htmlSegment{Name: s.Name, Offset: s.Address.String(), Size: s.LogicalSize, Raw: s.Raw,
+        Fields: make([]htmlField, 0), RepeatGroups: make([]htmlRepeatGroup, 0)}
+    sDataV := reflect.Indirect(s.Data)
+    sDataT := sDataV.Type()
+    redacted := false
+    for i := 0; i < sDataV.NumField(); i++ {
+        fValue := sDataV.Field(i)
+        fType := sDataT.Field(i)
+        if !isRepeatGroupField(fType) {
+            f, secret := htmlFieldValue(s.Name, fType.Name, fValue)
+            retVal.Fields = append(retVal.Fields, f)
+            redacted = redacted || secret
+            continue
+        }
+        if fValue.Len() == 0 {
+            continue
+        }
+        rg := htmlRepeatGroup{Name: fType.Name, Columns: make([]string, 0), Rows: make([][]htmlField, 0)}
+        itemT := fType.Type.Elem()
+        for j := 0; j < itemT.NumField(); j++ {
+            rg.Columns = append(rg.Columns, itemT.Field(j).Name)
+        }
+        for j := 0; j < fValue.Len(); j++ {
+            row := make([]htmlField, 0)
+            for k := 0; k < itemT.NumField(); k++ {
+                f, secret := htmlFieldValue(s.Name, itemT.Field(k).Name, fValue.Index(j).Field(k))
+                row = append(row, f)
+                redacted = redacted || secret
+            }
+            rg.Rows = append(rg.Rows, row)
+        }
+        retVal.RepeatGroups = append(retVal.RepeatGroups, rg)
+    }
+    if redacted {
+        retVal.Raw = COMMAND_SECRET
+    }
+    return retVal
+}
+
+// Get profiles grouped by profile type (in order of the first appearance in RACF DB)
+func htmlProfileTypes(profiles []*Profile) []*htmlProfileType {
+    retVal := make([]*htmlProfileType, 0)
+    types := make(map[string]*htmlProfileType)
+    for _, p := range profiles {
+        t, ok := types[p.Type.Name]
+        if !ok {
+            t = &htmlProfileType{Name: p.Type.Name, Profiles: make([]htmlProfile, 0)}
+            types[p.Type.Name] = t
+            retVal = append(retVal, t)
+        }
+        hp := htmlProfile{Name: strings.TrimSpace(p.Name), Segments: make([]htmlSegment, 0)}
+        if p.Type.Name == "GENERAL" {
+            hp.Class, hp.Name = p.Class(), p.ResourceName()
+        }
+        for i := range p.Segments {
+            hp.Segments = append(hp.Segments, newHTMLSegment(&p.Segments[i]))
+        }
+        t.Profiles = append(t.Profiles, hp)
+    }
+    return retVal
+}
+
+// Save runtime DB as self-contained HTML audit report (CSS and JavaScript are embedded)
+func ToHTML(profiles []*Profile, fileName string, icb *sections.ICB, source string) {
+    f, err := createOutput(fileName)
+    if err != nil {
+        common.Fatal(fmt.Errorf("Can not create HTML report: %v", err))
+    }
+    defer f.Close()
+
+    common.Log.Info("Saving security audit report as HTML file %s", fileName)
+    report := htmlReport{
+        Source:     source,
+        Generated:  time.Now().Format("2006-01-02 15:04:05"),
+        Settings:   htmlSettings(icb),
+        Privileged: htmlPrivilegedUsers(profiles),
+        Types:      htmlProfileTypes(profiles),
+    }
+    if icb != nil {
+        report.ICB = icb.String()
+    }
+
+    t, err := template.New("report").Funcs(template.FuncMap{"join": strings.Join}).Parse(htmlTemplate)
+    if err != nil {
+        common.Fatal(fmt.Errorf("Can not parse HTML report template: %v", err))
+    }
+    if err := t.Execute(f, report); err != nil {
+        common.Fatal(fmt.Errorf("Can not save HTML report: %v", err))
+    }
+}
+
+const htmlTemplate = `<!DOCTYPE html>
+<html lang="en">
+<head>
+<meta charset="utf-8">
+<title>RACF audit report: {{.Source}}</title>
+<style>
+body { font-family: Helvetica, Arial, sans-serif; font-size: 13px; margin: 0 20px 20px 20px; color: #222; }
+h1 { font-size: 20px; } h2 { font-size: 16px; border-bottom: 1px solid #888; padding-top: 10px; }
+nav { position: sticky; top: 0; background: #fff; padding: 8px 0; border-bottom: 1px solid #ccc; }
+nav a { margin-right: 12px; }
+table { border-collapse: collapse; margin: 4px 0 8px 0; }
+th, td { border: 1px solid #ccc; padding: 2px 6px; text-align: left; vertical-align: top; }
+th { background: #eee; }
+.hex { font-family: monospace; color: #777; word-break: break-all; }
+.raw { font-family: monospace; font-size: 11px; word-break: break-all; max-width: 1000px; }
+.attr { color: #b00; font-weight: bold; }
+details.profile { margin: 2px 0; } details.profile > summary { cursor: pointer; font-family: monospace; }
+details.profile > div { margin-left: 20px; }
+.hidden { display: none; }
+#search { width: 400px; }
+</style>
+</head>
+<body>
+<h1>RACF audit report</h1>
+<p>RACF DB: {{.Source}}<br>Generated: {{.Generated}}</p>
+<nav>
+<input id="search" type="search" placeholder="Filter profiles by name">
+<a href="#setropts">SETROPTS</a><a href="#privileged">Privileged users</a>
+{{- range .Types}}<a href="#type-{{.Name}}">{{.Name}} ({{len .Profiles}})</a>{{end}}
+</nav>
+
+<h2 id="setropts">SETROPTS</h2>
+{{- if .Settings}}
+<table>
+<tr><th>Option</th><th>Value</th><th>Description</th></tr>
+{{- range .Settings}}
+<tr><td>{{.Name}}</td><td>{{.Value}}</td><td>{{.Description}}</td></tr>
+{{- end}}
+</table>
+<details><summary>ICB</summary><pre>{{.ICB}}</pre></details>
+{{- else}}
+<p>ICB is not available</p>
+{{- end}}
+
+<h2 id="privileged">Privileged users ({{len .Privileged}})</h2>
+<table>
+<tr><th>User</th><th>Name</th><th>Default group</th><th>Attributes</th><th>Group-level attributes</th><th>UID 0</th><th>Revoked</th><th>Last access</th></tr>
+{{- range .Privileged}}
+<tr><td><a href="#USER-{{.Name}}">{{.Name}}</a></td><td>{{.Programmer}}</td><td>{{.DefaultGroup}}</td>
+<td class="attr">{{join .Attributes " "}}</td><td>{{join .Groups " "}}</td><td>{{if .UID}}Yes{{end}}</td>
+<td>{{if .Revoked}}Yes{{end}}</td><td>{{.LastAccess}}</td></tr>
+{{- end}}
+</table>
+
+{{- range $t := .Types}}
+<section class="type">
+<h2 id="type-{{$t.Name}}">{{$t.Name}} (<span class="count">{{len $t.Profiles}}</span>)</h2>
+{{- range $t.Profiles}}
+<details class="profile" id="{{$t.Name}}-{{.Name}}" data-name="{{.Class}} {{.Name}}">
+<summary>{{if .Class}}{{.Class}} {{end}}{{.Name}}{{range .Segments}} [{{.Name}}]{{end}}</summary>
+<div>
+{{- range .Segments}}
+<h3>{{.Name}} segment (offset {{.Offset}}, size {{.Size}})</h3>
+<table>
+<tr><th>Field</th><th>Value</th><th>Hex</th></tr>
+{{- range .Fields}}
+<tr><td>{{.Name}}</td><td>{{.Value}}</td><td class="hex">{{.Hex}}</td></tr>
+{{- end}}
+</table>
+{{- range .RepeatGroups}}
+<table>
+<tr><th colspan="{{len .Columns}}">{{.Name}}</th></tr>
+<tr>{{range .Columns}}<th>{{.}}</th>{{end}}</tr>
+{{- range .Rows}}
+<tr>{{range .}}<td>{{.Value}}<div class="hex">{{.Hex}}</div></td>{{end}}</tr>
+{{- end}}
+</table>
+{{- end}}
+<details><summary>Raw data</summary><div class="raw">{{.Raw}}</div></details>
+{{- end}}
+</div>
+</details>
+{{- end}}
+</section>
+{{- end}}
+
+<script>
+document.getElementById("search").addEventListener("input", function() {
+    var q = this.value.trim().toUpperCase();
+    document.querySelectorAll("section.type").forEach(function(section) {
+        var count = 0;
+        section.querySelectorAll("details.profile").forEach(function(p) {
+            var match = q.length == 0 || p.dataset.name.toUpperCase().indexOf(q) >= 0;
+            p.classList.toggle("hidden", !match);
+            if (match) {
+                count++;
+            }
+        });
+        section.querySelector(".count").textContent = count;
+    });
+});
+function openProfile() {
+    var p = document.getElementById(decodeURIComponent(location.hash.substring(1)));
+    if (p && p.tagName == "DETAILS") {
+        p.open = true;
+        p.scrollIntoView();
+    }
+}
+window.addEventListener("hashchange", openProfile);
+openProfile();
+</script>
+</body>
+</html>
+`
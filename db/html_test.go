@@ -0,0 +1,42 @@
+package db
+
+import (
+    "reflect"
+    "testing"
+
+    "racfudit/decode"
+)
+
+func TestHTMLSegmentRedactsSecrets(t *testing.T) {
+    itemT := reflect.StructOf([]reflect.StructField{{Name: "OLDPWD", Type: reflect.TypeOf(decode.HexStr{})}})
+    sT := reflect.StructOf([]reflect.StructField{
+        {Name: "PGMRNAME", Type: reflect.TypeOf(decode.EBCDICStr{})},
+        {Name: "PASSWORD", Type: reflect.TypeOf(decode.HexStr{})},
+        {Name: "PHRASE", Type: reflect.TypeOf(decode.HexStr{})},
+        {Name: "OLDPWD_RG", Type: reflect.SliceOf(itemT)},
+    })
+    v := reflect.New(sT)
+    v.Elem().Field(0).Set(reflect.ValueOf(testEBCDIC("JOHN")))
+    v.Elem().Field(1).Set(reflect.ValueOf(decode.HexStr{0x01, 0x02, 0x03, 0x04}))
+    item := reflect.New(itemT).Elem()
+    item.Field(0).Set(reflect.ValueOf(decode.HexStr{0x05, 0x06}))
+    v.Elem().Field(3).Set(reflect.Append(v.Elem().Field(3), item))
+
+    hs := newHTMLSegment(NewSegment("BASE", 1, 0, 0, 0, "0102030405", &v))
+    if hs.Raw != COMMAND_SECRET {
+        t.Errorf("Raw = %q, want %q", hs.Raw, COMMAND_SECRET)
+    }
+    want := []htmlField{{"PGMRNAME", "JOHN", "d1d6c8d5"}, {"PASSWORD", COMMAND_SECRET, COMMAND_SECRET}, {"PHRASE", "", ""}}
+    if !reflect.DeepEqual(hs.Fields, want) {
+        t.Errorf("Fields = %v, want %v", hs.Fields, want)
+    }
+    if len(hs.RepeatGroups) != 1 || hs.RepeatGroups[0].Rows[0][0].Value != COMMAND_SECRET || hs.RepeatGroups[0].Rows[0][0].Hex != COMMAND_SECRET {
+        t.Errorf("Password history isn't redacted: %v", hs.RepeatGroups)
+    }
+
+    // Segments without credentials keep raw data
+    tso := reflect.New(reflect.StructOf([]reflect.StructField{{Name: "TPROC", Type: reflect.TypeOf(decode.EBCDICStr{})}}))
+    if hs := newHTMLSegment(NewSegment("TSO", 2, 0, 0, 0, "e3e2d6", &tso)); hs.Raw != "e3e2d6" {
+        t.Errorf("Raw = %q, want e3e2d6", hs.Raw)
+    }
+}
@@ -0,0 +1,265 @@
+package db
+
+import (
+    "encoding/hex"
+    "encoding/json"
+    "fmt"
+    "io"
+    "os"
+    "path/filepath"
+    "reflect"
+    "sort"
+    "strings"
+
+    "racfudit/common"
+    "racfudit/decode"
+)
+
+// JSON representation of runtime DB profile
+type JSONProfile struct {
+    Name     string        `json:"name"`
+    Type     string        `json:"type"`
+    Class    string        `json:"class,omitempty"`
+    Segments []JSONSegment `json:"segments"`
+}
+
+type JSONSegment struct {
+    Name         string                 `json:"name"`
+    ID           uint8                  `json:"id"`
+    Offset       string                 `json:"offset"`
+    PhysicalSize uint32                 `json:"physicalSize"`
+    LogicalSize  uint32                 `json:"logicalSize"`
+    Raw          string                 `json:"raw"`
+    Fields       map[string]interface{} `json:"fields"`
+}
+
+// Field value: decoded value (number for integer fields, otherwise string) and raw data in hex
+type JSONField struct {
+    Value interface{} `json:"value"`
+    Hex   string      `json:"hex"`
+}
+
+// Get raw data of reflect.Value from runtime DB in hex
+func FieldHex(val reflect.Value) string {
+    switch v := val.Interface().(type) {
+    case uint8, uint16, uint32, uint64:
+        return fmt.Sprintf("%0*x", val.Type().Size()*2, v)
+    case decode.EBCDICStr:
+        return v.Hex()
+    case decode.HexStr:
+        return hex.EncodeToString(v)
+    case decode.Date:
+        return v.Hex()
+    case decode.Time:
+        return v.Hex()
+    case decode.Flag:
+        return v.Hex()
+    case decode.KerbEncType:
+        return v.Hex()
+    case []byte:
+        return hex.EncodeToString(v)
+    }
+    return ""
+}
+
+func jsonField(val reflect.Value) JSONField {
+    switch val.Kind() {
+    case reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+        return JSONField{val.Uint(), FieldHex(val)}
+    }
+    return JSONField{DumpField(val), FieldHex(val)}
+}
+
+// Convert segment structure to map of fields. RepeatGroups are converted to arrays of field maps
+func jsonFields(sDataV reflect.Value) map[string]interface{} {
+    retVal := make(map[string]interface{})
+    sDataV = reflect.Indirect(sDataV)
+    sDataT := sDataV.Type()
+    for i := 0; i < sDataV.NumField(); i++ {
+        fValue := sDataV.Field(i)
+        fType := sDataT.Field(i)
+
+        // Check if field is a RepeatGroup field
+        if fType.Type.Kind() == reflect.Slice &&
+            fType.Type.Elem().Kind() == reflect.Struct &&
+            strings.HasSuffix(fType.Name, "_RG") {
+
+            items := make([]map[string]interface{}, fValue.Len())
+            for j := 0; j < fValue.Len(); j++ {
+                items[j] = jsonFields(fValue.Index(j))
+            }
+            retVal[fType.Name] = items
+            continue
+        }
+        retVal[fType.Name] = jsonField(fValue)
+    }
+    return retVal
+}
+
+// Convert runtime DB profile to its JSON representation
+func NewJSONProfile(p *Profile) *JSONProfile {
+    retVal := &JSONProfile{Name: strings.TrimSpace(p.Name), Type: p.Type.Name, Class: p.Class(), Segments: make([]JSONSegment, 0)}
+    for _, s := range p.Segments {
+        retVal.Segments = append(retVal.Segments, JSONSegment{
+            Name:         s.Name,
+            ID:           s.ID,
+            Offset:       s.Address.String(),
+            PhysicalSize: s.PhysicalSize,
+            LogicalSize:  s.LogicalSize,
+            Raw:          s.Raw,
+            Fields:       jsonFields(s.Data),
+        })
+    }
+    return retVal
+}
+
+type nopCloser struct {
+    io.Writer
+}
+
+func (nopCloser) Close() error { return nil }
+
+// Create output file. If the file name is "-" then stdout is used
+func createOutput(fileName string) (io.WriteCloser, error) {
+    if fileName == "-" {
+        return nopCloser{os.Stdout}, nil
+    }
+    return os.Create(fileName)
+}
+
+// Save runtime DB as JSON array of profiles
+func ToJSON(profiles []*Profile, fileName string) {
+    f, err := createOutput(fileName)
+    if err != nil {
+        common.Fatal(fmt.Errorf("Can not create JSON file: %v", err))
+    }
+    defer f.Close()
+
+    common.Log.Info("Saving RACF profiles as JSON file %s", fileName)
+    fmt.Fprint(f, "[")
+    for i, p := range profiles {
+        common.Log.Debug("Saving profile: %q (%v)\n", p.Name, &p.Type)
+        data, err := json.MarshalIndent(NewJSONProfile(p), "  ", "  ")
+        if err != nil {
+            common.Fatal(fmt.Errorf("Can not convert profile %s to JSON: %v", p.Name, err))
+        }
+        if i > 0 {
+            fmt.Fprint(f, ",")
+        }
+        fmt.Fprintf(f, "\n  %s", data)
+    }
+    fmt.Fprint(f, "\n]\n")
+}
+
+// Save runtime DB as JSON Lines (one profile per line)
+func ToJSONLines(profiles []*Profile, fileName string) {
+    f, err := createOutput(fileName)
+    if err != nil {
+        common.Fatal(fmt.Errorf("Can not create JSON Lines file: %v", err))
+    }
+    defer f.Close()
+
+    common.Log.Info("Saving RACF profiles as JSON Lines file %s", fileName)
+    enc := json.NewEncoder(f)
+    for _, p := range profiles {
+        common.Log.Debug("Saving profile: %q (%v)\n", p.Name, &p.Type)
+        if err := enc.Encode(NewJSONProfile(p)); err != nil {
+            common.Fatal(fmt.Errorf("Can not convert profile %s to JSON: %v", p.Name, err))
+        }
+    }
+}
+
+// Get JSON Schema of the field value
+func jsonSchemaField(t reflect.Type) map[string]interface{} {
+    if t.Kind() == reflect.Slice && t.Elem().Kind() == reflect.Struct {
+        return map[string]interface{}{"type": "array", "items": jsonSchemaFields(t.Elem())}
+    }
+    valueType := "string"
+    switch t.Kind() {
+    case reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+        valueType = "integer"
+    }
+    return map[string]interface{}{
+        "type": "object",
+        "properties": map[string]interface{}{
+            "value": map[string]interface{}{"type": valueType, "description": t.String()},
+            "hex":   map[string]interface{}{"type": "string", "pattern": "^[0-9a-f]*$"},
+        },
+        "required":             []string{"value", "hex"},
+        "additionalProperties": false,
+    }
+}
+
+// Get JSON Schema of segment (or RepeatGroup) fields
+func jsonSchemaFields(t reflect.Type) map[string]interface{} {
+    properties := make(map[string]interface{})
+    required := make([]string, 0)
+    for i := 0; i < t.NumField(); i++ {
+        properties[t.Field(i).Name] = jsonSchemaField(t.Field(i).Type)
+        required = append(required, t.Field(i).Name)
+    }
+    return map[string]interface{}{"type": "object", "properties": properties, "required": required, "additionalProperties": false}
+}
+
+// Create JSON Schema for profiles of the template
+func NewJSONSchema(template string, segments map[string]reflect.Type) map[string]interface{} {
+    names := make([]string, 0)
+    for name := range segments {
+        names = append(names, name)
+    }
+    sort.Strings(names)
+
+    defs := make(map[string]interface{})
+    refs := make([]interface{}, 0)
+    for _, name := range names {
+        defs[name] = map[string]interface{}{
+            "type": "object",
+            "properties": map[string]interface{}{
+                "name":         map[string]interface{}{"const": name},
+                "id":           map[string]interface{}{"type": "integer"},
+                "offset":       map[string]interface{}{"type": "string"},
+                "physicalSize": map[string]interface{}{"type": "integer"},
+                "logicalSize":  map[string]interface{}{"type": "integer"},
+                "raw":          map[string]interface{}{"type": "string", "pattern": "^[0-9a-f]*$"},
+                "fields":       jsonSchemaFields(segments[name]),
+            },
+            "required": []string{"name", "id", "offset", "physicalSize", "logicalSize", "raw", "fields"},
+        }
+        refs = append(refs, map[string]interface{}{"$ref": "#/$defs/" + name})
+    }
+
+    return map[string]interface{}{
+        "$schema": "https://json-schema.org/draft/2020-12/schema",
+        "$id":     fmt.Sprintf("%s.schema.json", template),
+        "title":   fmt.Sprintf("RACF %s profile", template),
+        "type":    "object",
+        "properties": map[string]interface{}{
+            "name":     map[string]interface{}{"type": "string"},
+            "type":     map[string]interface{}{"const": template},
+            "class":    map[string]interface{}{"type": "string"},
+            "segments": map[string]interface{}{"type": "array", "items": map[string]interface{}{"oneOf": refs}},
+        },
+        "required": []string{"name", "type", "segments"},
+        "$defs":    defs,
+    }
+}
+
+// Save JSON Schema of each template into directory (<TEMPLATE>.schema.json)
+func ToJSONSchema(profileStructs map[string]map[string]reflect.Type, dirName string) {
+    if err := os.MkdirAll(dirName, 0755); err != nil {
+        common.Fatal(fmt.Errorf("Can not create JSON Schema directory: %v", err))
+    }
+
+    common.Log.Info("Saving JSON Schema of RACF templates in directory %s", dirName)
+    for template, segments := range profileStructs {
+        fileName := filepath.Join(dirName, fmt.Sprintf("%s.schema.json", template))
+        common.Log.Debug("Saving JSON Schema of template %s as %s", template, fileName)
+        data, err := json.MarshalIndent(NewJSONSchema(template, segments), "", "  ")
+        if err != nil {
+            common.Fatal(fmt.Errorf("Can not create JSON Schema of template %s: %v", template, err))
+        }
+        if err := os.WriteFile(fileName, append(data, '\n'), 0644); err != nil {
+            common.Fatal(fmt.Errorf("Can not save JSON Schema file: %v", err))
+        }
+    }
+}
@@ -0,0 +1,223 @@
+package db
+
+import (
+    "bufio"
+    "bytes"
+    "encoding/json"
+    "fmt"
+    "os"
+    "path/filepath"
+    "reflect"
+    "regexp"
+    "sort"
+    "strings"
+    "testing"
+
+    "racfudit/decode"
+)
+
+// Validate JSON value against JSON Schema. Only the keywords used by NewJSONSchema are supported:
+// $ref (to #/$defs), type, const, pattern, properties, required, additionalProperties, items and oneOf
+func testValidateJSONSchema(root, schema map[string]interface{}, value interface{}, path string) []string {
+    if ref, ok := schema["$ref"].(string); ok {
+        def, ok := root["$defs"].(map[string]interface{})[strings.TrimPrefix(ref, "#/$defs/")].(map[string]interface{})
+        if !ok {
+            return []string{fmt.Sprintf("%s: unresolved $ref %s", path, ref)}
+        }
+        return testValidateJSONSchema(root, def, value, path)
+    }
+
+    errs := make([]string, 0)
+    if c, ok := schema["const"]; ok && !reflect.DeepEqual(c, value) {
+        errs = append(errs, fmt.Sprintf("%s: %v is not %v", path, value, c))
+    }
+    if typ, ok := schema["type"].(string); ok {
+        valid := false
+        switch v := value.(type) {
+        case string:
+            valid = typ == "string"
+        case json.Number:
+            valid = typ == "number" || typ == "integer" && regexp.MustCompile(`^-?[0-9]+$`).MatchString(v.String())
+        case bool:
+            valid = typ == "boolean"
+        case []interface{}:
+            valid = typ == "array"
+        case map[string]interface{}:
+            valid = typ == "object"
+        case nil:
+            valid = typ == "null"
+        }
+        if !valid {
+            return append(errs, fmt.Sprintf("%s: %#v is not %s", path, value, typ))
+        }
+    }
+    if pattern, ok := schema["pattern"].(string); ok {
+        if s, _ := value.(string); !regexp.MustCompile(pattern).MatchString(s) {
+            errs = append(errs, fmt.Sprintf("%s: %q doesn't match %s", path, s, pattern))
+        }
+    }
+    if obj, ok := value.(map[string]interface{}); ok {
+        properties, _ := schema["properties"].(map[string]interface{})
+        required, _ := schema["required"].([]interface{})
+        for _, name := range required {
+            if _, ok := obj[name.(string)]; !ok {
+                errs = append(errs, fmt.Sprintf("%s: %s is missing", path, name))
+            }
+        }
+        for name, v := range obj {
+            if p, ok := properties[name].(map[string]interface{}); ok {
+                errs = append(errs, testValidateJSONSchema(root, p, v, path+"."+name)...)
+            } else if schema["additionalProperties"] == false {
+                errs = append(errs, fmt.Sprintf("%s: additional property %s", path, name))
+            }
+        }
+    }
+    if arr, ok := value.([]interface{}); ok {
+        if items, ok := schema["items"].(map[string]interface{}); ok {
+            for i, v := range arr {
+                errs = append(errs, testValidateJSONSchema(root, items, v, fmt.Sprintf("%s[%d]", path, i))...)
+            }
+        }
+    }
+    if oneOf, ok := schema["oneOf"].([]interface{}); ok {
+        matched := 0
+        for _, s := range oneOf {
+            if len(testValidateJSONSchema(root, s.(map[string]interface{}), value, path)) == 0 {
+                matched++
+            }
+        }
+        if matched != 1 {
+            errs = append(errs, fmt.Sprintf("%s: %d schemas of oneOf are matched, want 1", path, matched))
+        }
+    }
+    sort.Strings(errs)
+    return errs
+}
+
+// Decode JSON keeping numbers as json.Number (uint64 values don't fit float64)
+func testDecodeJSON(t *testing.T, data []byte, v interface{}) {
+    t.Helper()
+    dec := json.NewDecoder(bytes.NewReader(data))
+    dec.UseNumber()
+    if err := dec.Decode(v); err != nil {
+        t.Fatal(err)
+    }
+}
+
+func TestJSONSchemaOfProfiles(t *testing.T) {
+    p := NewProfile("IBMUSER", "USER", 2)
+    baseT := testSegmentOf(p, "BASE", testFields{
+        {"PGMRNAME", "SYS1"},
+        {"UID", uint64(1<<53 + 1)},
+        {"PASSINT", uint8(30)},
+        {"AUTHDATE", decode.Date{0x24, 0x00, 0x2f}},
+        {"FLAG1", decode.Flag{0x80}},
+        {"CGGRPCT", []testFields{
+            {{"CGGRPNM", "SYS1"}, {"CGINITCT", uint16(42)}},
+            {{"CGGRPNM", "SYS2"}, {"CGINITCT", uint16(7)}},
+        }},
+    })
+    omvsT := testSegmentOf(p, "OMVS", testFields{{"HOME", "/"}})
+
+    data, err := json.Marshal(NewJSONSchema("USER", map[string]reflect.Type{"BASE": baseT, "OMVS": omvsT}))
+    if err != nil {
+        t.Fatal(err)
+    }
+    var schema map[string]interface{}
+    testDecodeJSON(t, data, &schema)
+    if got := schema["$schema"]; got != "https://json-schema.org/draft/2020-12/schema" {
+        t.Errorf("$schema = %v, want draft 2020-12", got)
+    }
+
+    dir := t.TempDir()
+    jsonFile, linesFile := filepath.Join(dir, "racfdb.json"), filepath.Join(dir, "racfdb.jsonl")
+    ToJSON([]*Profile{p}, jsonFile)
+    ToJSONLines([]*Profile{p}, linesFile)
+
+    documents := make(map[string]map[string]interface{})
+    data, err = os.ReadFile(jsonFile)
+    if err != nil {
+        t.Fatal(err)
+    }
+    var array []map[string]interface{}
+    testDecodeJSON(t, data, &array)
+    if len(array) != 1 {
+        t.Fatalf("ToJSON() saved %d profiles, want 1", len(array))
+    }
+    documents["ToJSON"] = array[0]
+
+    f, err := os.Open(linesFile)
+    if err != nil {
+        t.Fatal(err)
+    }
+    defer f.Close()
+    scanner := bufio.NewScanner(f)
+    for scanner.Scan() {
+        var doc map[string]interface{}
+        testDecodeJSON(t, scanner.Bytes(), &doc)
+        documents[fmt.Sprintf("ToJSONLines[%d]", len(documents)-1)] = doc
+    }
+    if len(documents) != 2 {
+        t.Fatalf("ToJSONLines() saved %d profiles, want 1", len(documents)-1)
+    }
+
+    for name, doc := range documents {
+        if errs := testValidateJSONSchema(schema, schema, doc, "$"); len(errs) > 0 {
+            t.Errorf("%s: profile doesn't match schema:\n%s", name, strings.Join(errs, "\n"))
+        }
+
+        fields := doc["segments"].([]interface{})[0].(map[string]interface{})["fields"].(map[string]interface{})
+        for field, want := range map[string]string{
+            "PGMRNAME": "e2e8e2f1",
+            "UID":      "0020000000000001",
+            "PASSINT":  "1e",
+            "AUTHDATE": "24002f",
+            "FLAG1":    "80",
+            "CGGRPCT":  "00000002",
+        } {
+            if got := fields[field].(map[string]interface{})["hex"]; got != want {
+                t.Errorf("%s: hex of %s = %v, want %s", name, field, got, want)
+            }
+        }
+        if got := fields["UID"].(map[string]interface{})["value"]; got != json.Number("9007199254740993") {
+            t.Errorf("%s: value of UID = %v, want 9007199254740993", name, got)
+        }
+        items, ok := fields["CGGRPCT_RG"].([]interface{})
+        if !ok || len(items) != 2 {
+            t.Fatalf("%s: CGGRPCT_RG = %v, want array of 2 items", name, fields["CGGRPCT_RG"])
+        }
+        for i, want := range []string{"002a", "0007"} {
+            item := items[i].(map[string]interface{})
+            if got := item["CGINITCT"].(map[string]interface{})["hex"]; got != want {
+                t.Errorf("%s: hex of CGGRPCT_RG[%d].CGINITCT = %v, want %s", name, i, got, want)
+            }
+        }
+    }
+
+    // Schema rejects profiles with unknown fields, wrong value types and hex data
+    for _, tc := range []struct {
+        name   string
+        modify func(fields map[string]interface{})
+    }{
+        {"unknown field", func(fields map[string]interface{}) {
+            fields["UNKNOWN"] = map[string]interface{}{"value": "", "hex": ""}
+        }},
+        {"string value of integer field", func(fields map[string]interface{}) {
+            fields["PASSINT"].(map[string]interface{})["value"] = "30"
+        }},
+        {"upper case hex", func(fields map[string]interface{}) {
+            fields["PGMRNAME"].(map[string]interface{})["hex"] = "E2E8E2F1"
+        }},
+        {"RepeatGroup item without field", func(fields map[string]interface{}) {
+            delete(fields["CGGRPCT_RG"].([]interface{})[1].(map[string]interface{}), "CGGRPNM")
+        }},
+    } {
+        var doc map[string]interface{}
+        data, _ := json.Marshal(NewJSONProfile(p))
+        testDecodeJSON(t, data, &doc)
+        tc.modify(doc["segments"].([]interface{})[0].(map[string]interface{})["fields"].(map[string]interface{}))
+        if errs := testValidateJSONSchema(schema, schema, doc, "$"); len(errs) == 0 {
+            t.Errorf("Profile with %s matches schema", tc.name)
+        }
+    }
+}
@@ -0,0 +1,269 @@
+package db
+
+import (
+    "bufio"
+    "bytes"
+    "encoding/binary"
+    "encoding/hex"
+    "fmt"
+    "io"
+    "os"
+    "sort"
+    "strings"
+    "time"
+
+    "racfudit/common"
+    "racfudit/decode"
+)
+
+// Key length for each RACF Kerberos encryption type.
+// When several encryption types are set, CURKEY and PREVKEY keep keys one after another in order of decode.KerbEncTypes
+var kerbKeyLen = map[string]int{"DES": 8, "DES3": 24, "DESD": 8, "AES128": 16, "AES256": 32, "AES128SHA2": 16, "AES256SHA2": 32}
+
+// Kerberos enctype numbers (RFC 3961, RFC 3962, RFC 8009) used in keytab
+var kerbKeytabEncType = map[string]uint16{"DES": 3, "DES3": 16, "DESD": 8, "AES128": 17, "AES256": 18, "AES128SHA2": 19, "AES256SHA2": 20}
+
+// Encryption types which are considered as weak
+var kerbWeakEncTypes = map[string]bool{"DES": true, "DESD": true}
+
+type KerbKey struct {
+    Version uint8
+    Current bool
+    EncType string
+    Key     []byte
+}
+
+type KerbPrincipal struct {
+    Profile   string
+    Type      string
+    Principal string
+    Realm     string
+    Salt      string
+    EncTypes  []string
+    Keys      []KerbKey
+    Trust     string // Realm trust relationship (only for REALM class profiles)
+}
+
+func (kp *KerbPrincipal) String() string {
+    retVal := fmt.Sprintf("%s@%s (%s profile %s)\n", kp.Principal, kp.Realm, kp.Type, kp.Profile)
+    if len(kp.Trust) > 0 {
+        retVal += fmt.Sprintf("\tTrust: %s\n", kp.Trust)
+    }
+    retVal += fmt.Sprintf("\tEncryption types: %s\n", strings.Join(kp.EncTypes, ","))
+    retVal += fmt.Sprintf("\tSalt: %s\n", kp.Salt)
+    for _, k := range kp.Keys {
+        state := "previous"
+        if k.Current {
+            state = "current"
+        }
+        retVal += fmt.Sprintf("\tKey (%s; version %d; %s): %s\n", state, k.Version, k.EncType, hex.EncodeToString(k.Key))
+    }
+    return retVal
+}
+
+// Check if the principal has only weak (DES based) encryption types
+func (kp *KerbPrincipal) IsWeak() bool {
+    if len(kp.EncTypes) == 0 {
+        return false
+    }
+    for _, e := range kp.EncTypes {
+        if !kerbWeakEncTypes[e] {
+            return false
+        }
+    }
+    return true
+}
+
+// Extract Kerberos principals and their keys from USER and REALM class KERB segments
+func ExtractKerberos(profiles []*Profile) []*KerbPrincipal {
+    retVal := make([]*KerbPrincipal, 0)
+
+    // Local realm is defined by KERBNAME of KERBDFLT profile in REALM class
+    var localRealm string
+    for _, p := range profiles {
+        if p.Class() == "REALM" && p.ResourceName() == "KERBDFLT" {
+            if s, ok := p.Segment("KERB"); ok {
+                localRealm = s.FieldString("KERBNAME")
+            }
+        }
+    }
+
+    for _, p := range profiles {
+        if p.Type.Name != "USER" && p.Class() != "REALM" {
+            continue
+        }
+        s, ok := p.Segment("KERB")
+        if !ok {
+            continue
+        }
+
+        kp := &KerbPrincipal{Profile: p.Name, Type: p.Type.Name, Realm: localRealm, Salt: s.FieldString("SALT")}
+        if p.Type.Name == "USER" {
+            kp.Principal = s.FieldString("KERBNAME")
+            if len(kp.Principal) == 0 {
+                kp.Principal = strings.TrimSpace(p.Name)
+            }
+        } else {
+            kp.Type = "REALM"
+            kp.Profile = p.ResourceName()
+            kp.Principal, kp.Realm, kp.Trust = kerbRealmPrincipal(kp.Profile, localRealm)
+        }
+
+        // ENCRYPT contains allowed encryption types, ENCTYPE contains types of the stored keys
+        var encTypes decode.KerbEncType = s.FieldBytes("ENCTYPE")
+        if len(encTypes.Names()) == 0 {
+            encTypes = s.FieldBytes("ENCRYPT")
+        }
+        kp.EncTypes = encTypes.Names()
+
+        kp.Keys = append(kp.Keys, kerbSplitKeys(s.FieldBytes("CURKEY"), uint8(s.FieldUint("CURKEYV")), true, kp.EncTypes)...)
+        kp.Keys = append(kp.Keys, kerbSplitKeys(s.FieldBytes("PREVKEY"), uint8(s.FieldUint("PREVKEYV")), false, kp.EncTypes)...)
+        retVal = append(retVal, kp)
+    }
+    return retVal
+}
+
+// Get principal, realm and trust relationship from REALM class profile name:
+// KERBDFLT - local realm definition; krbtgt/REALM - local ticket-granting service;
+// /.../REALM1/krbtgt/REALM2 - cross-realm trust between REALM1 and REALM2
+func kerbRealmPrincipal(name string, localRealm string) (string, string, string) {
+    switch {
+    case name == "KERBDFLT":
+        return "krbtgt/" + localRealm, localRealm, "local realm definition"
+    case strings.HasPrefix(name, "/.../"):
+        parts := strings.SplitN(strings.TrimPrefix(name, "/.../"), "/", 2)
+        if len(parts) == 2 {
+            return parts[1], parts[0], fmt.Sprintf("cross-realm %s -> %s", parts[0], strings.TrimPrefix(parts[1], "krbtgt/"))
+        }
+    case strings.HasPrefix(name, "krbtgt/"):
+        return name, localRealm, "local ticket-granting service"
+    }
+    return name, localRealm, ""
+}
+
+// Split key field into keys of each encryption type. If the key length doesn't match
+// the set of encryption types then the whole key is paired with the first one
+func kerbSplitKeys(data []byte, version uint8, current bool, encTypes []string) []KerbKey {
+    retVal := make([]KerbKey, 0)
+    if len(bytes.Trim(data, "\x00")) == 0 {
+        return retVal
+    }
+
+    var size int
+    for _, e := range encTypes {
+        size += kerbKeyLen[e]
+    }
+    if size != len(data) || len(encTypes) == 0 {
+        encType := "UNKNOWN"
+        if len(encTypes) > 0 {
+            encType = encTypes[0]
+        }
+        common.Log.Warning("Kerberos key length %d doesn't match encryption types %v", len(data), encTypes)
+        return append(retVal, KerbKey{version, current, encType, data})
+    }
+
+    for ptr, i := 0, 0; i < len(encTypes); i++ {
+        l := kerbKeyLen[encTypes[i]]
+        retVal = append(retVal, KerbKey{version, current, encTypes[i], data[ptr : ptr+l]})
+        ptr += l
+    }
+    return retVal
+}
+
+// Create keytab entry (MIT keytab format version 0x0502)
+func kerbKeytabEntry(kp *KerbPrincipal, k KerbKey, timestamp uint32) []byte {
+    var entry bytes.Buffer
+    components := strings.Split(kp.Principal, "/")
+    nameType := uint32(1) // KRB5_NT_PRINCIPAL
+    if len(components) > 1 {
+        nameType = 2 // KRB5_NT_SRV_INST
+    }
+
+    binary.Write(&entry, binary.BigEndian, uint16(len(components)))
+    binary.Write(&entry, binary.BigEndian, uint16(len(kp.Realm)))
+    entry.WriteString(kp.Realm)
+    for _, c := range components {
+        binary.Write(&entry, binary.BigEndian, uint16(len(c)))
+        entry.WriteString(c)
+    }
+    binary.Write(&entry, binary.BigEndian, nameType)
+    binary.Write(&entry, binary.BigEndian, timestamp)
+    entry.WriteByte(k.Version)
+    binary.Write(&entry, binary.BigEndian, kerbKeytabEncType[k.EncType])
+    binary.Write(&entry, binary.BigEndian, uint16(len(k.Key)))
+    entry.Write(k.Key)
+    binary.Write(&entry, binary.BigEndian, uint32(k.Version))
+
+    var retVal bytes.Buffer
+    binary.Write(&retVal, binary.BigEndian, int32(entry.Len()))
+    retVal.Write(entry.Bytes())
+    return retVal.Bytes()
+}
+
+// Get plain text report about Kerberos principals and realms
+func kerbReport(profiles []*Profile) string {
+    principals := ExtractKerberos(profiles)
+    retVal := fmt.Sprintf("Kerberos principals: %d\n", len(principals))
+
+    trusts := make([]string, 0)
+    weak := make([]string, 0)
+    for _, kp := range principals {
+        if len(kp.Trust) > 0 {
+            trusts = append(trusts, fmt.Sprintf("\t%s: %s (%s)\n", kp.Profile, kp.Trust, strings.Join(kp.EncTypes, ",")))
+        }
+        if kp.IsWeak() {
+            weak = append(weak, fmt.Sprintf("\t%s@%s (%s profile %s): %s\n", kp.Principal, kp.Realm, kp.Type, kp.Profile, strings.Join(kp.EncTypes, ",")))
+        }
+    }
+    sort.Strings(trusts)
+    sort.Strings(weak)
+
+    retVal += fmt.Sprintf("Realm trust relationships: %d\n%s", len(trusts), strings.Join(trusts, ""))
+    retVal += fmt.Sprintf("Principals with only weak DES encryption types: %d\n%s", len(weak), strings.Join(weak, ""))
+    retVal += "Principal details:\n"
+    for _, kp := range principals {
+        retVal += kp.String()
+    }
+    return retVal
+}
+
+// Write Kerberos keys from runtime DB as keytab (MIT keytab format version 0x0502)
+func WriteKeytab(w io.Writer, profiles []*Profile, timestamp uint32) error {
+    if err := binary.Write(w, binary.BigEndian, uint16(0x0502)); err != nil {
+        return err
+    }
+    for _, kp := range ExtractKerberos(profiles) {
+        for _, k := range kp.Keys {
+            if _, ok := kerbKeytabEncType[k.EncType]; !ok {
+                common.Log.Warning("Skipping key of %s@%s: unknown encryption type", kp.Principal, kp.Realm)
+                continue
+            }
+            common.Log.Debug("Saving key of %s@%s (version %d; %s)", kp.Principal, kp.Realm, k.Version, k.EncType)
+            if _, err := w.Write(kerbKeytabEntry(kp, k, timestamp)); err != nil {
+                return err
+            }
+        }
+    }
+    return nil
+}
+
+// Save Kerberos keys from runtime DB as keytab file
+func ToKeytab(profiles []*Profile, fileName string) {
+    f, err := os.Create(fileName)
+    if err != nil {
+        common.Fatal(fmt.Errorf("Can not create keytab file: %v", err))
+    }
+    defer f.Close()
+
+    common.Log.Info("Saving Kerberos keys as keytab file %s", fileName)
+    w := bufio.NewWriter(f)
+    if err := WriteKeytab(w, profiles, uint32(time.Now().Unix())); err != nil {
+        common.Fatal(fmt.Errorf("Can not save keytab file: %v", err))
+    }
+    if err := w.Flush(); err != nil {
+        common.Fatal(fmt.Errorf("Can not save keytab file: %v", err))
+    }
+    if err := f.Close(); err != nil {
+        common.Fatal(fmt.Errorf("Can not save keytab file: %v", err))
+    }
+}
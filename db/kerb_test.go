@@ -0,0 +1,103 @@
+package db
+
+import (
+    "bytes"
+    "encoding/hex"
+    "reflect"
+    "strings"
+    "testing"
+
+    "racfudit/decode"
+)
+
+func TestKerbSplitKeys(t *testing.T) {
+    aes128 := bytes.Repeat([]byte{0x11}, 16)
+    aes256 := bytes.Repeat([]byte{0x22}, 32)
+    des := bytes.Repeat([]byte{0x33}, 8)
+    for _, tc := range []struct {
+        data     []byte
+        encTypes []string
+        want     []KerbKey
+    }{
+        {nil, []string{"AES128"}, []KerbKey{}},
+        {make([]byte, 16), []string{"AES128"}, []KerbKey{}},
+        {aes128, []string{"AES128"}, []KerbKey{{3, true, "AES128", aes128}}},
+        {append(append(append([]byte{}, des...), aes128...), aes256...), []string{"DES", "AES128", "AES256"},
+            []KerbKey{{3, true, "DES", des}, {3, true, "AES128", aes128}, {3, true, "AES256", aes256}}},
+        // Key length doesn't match encryption types: the whole key is paired with the first type
+        {aes256, []string{"AES128", "DES"}, []KerbKey{{3, true, "AES128", aes256}}},
+        {aes128, nil, []KerbKey{{3, true, "UNKNOWN", aes128}}},
+    } {
+        if got := kerbSplitKeys(tc.data, 3, true, tc.encTypes); !reflect.DeepEqual(got, tc.want) {
+            t.Errorf("kerbSplitKeys(%x, %v) = %v, want %v", tc.data, tc.encTypes, got, tc.want)
+        }
+    }
+}
+
+func TestKerbRealmPrincipal(t *testing.T) {
+    for _, tc := range []struct {
+        name      string
+        principal string
+        realm     string
+        trust     string
+    }{
+        {"KERBDFLT", "krbtgt/LOCAL.COM", "LOCAL.COM", "local realm definition"},
+        {"krbtgt/LOCAL.COM", "krbtgt/LOCAL.COM", "LOCAL.COM", "local ticket-granting service"},
+        {"/.../R1.COM/krbtgt/R2.COM", "krbtgt/R2.COM", "R1.COM", "cross-realm R1.COM -> R2.COM"},
+        {"/.../R1.COM", "/.../R1.COM", "LOCAL.COM", ""},
+        {"OTHER", "OTHER", "LOCAL.COM", ""},
+    } {
+        principal, realm, trust := kerbRealmPrincipal(tc.name, "LOCAL.COM")
+        if principal != tc.principal || realm != tc.realm || trust != tc.trust {
+            t.Errorf("kerbRealmPrincipal(%s) = %q, %q, %q; want %q, %q, %q", tc.name, principal, realm, trust, tc.principal, tc.realm, tc.trust)
+        }
+    }
+}
+
+// Keytab entry of HTTP/host.example.com@EXAMPLE.COM (version 3, AES128 key 000102..0f, timestamp 0x5f5e1000)
+// in MIT keytab format version 0x0502
+var kerbTestEntry = strings.Join([]string{
+    "00000048", // Entry size
+    "0002",     // Number of components
+    "000b", hex.EncodeToString([]byte("EXAMPLE.COM")),
+    "0004", hex.EncodeToString([]byte("HTTP")),
+    "0010", hex.EncodeToString([]byte("host.example.com")),
+    "00000002", // Name type (KRB5_NT_SRV_INST)
+    "5f5e1000", // Timestamp
+    "03",       // Key version (8 bits)
+    "0011",     // Encryption type (aes128-cts-hmac-sha1-96)
+    "0010", "000102030405060708090a0b0c0d0e0f",
+    "00000003", // Key version (32 bits)
+}, "")
+
+func TestKerbKeytabEntry(t *testing.T) {
+    kp := &KerbPrincipal{Principal: "HTTP/host.example.com", Realm: "EXAMPLE.COM"}
+    key, _ := hex.DecodeString("000102030405060708090a0b0c0d0e0f")
+    got := hex.EncodeToString(kerbKeytabEntry(kp, KerbKey{3, true, "AES128", key}, 0x5f5e1000))
+    if got != kerbTestEntry {
+        t.Errorf("kerbKeytabEntry() = %s, want %s", got, kerbTestEntry)
+    }
+}
+
+func TestWriteKeytab(t *testing.T) {
+    key, _ := hex.DecodeString("000102030405060708090a0b0c0d0e0f")
+    user := NewProfile("HTTPSRV", "USER", 2)
+    testSegmentOf(user, "KERB", testFields{
+        {"KERBNAME", "HTTP/host.example.com"},
+        {"ENCTYPE", decode.KerbEncType{0x10, 0, 0, 0}},
+        {"CURKEY", decode.HexStr(key)},
+        {"CURKEYV", uint8(3)},
+        {"PREVKEY", decode.HexStr(make([]byte, 16))},
+        {"PREVKEYV", uint8(2)},
+    })
+    realm := NewProfile("REALM   KERBDFLT", "GENERAL", 5)
+    testSegmentOf(realm, "KERB", testFields{{"KERBNAME", "EXAMPLE.COM"}})
+
+    var buf bytes.Buffer
+    if err := WriteKeytab(&buf, []*Profile{realm, user}, 0x5f5e1000); err != nil {
+        t.Fatal(err)
+    }
+    if got, want := hex.EncodeToString(buf.Bytes()), "0502"+kerbTestEntry; got != want {
+        t.Errorf("WriteKeytab() = %s, want %s", got, want)
+    }
+}
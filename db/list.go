@@ -0,0 +1,581 @@
+package db
+
+import (
+    "bufio"
+    "fmt"
+    "io"
+    "sort"
+    "strings"
+
+    "racfudit/common"
+    "racfudit/decode"
+)
+
+// Days of LOGDAYS flag byte (the bit is set if the user can't log on that day)
+var listLogonDays = []string{"SUNDAY", "MONDAY", "TUESDAY", "WEDNESDAY", "THURSDAY", "FRIDAY", "SATURDAY"}
+
+// Renderer of RACF LISTUSER, LISTGRP, LISTDSD and RLIST command output
+type lister struct {
+    w           io.Writer
+    schema      map[string][]*CustomField
+    authorities map[string]map[string]string   // Group -> user -> group authority
+    connects    map[string]map[string]*Segment // Group -> user -> connect item of user BASE segment
+}
+
+func newLister(w io.Writer, profiles []*Profile) *lister {
+    l := &lister{
+        w:           w,
+        schema:      BuildCustomFieldSchema(profiles),
+        authorities: make(map[string]map[string]string),
+        connects:    make(map[string]map[string]*Segment),
+    }
+    for _, p := range profiles {
+        s, ok := p.Segment("BASE")
+        if !ok {
+            continue
+        }
+        name := strings.TrimSpace(p.Name)
+        switch p.Type.Name {
+        case "GROUP":
+            l.authorities[name] = make(map[string]string)
+            for _, item := range s.Items("ACLCNT") {
+                l.authorities[name][item.FieldString("USERID")] = GroupAuthority(item.FieldBytes("USERACS"))
+            }
+        case "USER":
+            for _, item := range s.Items("CGGRPCT") {
+                group := item.FieldString("CGGRPNM")
+                if l.connects[group] == nil {
+                    l.connects[group] = make(map[string]*Segment)
+                }
+                l.connects[group][name] = item
+            }
+        }
+    }
+    return l
+}
+
+func (l *lister) printf(format string, args ...interface{}) {
+    fmt.Fprintf(l.w, format, args...)
+}
+
+// Print section title underlined by dashes
+func (l *lister) title(title string) {
+    l.printf("\n%s\n%s\n", title, strings.Repeat("-", len(title)))
+}
+
+// Format date field as YY.DDD (default value if the date isn't set)
+func listDate(s *Segment, name string, none string) string {
+    var d decode.Date = s.FieldBytes(name)
+    if t, ok := d.Time(); ok {
+        return fmt.Sprintf("%s.%03d", t.Format("06"), t.YearDay())
+    }
+    return none
+}
+
+// Format date field as day of year and year columns of LISTDSD and RLIST
+func listDayYear(s *Segment, name string) string {
+    var d decode.Date = s.FieldBytes(name)
+    if t, ok := d.Time(); ok {
+        return fmt.Sprintf(" %03d    %s", t.YearDay(), t.Format("06"))
+    }
+    return " 000    00"
+}
+
+// Get character field or default value if it is blank
+func listString(s *Segment, name string, none string) string {
+    if v := s.FieldString(name); len(v) > 0 {
+        return v
+    }
+    return none
+}
+
+// Get audit option in RACF list format, e.g. FAILURES(READ)
+func listAudit(s *Segment, field string, success string, failure string) string {
+    if audit := cmdAudit(s, field, success, failure); len(audit) > 0 {
+        return audit
+    }
+    return "NONE"
+}
+
+// Convert command operand KEYWORD(VALUE) to list line KEYWORD= VALUE
+func listOperand(op string) (string, bool) {
+    i := strings.Index(op, "(")
+    if i < 0 || !strings.HasSuffix(op, ")") {
+        return op, true
+    }
+    keyword, value := op[:i], op[i+1:len(op)-1]
+    if len(value) > 1 && strings.HasPrefix(value, "'") && strings.HasSuffix(value, "'") {
+        value = strings.ReplaceAll(value[1:len(value)-1], "''", "'")
+    }
+    // Secrets aren't displayed by RACF
+    if value == COMMAND_SECRET {
+        return "", false
+    }
+    return fmt.Sprintf("%s= %s", keyword, value), true
+}
+
+// Print sections of non-BASE segments (e.g. TSO INFORMATION). Segment keywords are the same as in RACF commands
+func (l *lister) segments(p *Profile) {
+    for i := range p.Segments {
+        s := &p.Segments[i]
+        if s.Name == "BASE" {
+            continue
+        }
+        l.title(s.Name + " INFORMATION")
+        lines := make([]string, 0)
+        switch s.Name {
+        case "CSDATA":
+            fields := DecodeCustomFields(p, l.schema)
+            for name, v := range fields {
+                label := name
+                if len(v.Field.ListHead) > 0 {
+                    label = v.Field.ListHead
+                }
+                lines = append(lines, fmt.Sprintf("%s= %s", label, v.Value))
+            }
+            sort.Strings(lines)
+        default:
+            operands, ok := commandSegments[p.Type.Name][s.Name]
+            if !ok {
+                lines = append(lines, "SEGMENT DATA CAN NOT BE DISPLAYED")
+                break
+            }
+            for _, op := range operands {
+                if line, ok := listOperand(op(s)); ok && len(line) > 0 {
+                    lines = append(lines, line)
+                }
+            }
+        }
+        if len(lines) == 0 {
+            lines = append(lines, "NO INFORMATION")
+        }
+        for _, line := range lines {
+            l.printf(" %s\n", line)
+        }
+    }
+}
+
+// Print LISTUSER output
+func (l *lister) listUser(p *Profile) {
+    name := strings.TrimSpace(p.Name)
+    s, ok := p.Segment("BASE")
+    if !ok {
+        l.printf("USER=%s\n", name)
+        l.segments(p)
+        return
+    }
+    l.printf("USER=%-8s  NAME=%-20s  OWNER=%-8s  CREATED=%s\n", name, listString(s, "PGMRNAME", "UNKNOWN"),
+        s.FieldString("AUTHOR"), listDate(s, "AUTHDATE", "00.000"))
+
+    protected := isFlagSet(s, "FLAG7", 0x80)
+    interval := fmt.Sprintf("%3d", s.FieldUint("PASSINT"))
+    if protected || s.FieldUint("PASSINT") == 0 {
+        interval = "N/A"
+    }
+    l.printf(" DEFAULT-GROUP=%-8s PASSDATE=%s PASS-INTERVAL=%s PHRASEDATE=%s\n", s.FieldString("DFLTGRP"),
+        listDate(s, "PASSDATE", "N/A"), interval, listDate(s, "PHRDATE", "N/A"))
+
+    attributes := make([]string, 0)
+    for _, a := range []struct {
+        name  string
+        field string
+    }{{"SPECIAL", "FLAG2"}, {"OPERATIONS", "FLAG3"}, {"AUDITOR", "FLAG6"}, {"ROAUDIT", "FLAGROA"}, {"REVOKED", "FLAG4"},
+        {"PROTECTED", "FLAG7"}, {"ADSP", "FLAG1"}, {"GRPACC", "FLAG5"}, {"OIDCARD", "FLAG8"}, {"UAUDIT", "UAUDIT"}} {
+        if isFlagSet(s, a.field, 0x80) {
+            attributes = append(attributes, a.name)
+        }
+    }
+    if len(attributes) == 0 {
+        attributes = append(attributes, "NONE")
+    }
+    l.printf(" ATTRIBUTES=%s\n", strings.Join(attributes, " "))
+    l.printf(" REVOKE DATE=%s   RESUME DATE=%s\n", listDate(s, "REVOKEDT", "NONE"), listDate(s, "RESUMEDT", "NONE"))
+    if date := listDate(s, "LJDATE", ""); len(date) > 0 {
+        l.printf(" LAST-ACCESS=%s/%s\n", date, unloadTime(s, "LJTIME"))
+    } else {
+        l.printf(" LAST-ACCESS=UNKNOWN\n")
+    }
+    classes := make([]string, 0)
+    for _, item := range s.Items("CLCNT") {
+        if v := item.FieldString("CLNAME"); len(v) > 0 {
+            classes = append(classes, v)
+        }
+    }
+    if len(classes) == 0 {
+        classes = append(classes, "NONE")
+    }
+    l.printf(" CLASS AUTHORIZATIONS=%s\n", strings.Join(classes, " "))
+    l.printf(" INSTALLATION-DATA=%s\n", listString(s, "INSTDATA", "NONE"))
+    l.printf(" MODEL-NAME=%s\n", listString(s, "MODELNAM", "NONE"))
+
+    days := "ANYDAY"
+    if b := s.FieldBytes("LOGDAYS"); len(b) > 0 && b[0]&0xfe != 0 {
+        allowed := make([]string, 0)
+        for i, day := range listLogonDays {
+            if b[0]&(0x80>>uint(i)) == 0 {
+                allowed = append(allowed, day)
+            }
+        }
+        days = strings.Join(allowed, " ")
+    }
+    logonTime := "ANYTIME"
+    if b := s.FieldBytes("LOGTIME"); len(strings.Trim(string(b), "\x00")) > 0 {
+        logonTime = s.FieldString("LOGTIME")
+    }
+    l.printf(" LOGON ALLOWED   (DAYS)          (TIME)\n")
+    l.printf(" ---------------------------------------------\n")
+    l.printf(" %-31s %s\n", days, logonTime)
+
+    for _, item := range s.Items("CGGRPCT") {
+        group := item.FieldString("CGGRPNM")
+        authority := "USE"
+        if a, ok := l.authorities[group][name]; ok {
+            authority = a
+        }
+        l.printf("  GROUP=%-8s  AUTH=%-8s CONNECT-OWNER=%-8s CONNECT-DATE=%s\n", group, authority,
+            item.FieldString("CGAUTHOR"), listDate(item, "CGAUTHDA", "00.000"))
+        lastConnect := "UNKNOWN"
+        if date := listDate(item, "CGLJDATE", ""); len(date) > 0 {
+            lastConnect = date + "/" + unloadTime(item, "CGLJTIME")
+        }
+        l.printf("    CONNECTS=%5d  UACC=%-8s LAST-CONNECT=%s\n", item.FieldUint("CGINITCT"), unloadAccess(item, "CGUACC"), lastConnect)
+        l.printf("    CONNECT ATTRIBUTES=%s\n", listConnectAttributes(item))
+        l.printf("    REVOKE DATE=%s   RESUME DATE=%s\n", listDate(item, "CGREVKDT", "NONE"), listDate(item, "CGRESMDT", "NONE"))
+    }
+    l.printf("SECURITY-LEVEL=%s\n", listSecurityLevel(s))
+    l.printf("CATEGORY-AUTHORIZATION\n")
+    if s.FieldUint("NUMCTGY") == 0 {
+        l.printf(" NONE SPECIFIED\n")
+    } else {
+        l.printf(" %d CATEGORIES\n", s.FieldUint("NUMCTGY"))
+    }
+    l.printf("SECURITY-LABEL=%s\n", listString(s, "SECLABEL", "NONE SPECIFIED"))
+    l.segments(p)
+}
+
+// Get connect attributes of the user connect item
+func listConnectAttributes(item *Segment) string {
+    attributes := make([]string, 0)
+    for _, a := range []struct {
+        name  string
+        field string
+    }{{"SPECIAL", "CGFLAG2"}, {"OPERATIONS", "CGFLAG3"}, {"AUDITOR", "CGGRPAUD"}, {"REVOKED", "CGFLAG4"},
+        {"ADSP", "CGFLAG1"}, {"GRPACC", "CGFLAG5"}} {
+        if isFlagSet(item, a.field, 0x80) {
+            attributes = append(attributes, a.name)
+        }
+    }
+    if len(attributes) == 0 {
+        return "NONE"
+    }
+    return strings.Join(attributes, " ")
+}
+
+func listSecurityLevel(s *Segment) string {
+    if level := s.FieldUint("SECLEVEL"); level > 0 {
+        return fmt.Sprintf("%d", level)
+    }
+    return "NONE SPECIFIED"
+}
+
+// Print LISTGRP output
+func (l *lister) listGroup(p *Profile) {
+    name := strings.TrimSpace(p.Name)
+    l.printf("INFORMATION FOR GROUP %s\n", name)
+    s, ok := p.Segment("BASE")
+    if !ok {
+        l.segments(p)
+        return
+    }
+    l.printf("    SUPERIOR GROUP=%-8s  OWNER=%-8s  CREATED=%s\n", listString(s, "SUPGROUP", "NONE"),
+        s.FieldString("AUTHOR"), listDate(s, "AUTHDATE", "00.000"))
+    l.printf("    INSTALLATION DATA=%s\n", listString(s, "INSTDATA", "NONE"))
+    if model := s.FieldString("MODELNAM"); len(model) > 0 {
+        l.printf("    MODEL DATA SET=%s\n", model)
+    } else {
+        l.printf("    NO MODEL DATA SET\n")
+    }
+    l.printf("    %s\n", onOff(isFlagSet(s, "NOTRMUAC", 0x80), "NOTERMUACC", "TERMUACC"))
+    if isFlagSet(s, "UNVFLG", 0x80) {
+        l.printf("    UNIVERSAL\n")
+    }
+
+    subgroups := make([]string, 0)
+    for _, item := range s.Items("SUBGRPCT") {
+        if v := item.FieldString("SUBGRPNM"); len(v) > 0 {
+            subgroups = append(subgroups, v)
+        }
+    }
+    if len(subgroups) == 0 {
+        l.printf("    NO SUBGROUPS\n")
+    }
+    for i := 0; i < len(subgroups); i += 8 {
+        label := "               "
+        if i == 0 {
+            label = "    SUBGROUP(S)="
+        }
+        end := i + 8
+        if end > len(subgroups) {
+            end = len(subgroups)
+        }
+        l.printf("%s %s\n", label, strings.Join(subgroups[i:end], " "))
+    }
+
+    members := s.Items("ACLCNT")
+    if len(members) == 0 {
+        l.printf("    NO USERS\n")
+    } else {
+        l.printf("    USER(S)=      ACCESS=      ACCESS COUNT=      UNIVERSAL ACCESS=\n")
+    }
+    for _, item := range members {
+        user := item.FieldString("USERID")
+        connect := l.connects[name][user]
+        count, uacc, attributes, revoke, resume := uint64(0), "NONE", "NONE", "NONE", "NONE"
+        if connect != nil {
+            count, uacc, attributes = connect.FieldUint("CGINITCT"), unloadAccess(connect, "CGUACC"), listConnectAttributes(connect)
+            revoke, resume = listDate(connect, "CGREVKDT", "NONE"), listDate(connect, "CGRESMDT", "NONE")
+        }
+        l.printf("      %-8s      %-8s      %06d              %s\n", user, GroupAuthority(item.FieldBytes("USERACS")), count, uacc)
+        l.printf("         CONNECT ATTRIBUTES=%s\n", attributes)
+        l.printf("         REVOKE DATE=%-22s RESUME DATE=%s\n", revoke, resume)
+    }
+    l.segments(p)
+}
+
+// Print creation, reference and change dates and access counts of data set and general resource profiles
+func (l *lister) listResourceStatistics(s *Segment, created string) {
+    l.printf("\nCREATION DATE   LAST REFERENCE DATE   LAST CHANGE DATE\n")
+    l.printf(" (DAY) (YEAR)       (DAY) (YEAR)         (DAY) (YEAR)\n")
+    l.printf("-------------   -------------------   ----------------\n")
+    l.printf("%-13s   %-11s           %s\n", listDayYear(s, created), listDayYear(s, "LREFDAT"), listDayYear(s, "LCHGDAT"))
+    l.printf("\nALTER COUNT   CONTROL COUNT   UPDATE COUNT   READ COUNT\n")
+    l.printf("-----------   -------------   ------------   ----------\n")
+    l.printf(" %05d          %05d           %05d          %05d\n", s.FieldUint("ACSALTR"), s.FieldUint("ACSCNTL"),
+        s.FieldUint("ACSUPDT"), s.FieldUint("ACSREAD"))
+}
+
+// Print standard and conditional access lists
+func (l *lister) listAccess(p *Profile) {
+    acl := p.ACL()
+    l.printf("\n  ID     ACCESS   ACCESS COUNT\n")
+    l.printf("-------- -------  ------------\n")
+    if len(acl) == 0 {
+        l.printf("NO USERS IN ACCESS LIST\n")
+    }
+    for _, e := range acl {
+        l.printf("%-8s %-8s    %06d\n", e.ID, e.Access, e.Count)
+    }
+
+    cacl := p.ConditionalACL()
+    l.printf("\n  ID     ACCESS   ACCESS COUNT   CLASS     ENTITY NAME\n")
+    l.printf("-------- -------  ------------   --------  -----------\n")
+    if len(cacl) == 0 {
+        l.printf("NO ENTRIES IN CONDITIONAL ACCESS LIST\n")
+    }
+    for _, e := range cacl {
+        l.printf("%-8s %-8s    %06d       %-8s  %s\n", e.ID, e.Access, e.Count, e.EntityClass, e.Entity)
+    }
+}
+
+// Print LISTDSD output
+func (l *lister) listDataset(p *Profile) {
+    name := strings.TrimSpace(p.Name)
+    if isGenericName(name) {
+        l.printf("INFORMATION FOR DATASET %s (G)\n", name)
+    } else {
+        l.printf("INFORMATION FOR DATASET %s\n", name)
+    }
+    s, ok := p.Segment("BASE")
+    if !ok {
+        l.segments(p)
+        return
+    }
+    l.printf("\nLEVEL  OWNER      UNIVERSAL ACCESS   WARNING\n")
+    l.printf("-----  --------   ----------------   -------\n")
+    l.printf(" %02d    %-8s   %-16s   %s\n", s.FieldUint("LEVEL"), s.FieldString("AUTHOR"), p.UACC(), unloadYesNo(s, "WARNING", 0x80))
+
+    l.title("AUDITING")
+    l.printf("%s\n", listAudit(s, "AUDIT", "AUDITQS", "AUDITQF"))
+    l.title("GLOBALAUDIT")
+    l.printf("%s\n", listAudit(s, "GAUDIT", "GAUDITQS", "GAUDITQF"))
+    l.title("NOTIFY")
+    l.printf("%s\n", listString(s, "NOTIFY", "NO USER TO BE NOTIFIED"))
+
+    dsType := unloadDSType(s)
+    if dsType == "NONVSAM" {
+        dsType = "NON-VSAM"
+    }
+    l.printf("\nCREATION GROUP  DATASET TYPE\n")
+    l.printf("--------------  ------------\n")
+    l.printf(" %-8s       %s\n", s.FieldString("GROUPNM"), dsType)
+    if !isGenericName(name) {
+        volumes := make([]string, 0)
+        for _, item := range s.Items("VOLCNT") {
+            if v := item.FieldString("VOLSER"); len(v) > 0 {
+                volumes = append(volumes, v)
+            }
+        }
+        l.title("VOLUMES ON WHICH DATASET RESIDES")
+        l.printf("%s\n", strings.Join(volumes, " "))
+    }
+    if retpd := s.FieldUint("RETPD"); retpd > 0 {
+        l.printf("\nRETENTION PERIOD=%d\n", retpd)
+    }
+    l.title("INSTALLATION DATA")
+    l.printf("%s\n", listString(s, "INSTDATA", "NONE"))
+    l.printf("\nSECURITY LEVEL\n")
+    l.printf("%s\n", listSecurityLevel(s))
+    l.printf("\nSECLABEL\n")
+    l.printf("%s\n", listString(s, "SECLABEL", "NO SECLABEL"))
+
+    l.listResourceStatistics(s, "CREADATE")
+    l.listAccess(p)
+    l.segments(p)
+}
+
+// Print RLIST output
+func (l *lister) listResource(p *Profile) {
+    l.printf("CLASS      NAME\n")
+    l.printf("-----      ----\n")
+    l.printf("%-8s   %s\n", p.Class(), p.ResourceName())
+    s, ok := p.Segment("BASE")
+    if !ok {
+        l.segments(p)
+        return
+    }
+
+    members := make([]string, 0)
+    for _, item := range s.Items("MEMCNT") {
+        if m := decode.EBCDICStr(item.FieldBytes("MEMLST")); m.IsPrint() {
+            members = append(members, m.Trim())
+        } else {
+            members = append(members, fmt.Sprintf("%X", item.FieldBytes("MEMLST")))
+        }
+    }
+    if len(members) > 0 {
+        l.title("RESOURCES IN GROUP")
+        for _, m := range members {
+            l.printf("%s\n", m)
+        }
+    }
+
+    l.printf("\nLEVEL  OWNER      UNIVERSAL ACCESS  WARNING\n")
+    l.printf("-----  --------   ----------------  -------\n")
+    l.printf(" %02d    %-8s   %-16s  %s\n", s.FieldUint("LEVEL"), s.FieldString("OWNER"), p.UACC(), unloadYesNo(s, "WARNING", 0x80))
+
+    l.title("INSTALLATION DATA")
+    l.printf("%s\n", listString(s, "INSTDATA", "NONE"))
+    l.title("APPLICATION DATA")
+    l.printf("%s\n", listString(s, "APPLDATA", "NONE"))
+    l.printf("\nSECLEVEL\n")
+    l.printf("%s\n", listSecurityLevel(s))
+    l.printf("\nSECLABEL\n")
+    l.printf("%s\n", listString(s, "SECLABEL", "NO SECLABEL"))
+    l.title("AUDITING")
+    l.printf("%s\n", listAudit(s, "AUDIT", "AUDITQS", "AUDITQF"))
+    l.title("GLOBALAUDIT")
+    l.printf("%s\n", listAudit(s, "GAUDIT", "GAUDITQS", "GAUDITQF"))
+    l.title("NOTIFY")
+    l.printf("%s\n", listString(s, "NOTIFY", "NO USER TO BE NOTIFIED"))
+
+    l.listResourceStatistics(s, "DEFDATE")
+    l.listAccess(p)
+    l.segments(p)
+}
+
+// Print the profile as output of the corresponding RACF list command (false for other profile types)
+func (l *lister) list(p *Profile) bool {
+    switch p.Type.Name {
+    case "USER":
+        l.listUser(p)
+    case "GROUP":
+        l.listGroup(p)
+    case "DATASET":
+        l.listDataset(p)
+    case "GENERAL":
+        l.listResource(p)
+    default:
+        return false
+    }
+    l.printf("\n")
+    return true
+}
+
+// List filter [USER:|GROUP:|DATASET:|<CLASS>:]<NAME> with compiled name pattern
+type listFilter struct {
+    kind    string // Profile type or class (empty for any profile)
+    pattern *GenericPattern
+}
+
+// Compile list filters (patterns are compiled once to be matched against all profiles)
+func newListFilters(filters []string) []listFilter {
+    retVal := make([]listFilter, 0, len(filters))
+    for _, f := range filters {
+        var lf listFilter
+        f = strings.TrimSpace(f)
+        if i := strings.Index(f, ":"); i > 0 {
+            lf.kind = strings.ToUpper(f[:i])
+            f = f[i+1:]
+        }
+        lf.pattern = NewGenericPattern(f)
+        retVal = append(retVal, lf)
+    }
+    return retVal
+}
+
+// Check if the profile is selected by one of the compiled filters. Empty filter list selects all profiles
+func listSelected(p *Profile, filters []listFilter) bool {
+    if len(filters) == 0 {
+        return true
+    }
+    name := strings.TrimSpace(p.Name)
+    if p.Type.Name == "GENERAL" {
+        name = p.ResourceName()
+    }
+    for _, f := range filters {
+        if len(f.kind) > 0 && f.kind != p.Type.Name && f.kind != p.Class() {
+            continue
+        }
+        if f.pattern.Match(name) {
+            return true
+        }
+    }
+    return false
+}
+
+// Check if the profile is selected by one of the filters [USER:|GROUP:|DATASET:|<CLASS>:]<NAME>.
+// NAME can be RACF generic pattern. Empty filter list selects all profiles
+func ListSelected(p *Profile, filters []string) bool {
+    return listSelected(p, newListFilters(filters))
+}
+
+// Write RACF list command output (LISTUSER, LISTGRP, LISTDSD and RLIST with all segments) of profiles selected by filters
+func WriteListing(w io.Writer, profiles []*Profile, filters []string) int {
+    l := newLister(w, profiles)
+    compiled := newListFilters(filters)
+    count := 0
+    for _, p := range profiles {
+        if listSelected(p, compiled) && l.list(p) {
+            count++
+        }
+    }
+    return count
+}
+
+// Save RACF list command output of profiles selected by filters (all profiles if there are no filters)
+func ToListing(profiles []*Profile, fileName string, filters []string) {
+    f, err := createOutput(fileName)
+    if err != nil {
+        common.Fatal(fmt.Errorf("Can not create list file: %v", err))
+    }
+    defer f.Close()
+
+    common.Log.Info("Saving RACF list output as file %s", fileName)
+    w := bufio.NewWriter(f)
+    if count := WriteListing(w, profiles, filters); count == 0 {
+        common.Log.Info("No profiles are selected by list filter")
+    }
+    if err := w.Flush(); err != nil {
+        common.Fatal(fmt.Errorf("Can not save list file: %v", err))
+    }
+}
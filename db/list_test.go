@@ -0,0 +1,262 @@
+package db
+
+import (
+    "strings"
+    "testing"
+
+    "racfudit/decode"
+)
+
+// Profiles of each type for list output tests
+func testListProfiles() []*Profile {
+    date := decode.Date{0x24, 0x00, 0x2f}
+
+    group := NewProfile("SYS1", "GROUP", 1)
+    testSegmentOf(group, "BASE", testFields{
+        {"AUTHOR", "IBMUSER"},
+        {"AUTHDATE", date},
+        {"INSTDATA", "SYSTEM GROUP"},
+        {"SUBGRPCT", []testFields{{{"SUBGRPNM", "DEPT"}}}},
+        {"ACLCNT", []testFields{{{"USERID", "IBMUSER"}, {"USERACS", decode.Flag{0x80}}}}},
+    })
+
+    user := NewProfile("IBMUSER", "USER", 2)
+    testSegmentOf(user, "BASE", testFields{
+        {"AUTHOR", "IBMUSER"},
+        {"AUTHDATE", date},
+        {"PGMRNAME", "JOHN SMITH"},
+        {"DFLTGRP", "SYS1"},
+        {"PASSINT", uint8(30)},
+        {"PASSDATE", date},
+        {"FLAG2", decode.Flag{0x80}},
+        {"CGGRPCT", []testFields{{
+            {"CGGRPNM", "SYS1"},
+            {"CGAUTHOR", "IBMUSER"},
+            {"CGAUTHDA", date},
+            {"CGINITCT", uint32(5)},
+            {"CGUACC", decode.Flag{0x10}},
+        }}},
+    })
+    testSegmentOf(user, "WORKATTR", testFields{{"WANAME", "JOHN SMITH"}, {"WADEPT", "IT"}})
+
+    dataset := NewProfile("SYS1.**", "DATASET", 4)
+    testSegmentOf(dataset, "BASE", testFields{
+        {"AUTHOR", "SYS1"},
+        {"CREADATE", date},
+        {"LEVEL", uint8(1)},
+        {"UNIVACS", decode.Flag{0x10}},
+        {"AUDIT", decode.Flag{0x20}},
+        {"AUDITQF", decode.Flag{0x10}},
+        {"GROUPNM", "SYS1"},
+        {"ACSREAD", uint32(3)},
+        {"ACLCNT", []testFields{{{"USERID", "SYSPROG"}, {"USERACS", decode.Flag{0x80}}, {"ACSCNT", uint32(7)}}}},
+        {"ACL2CNT", []testFields{{{"USER2ACS", "JOHN"}, {"PROGRAM", "PAYROLL"}, {"PROGACS", decode.Flag{0x20}}, {"PACSCNT", uint32(2)}}}},
+    })
+
+    resource := NewProfile("FACILITYBPX.SUPERUSER", "GENERAL", 5)
+    testSegmentOf(resource, "BASE", testFields{
+        {"OWNER", "SYS1"},
+        {"DEFDATE", date},
+        {"UACC", decode.Flag{0x00}},
+        {"INSTDATA", "SUPERUSER"},
+        {"ACLCNT", []testFields{{{"USERID", "SYSPROG"}, {"USERACS", decode.Flag{0x10}}, {"ACSCNT", uint32(9)}}}},
+        {"ACL2CNT", []testFields{{{"ACL2UID", "OPER"}, {"ACL2NAME", "CONSOLE"}, {"ACL2VAR", "MASTER"}, {"ACL2ACC", decode.Flag{0x10}}, {"ACL2ACNT", uint32(1)}}}},
+    })
+    return []*Profile{group, user, dataset, resource}
+}
+
+// Expected list output of test profiles (LISTGRP, LISTUSER, LISTDSD and RLIST)
+var testListGolden = map[string]string{
+    "GROUP:SYS1": `INFORMATION FOR GROUP SYS1
+    SUPERIOR GROUP=NONE      OWNER=IBMUSER   CREATED=24.002
+    INSTALLATION DATA=SYSTEM GROUP
+    NO MODEL DATA SET
+    TERMUACC
+    SUBGROUP(S)= DEPT
+    USER(S)=      ACCESS=      ACCESS COUNT=      UNIVERSAL ACCESS=
+      IBMUSER       JOIN          000005              READ
+         CONNECT ATTRIBUTES=NONE
+         REVOKE DATE=NONE                   RESUME DATE=NONE
+
+`,
+    "USER:IBMUSER": `USER=IBMUSER   NAME=JOHN SMITH            OWNER=IBMUSER   CREATED=24.002
+ DEFAULT-GROUP=SYS1     PASSDATE=24.002 PASS-INTERVAL= 30 PHRASEDATE=N/A
+ ATTRIBUTES=SPECIAL
+ REVOKE DATE=NONE   RESUME DATE=NONE
+ LAST-ACCESS=UNKNOWN
+ CLASS AUTHORIZATIONS=NONE
+ INSTALLATION-DATA=NONE
+ MODEL-NAME=NONE
+ LOGON ALLOWED   (DAYS)          (TIME)
+ ---------------------------------------------
+ ANYDAY                          ANYTIME
+  GROUP=SYS1      AUTH=JOIN     CONNECT-OWNER=IBMUSER  CONNECT-DATE=24.002
+    CONNECTS=    5  UACC=READ     LAST-CONNECT=UNKNOWN
+    CONNECT ATTRIBUTES=NONE
+    REVOKE DATE=NONE   RESUME DATE=NONE
+SECURITY-LEVEL=NONE SPECIFIED
+CATEGORY-AUTHORIZATION
+ NONE SPECIFIED
+SECURITY-LABEL=NONE SPECIFIED
+
+WORKATTR INFORMATION
+--------------------
+ WANAME= JOHN SMITH
+ WADEPT= IT
+
+`,
+    "DATASET:SYS1.**": `INFORMATION FOR DATASET SYS1.** (G)
+
+LEVEL  OWNER      UNIVERSAL ACCESS   WARNING
+-----  --------   ----------------   -------
+ 01    SYS1       READ               NO
+
+AUDITING
+--------
+FAILURES(READ)
+
+GLOBALAUDIT
+-----------
+NONE
+
+NOTIFY
+------
+NO USER TO BE NOTIFIED
+
+CREATION GROUP  DATASET TYPE
+--------------  ------------
+ SYS1           NON-VSAM
+
+INSTALLATION DATA
+-----------------
+NONE
+
+SECURITY LEVEL
+NONE SPECIFIED
+
+SECLABEL
+NO SECLABEL
+
+CREATION DATE   LAST REFERENCE DATE   LAST CHANGE DATE
+ (DAY) (YEAR)       (DAY) (YEAR)         (DAY) (YEAR)
+-------------   -------------------   ----------------
+ 002    24       000    00             000    00
+
+ALTER COUNT   CONTROL COUNT   UPDATE COUNT   READ COUNT
+-----------   -------------   ------------   ----------
+ 00000          00000           00000          00003
+
+  ID     ACCESS   ACCESS COUNT
+-------- -------  ------------
+SYSPROG  ALTER       000007
+
+  ID     ACCESS   ACCESS COUNT   CLASS     ENTITY NAME
+-------- -------  ------------   --------  -----------
+JOHN     UPDATE      000002       PROGRAM   PAYROLL
+
+`,
+    "FACILITY:BPX.SUPERUSER": `CLASS      NAME
+-----      ----
+FACILITY   BPX.SUPERUSER
+
+LEVEL  OWNER      UNIVERSAL ACCESS  WARNING
+-----  --------   ----------------  -------
+ 00    SYS1       NONE              NO
+
+INSTALLATION DATA
+-----------------
+SUPERUSER
+
+APPLICATION DATA
+----------------
+NONE
+
+SECLEVEL
+NONE SPECIFIED
+
+SECLABEL
+NO SECLABEL
+
+AUDITING
+--------
+NONE
+
+GLOBALAUDIT
+-----------
+NONE
+
+NOTIFY
+------
+NO USER TO BE NOTIFIED
+
+CREATION DATE   LAST REFERENCE DATE   LAST CHANGE DATE
+ (DAY) (YEAR)       (DAY) (YEAR)         (DAY) (YEAR)
+-------------   -------------------   ----------------
+ 002    24       000    00             000    00
+
+ALTER COUNT   CONTROL COUNT   UPDATE COUNT   READ COUNT
+-----------   -------------   ------------   ----------
+ 00000          00000           00000          00000
+
+  ID     ACCESS   ACCESS COUNT
+-------- -------  ------------
+SYSPROG  READ        000009
+
+  ID     ACCESS   ACCESS COUNT   CLASS     ENTITY NAME
+-------- -------  ------------   --------  -----------
+OPER     READ        000001       CONSOLE   MASTER
+
+`,
+}
+
+func TestWriteListingGolden(t *testing.T) {
+    profiles := testListProfiles()
+    for filter, want := range testListGolden {
+        var sb strings.Builder
+        if count := WriteListing(&sb, profiles, []string{filter}); count != 1 {
+            t.Errorf("%s: %d profiles are listed, want 1", filter, count)
+        }
+        if got := sb.String(); got != want {
+            t.Errorf("%s: output differs from expected one:\n%s\nwant:\n%s", filter, got, want)
+        }
+    }
+}
+
+func TestListSelected(t *testing.T) {
+    profiles := []*Profile{
+        NewProfile("IBMUSER", "USER", 2),
+        NewProfile("IBMADM", "USER", 2),
+        NewProfile("SYS1", "GROUP", 1),
+        NewProfile("SYS1.PARMLIB", "DATASET", 4),
+        NewProfile("SYS1.**", "DATASET", 4),
+        NewProfile("FACILITYBPX.SUPERUSER", "GENERAL", 5),
+        NewProfile("FACILITYBPX.DAEMON", "GENERAL", 5),
+        NewProfile("SURROGATIBMUSER.SUBMIT", "GENERAL", 5),
+    }
+    for _, tc := range []struct {
+        filters []string
+        want    []string
+    }{
+        {nil, []string{"IBMUSER", "IBMADM", "SYS1", "SYS1.PARMLIB", "SYS1.**", "FACILITYBPX.SUPERUSER", "FACILITYBPX.DAEMON", "SURROGATIBMUSER.SUBMIT"}},
+        {[]string{"USER:IBM*"}, []string{"IBMUSER", "IBMADM"}},
+        {[]string{"user:IBMUSER"}, []string{"IBMUSER"}},
+        {[]string{"IBMUSER"}, []string{"IBMUSER"}},
+        {[]string{"SYS1"}, []string{"SYS1"}},
+        {[]string{"DATASET:SYS1.*"}, []string{"SYS1.PARMLIB", "SYS1.**"}},
+        {[]string{"SYS1.**"}, []string{"SYS1.PARMLIB", "SYS1.**"}},
+        {[]string{"FACILITY:BPX.*"}, []string{"FACILITYBPX.SUPERUSER", "FACILITYBPX.DAEMON"}},
+        {[]string{"GENERAL:**"}, []string{"FACILITYBPX.SUPERUSER", "FACILITYBPX.DAEMON", "SURROGATIBMUSER.SUBMIT"}},
+        {[]string{"FACILITY:BPX.SUPERUSE%", " USER:%BMADM "}, []string{"IBMADM", "FACILITYBPX.SUPERUSER"}},
+        {[]string{"SURROGAT:*.SUBMIT", "GROUP:IBM*"}, []string{"SURROGATIBMUSER.SUBMIT"}},
+    } {
+        got := make([]string, 0)
+        for _, p := range profiles {
+            if ListSelected(p, tc.filters) {
+                got = append(got, p.Name)
+            }
+        }
+        if strings.Join(got, ",") != strings.Join(tc.want, ",") {
+            t.Errorf("Profiles selected by %q = %q, want %q", tc.filters, got, tc.want)
+        }
+    }
+}
@@ -0,0 +1,160 @@
+package db
+
+import (
+    "context"
+    "fmt"
+    "os"
+    "sort"
+    "strings"
+
+    "racfudit/common"
+
+    "github.com/neo4j/neo4j-go-driver/v5/neo4j"
+)
+
+// Number of Cypher statements executed in one Bolt transaction
+const NEO4J_BATCH_SIZE = 500
+
+// Edge properties which distinguish edges of the same type between the same nodes
+var graphEdgeKeys = map[string][]string{
+    EDGE_HAS_ACCESS:    {"condition"},
+    EDGE_SURROGATE_FOR: {"resource"},
+}
+
+// Quote string as Cypher string literal
+func cypherString(s string) string {
+    s = strings.ReplaceAll(s, `\`, `\\`)
+    s = strings.ReplaceAll(s, `'`, `\'`)
+    return "'" + s + "'"
+}
+
+// Get Cypher literal of property value
+func cypherValue(v interface{}) string {
+    switch v := v.(type) {
+    case string:
+        return cypherString(v)
+    case bool, int, uint8, uint16, uint32, uint64, int64:
+        return fmt.Sprintf("%v", v)
+    }
+    return cypherString(fmt.Sprintf("%v", v))
+}
+
+// Get Cypher map literal of properties (keys are sorted). Only listed keys are used if keys isn't nil
+func cypherMap(props map[string]interface{}, keys []string) string {
+    if keys == nil {
+        for k := range props {
+            keys = append(keys, k)
+        }
+        sort.Strings(keys)
+    }
+    items := make([]string, 0)
+    for _, k := range keys {
+        if v, ok := props[k]; ok {
+            items = append(items, fmt.Sprintf("%s: %s", k, cypherValue(v)))
+        }
+    }
+    return "{" + strings.Join(items, ", ") + "}"
+}
+
+// Get Cypher statements creating uniqueness constraints of node IDs
+func CypherConstraints() []string {
+    retVal := make([]string, 0)
+    for _, label := range []string{NODE_USER, NODE_GROUP, NODE_DATASET, NODE_RESOURCE} {
+        retVal = append(retVal, fmt.Sprintf("CREATE CONSTRAINT racf_%s_id IF NOT EXISTS FOR (n:%s) REQUIRE n.id IS UNIQUE",
+            strings.ToLower(label), label))
+    }
+    return retVal
+}
+
+// Get Cypher statements creating nodes and edges of the graph. Statements use MERGE so the script can be applied repeatedly
+func CypherStatements(g *Graph) []string {
+    retVal := make([]string, 0)
+    for _, n := range g.Nodes {
+        retVal = append(retVal, fmt.Sprintf("MERGE (n:%s {id: %s}) SET n += %s", n.Label, cypherString(n.ID), cypherMap(n.Properties, nil)))
+    }
+    for _, e := range g.Edges {
+        from, _ := g.Node(e.From)
+        to, _ := g.Node(e.To)
+        q := fmt.Sprintf("MATCH (a:%s {id: %s}), (b:%s {id: %s}) MERGE (a)-[r:%s", from.Label, cypherString(from.ID), to.Label, cypherString(to.ID), e.Type)
+        if keys, ok := graphEdgeKeys[e.Type]; ok {
+            q += " " + cypherMap(e.Properties, keys)
+        }
+        q += "]->(b)"
+        if len(e.Properties) > 0 {
+            q += " SET r += " + cypherMap(e.Properties, nil)
+        }
+        retVal = append(retVal, q)
+    }
+    return retVal
+}
+
+// Save RACF graph as Cypher script (for cypher-shell -f)
+func ToCypher(g *Graph, fileName string) {
+    f, err := createOutput(fileName)
+    if err != nil {
+        common.Fatal(fmt.Errorf("Can not create Cypher script: %v", err))
+    }
+    defer f.Close()
+
+    common.Log.Info("Saving RACF graph (%d nodes, %d edges) as Cypher script %s", len(g.Nodes), len(g.Edges), fileName)
+    fmt.Fprintf(f, "// RACF graph: %d nodes, %d edges\n", len(g.Nodes), len(g.Edges))
+    for _, q := range CypherConstraints() {
+        fmt.Fprintf(f, "%s;\n", q)
+    }
+    for _, q := range CypherStatements(g) {
+        fmt.Fprintf(f, "%s;\n", q)
+    }
+}
+
+// Push RACF graph to Neo4j over Bolt. Password is taken from NEO4J_PASSWORD environment variable
+// (it isn't accepted as command line option to keep it out of the process list)
+func ToNeo4j(g *Graph, uri string, user string) {
+    password := os.Getenv("NEO4J_PASSWORD")
+    ctx := context.Background()
+    driver, err := neo4j.NewDriverWithContext(uri, neo4j.BasicAuth(user, password, ""))
+    if err != nil {
+        common.Fatal(fmt.Errorf("Can not create Neo4j driver: %v", err))
+    }
+    defer driver.Close(ctx)
+    if err := driver.VerifyConnectivity(ctx); err != nil {
+        common.Fatal(fmt.Errorf("Can not connect to Neo4j %s: %v", uri, err))
+    }
+
+    session := driver.NewSession(ctx, neo4j.SessionConfig{AccessMode: neo4j.AccessModeWrite})
+    defer session.Close(ctx)
+
+    common.Log.Info("Pushing RACF graph (%d nodes, %d edges) to Neo4j %s", len(g.Nodes), len(g.Edges), uri)
+
+    // Schema statements can't be mixed with data statements in one transaction
+    batches := make([][]string, 0)
+    for _, q := range CypherConstraints() {
+        batches = append(batches, []string{q})
+    }
+    statements := CypherStatements(g)
+    for i := 0; i < len(statements); i += NEO4J_BATCH_SIZE {
+        end := i + NEO4J_BATCH_SIZE
+        if end > len(statements) {
+            end = len(statements)
+        }
+        batches = append(batches, statements[i:end])
+    }
+
+    for _, batch := range batches {
+        _, err := session.ExecuteWrite(ctx, func(tx neo4j.ManagedTransaction) (any, error) {
+            for _, q := range batch {
+                common.Log.Debug("Executing Cypher query: %s", q)
+                r, err := tx.Run(ctx, q, nil)
+                if err != nil {
+                    return nil, fmt.Errorf("Can not execute Cypher query %q: %v", q, err)
+                }
+                if _, err := r.Consume(ctx); err != nil {
+                    return nil, fmt.Errorf("Can not execute Cypher query %q: %v", q, err)
+                }
+            }
+            return nil, nil
+        })
+        if err != nil {
+            common.Fatal(fmt.Errorf("Can not push RACF graph to Neo4j: %v", err))
+        }
+    }
+}
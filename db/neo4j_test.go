@@ -0,0 +1,92 @@
+package db
+
+import (
+    "reflect"
+    "testing"
+)
+
+// Create graph with all node and edge types
+func testGraph() *Graph {
+    g := &Graph{Nodes: make([]*GraphNode, 0), Edges: make([]*GraphEdge, 0), nodes: make(map[string]*GraphNode)}
+    g.addNode(NODE_USER, "IBMUSER", map[string]interface{}{"special": true, "uid": uint64(0)})
+    g.addNode(NODE_USER, `O'NEIL\A`, map[string]interface{}{"special": false})
+    g.addNode(NODE_GROUP, "SYS1", map[string]interface{}{"universal": false})
+    g.addNode(NODE_GROUP, "DEPT", map[string]interface{}{})
+    g.addNode(NODE_DATASET, "SYS1.**", map[string]interface{}{"uacc": "NONE", "generic": true})
+    g.addNode(NODE_RESOURCE, "BPX.SUPERUSER", map[string]interface{}{"class": "FACILITY", "uacc": "NONE"})
+
+    g.addEdge(EDGE_MEMBER_OF, "User:IBMUSER", "Group:SYS1", map[string]interface{}{"authority": "JOIN"})
+    g.addEdge(EDGE_OWNS, "Group:SYS1", "Dataset:SYS1.**", map[string]interface{}{})
+    g.addEdge(EDGE_HAS_ACCESS, `User:O'NEIL\A`, "Resource:FACILITY:BPX.SUPERUSER", map[string]interface{}{"access": "READ", "condition": ""})
+    g.addEdge(EDGE_HAS_ACCESS, `User:O'NEIL\A`, "Resource:FACILITY:BPX.SUPERUSER", map[string]interface{}{"access": "UPDATE", "condition": "WHEN(PROGRAM(X))"})
+    g.addEdge(EDGE_SUPGROUP_OF, "Group:SYS1", "Group:DEPT", map[string]interface{}{})
+    g.addEdge(EDGE_SURROGATE_FOR, "Group:DEPT", "User:IBMUSER", map[string]interface{}{"access": "READ", "resource": "IBMUSER.SUBMIT"})
+    return g
+}
+
+func TestCypherString(t *testing.T) {
+    for _, tc := range []struct {
+        in   string
+        want string
+    }{
+        {"SYS1", `'SYS1'`},
+        {"O'NEIL", `'O\'NEIL'`},
+        {`A\B`, `'A\\B'`},
+        {`\'`, `'\\\''`},
+        {"", `''`},
+    } {
+        if got := cypherString(tc.in); got != tc.want {
+            t.Errorf("cypherString(%q) = %s, want %s", tc.in, got, tc.want)
+        }
+    }
+}
+
+func TestCypherStatements(t *testing.T) {
+    want := []string{
+        `MERGE (n:User {id: 'User:IBMUSER'}) SET n += {name: 'IBMUSER', special: true, uid: 0}`,
+        `MERGE (n:User {id: 'User:O\'NEIL\\A'}) SET n += {name: 'O\'NEIL\\A', special: false}`,
+        `MERGE (n:Group {id: 'Group:SYS1'}) SET n += {name: 'SYS1', universal: false}`,
+        `MERGE (n:Group {id: 'Group:DEPT'}) SET n += {name: 'DEPT'}`,
+        `MERGE (n:Dataset {id: 'Dataset:SYS1.**'}) SET n += {generic: true, name: 'SYS1.**', uacc: 'NONE'}`,
+        `MERGE (n:Resource {id: 'Resource:FACILITY:BPX.SUPERUSER'}) SET n += {class: 'FACILITY', name: 'BPX.SUPERUSER', uacc: 'NONE'}`,
+        `MATCH (a:User {id: 'User:IBMUSER'}), (b:Group {id: 'Group:SYS1'}) MERGE (a)-[r:MEMBER_OF]->(b) SET r += {authority: 'JOIN'}`,
+        `MATCH (a:Group {id: 'Group:SYS1'}), (b:Dataset {id: 'Dataset:SYS1.**'}) MERGE (a)-[r:OWNS]->(b)`,
+        `MATCH (a:User {id: 'User:O\'NEIL\\A'}), (b:Resource {id: 'Resource:FACILITY:BPX.SUPERUSER'}) ` +
+            `MERGE (a)-[r:HAS_ACCESS {condition: ''}]->(b) SET r += {access: 'READ', condition: ''}`,
+        `MATCH (a:User {id: 'User:O\'NEIL\\A'}), (b:Resource {id: 'Resource:FACILITY:BPX.SUPERUSER'}) ` +
+            `MERGE (a)-[r:HAS_ACCESS {condition: 'WHEN(PROGRAM(X))'}]->(b) SET r += {access: 'UPDATE', condition: 'WHEN(PROGRAM(X))'}`,
+        `MATCH (a:Group {id: 'Group:SYS1'}), (b:Group {id: 'Group:DEPT'}) MERGE (a)-[r:SUPGROUP_OF]->(b)`,
+        `MATCH (a:Group {id: 'Group:DEPT'}), (b:User {id: 'User:IBMUSER'}) ` +
+            `MERGE (a)-[r:SURROGATE_FOR {resource: 'IBMUSER.SUBMIT'}]->(b) SET r += {access: 'READ', resource: 'IBMUSER.SUBMIT'}`,
+    }
+    got := CypherStatements(testGraph())
+    if len(got) != len(want) {
+        t.Fatalf("Got %d statements, want %d:\n%v", len(got), len(want), got)
+    }
+    for i := range want {
+        if got[i] != want[i] {
+            t.Errorf("Statement %d:\n got: %s\nwant: %s", i, got[i], want[i])
+        }
+    }
+}
+
+func TestCypherStatementsDeterministic(t *testing.T) {
+    first := CypherStatements(testGraph())
+    for i := 0; i < 20; i++ {
+        if got := CypherStatements(testGraph()); !reflect.DeepEqual(got, first) {
+            t.Fatalf("Statements differ between runs:\n%v\n%v", first, got)
+        }
+    }
+}
+
+func TestCypherConstraints(t *testing.T) {
+    want := []string{
+        "CREATE CONSTRAINT racf_user_id IF NOT EXISTS FOR (n:User) REQUIRE n.id IS UNIQUE",
+        "CREATE CONSTRAINT racf_group_id IF NOT EXISTS FOR (n:Group) REQUIRE n.id IS UNIQUE",
+        "CREATE CONSTRAINT racf_dataset_id IF NOT EXISTS FOR (n:Dataset) REQUIRE n.id IS UNIQUE",
+        "CREATE CONSTRAINT racf_resource_id IF NOT EXISTS FOR (n:Resource) REQUIRE n.id IS UNIQUE",
+    }
+    if got := CypherConstraints(); !reflect.DeepEqual(got, want) {
+        t.Errorf("CypherConstraints() = %v, want %v", got, want)
+    }
+}
@@ -0,0 +1,252 @@
+package db
+
+import (
+    "bufio"
+    "fmt"
+    "io"
+    "math/big"
+    "reflect"
+    "strings"
+
+    "racfudit/common"
+    "racfudit/decode"
+)
+
+// PostgreSQL column types of SQLite column types (see GetDBFieldType)
+var pgColumnTypes = map[string]string{
+    "INTEGER": "bigint",
+    "DATE":    "date",
+    "BLOB":    "bytea",
+    "TEXT":    "text",
+}
+
+// Get PostgreSQL type of the column. Unsigned 64-bit integers and flag bitmasks may not fit into bigint and are numeric
+func pgColumnType(c sqliteColumn) string {
+    if c.Field != nil && !c.Decoded && !c.Raw && (c.Field.Type == reflect.TypeOf(uint64(0)) || c.Field.Type == reflect.TypeOf(decode.Flag{})) {
+        return "numeric"
+    }
+    return pgColumnTypes[c.Type]
+}
+
+// Get values of the table row for COPY (see rowArgs). Values of numeric columns which don't fit into
+// SQLite integer (NULL) are converted to decimal numbers from their <FIELD>_Raw column
+func pgRowArgs(t *Table, row TableRow, keyValues ...interface{}) []interface{} {
+    _, args := rowArgs(t, row, keyValues...)
+    numeric := -1
+    for i, c := range sqliteColumns(t) {
+        switch {
+        case pgColumnType(c) == "numeric":
+            numeric = i
+        case c.Raw && numeric >= 0 && args[numeric] == nil:
+            if b, ok := args[i].([]byte); ok {
+                args[numeric] = new(big.Int).SetBytes(b).String()
+            }
+        }
+    }
+    return args
+}
+
+// Quote PostgreSQL identifier (field names may contain characters like '#', '@' and '$')
+func pgIdent(name string) string {
+    return `"` + strings.ReplaceAll(name, `"`, `""`) + `"`
+}
+
+// Quote PostgreSQL string literal
+func pgLiteral(s string) string {
+    return "'" + strings.ReplaceAll(s, "'", "''") + "'"
+}
+
+// Format value for COPY text format (NULL is \N, binary data is written as bytea hex).
+// PostgreSQL text can't contain NUL characters, they are removed with a warning
+func pgCopyValue(v interface{}) string {
+    switch val := v.(type) {
+    case nil:
+        return `\N`
+    case int64:
+        return fmt.Sprintf("%d", val)
+    case []byte:
+        return fmt.Sprintf(`\\x%x`, val)
+    case string:
+        if strings.ContainsRune(val, 0) {
+            common.Log.Warning("NUL characters are removed from value %q in PostgreSQL dump", val)
+        }
+        return strings.NewReplacer("\x00", "", `\`, `\\`, "\t", `\t`, "\n", `\n`, "\r", `\r`).Replace(val)
+    }
+    return pgCopyValue(fmt.Sprint(v))
+}
+
+// Writer of PostgreSQL dump of runtime DB
+type pgWriter struct {
+    w        io.Writer
+    profiles []*Profile
+}
+
+func (d *pgWriter) printf(format string, args ...interface{}) {
+    fmt.Fprintf(d.w, format, args...)
+}
+
+// Write CREATE TABLE statement of segment or RepeatGroup table. Indexes are created after the data is loaded
+func (d *pgWriter) createTable(t *Table) {
+    fields := []string{`"id" bigint GENERATED BY DEFAULT AS IDENTITY PRIMARY KEY`}
+    for _, c := range sqliteColumns(t) {
+        switch {
+        case c.Name == "profile_id":
+            fields = append(fields, fmt.Sprintf(`"profile_id" bigint NOT NULL REFERENCES %s("id") ON DELETE CASCADE`, pgIdent(t.Parent)))
+        case c.Name == "ItemIndex":
+            fields = append(fields, `"ItemIndex" bigint NOT NULL`)
+        default:
+            fields = append(fields, fmt.Sprintf("%s %s", pgIdent(c.Name), pgColumnType(c)))
+        }
+    }
+    d.printf("CREATE TABLE %s (\n    %s\n);\n\n", pgIdent(t.Name), strings.Join(fields, ",\n    "))
+}
+
+// Write rows of segment table and its RepeatGroup tables as COPY blocks. Row ids are assigned in the order
+// of profiles, so RepeatGroup items reference the segment rows by the same ids
+func (d *pgWriter) copyTable(t *Table) {
+    columns := []string{pgIdent("id")}
+    for _, c := range sqliteColumns(t) {
+        columns = append(columns, pgIdent(c.Name))
+    }
+    d.printf("COPY %s (%s) FROM stdin;\n", pgIdent(t.Name), strings.Join(columns, ", "))
+    id := int64(0)
+    for _, p := range d.profiles {
+        for i := range p.Segments {
+            s := &p.Segments[i]
+            for _, row := range t.SegmentRows(p, s) {
+                id++
+                args := pgRowArgs(t, row, p.Name, s.Address.String(), s.Raw)
+                d.copyRow(append([]interface{}{id}, args...))
+            }
+        }
+    }
+    d.printf("\\.\n\n")
+    d.setSequence(t, id)
+
+    for _, child := range t.Children {
+        columns := []string{pgIdent("id")}
+        for _, c := range sqliteColumns(child) {
+            columns = append(columns, pgIdent(c.Name))
+        }
+        d.printf("COPY %s (%s) FROM stdin;\n", pgIdent(child.Name), strings.Join(columns, ", "))
+        parentID, childID := int64(0), int64(0)
+        for _, p := range d.profiles {
+            for i := range p.Segments {
+                s := &p.Segments[i]
+                if len(t.SegmentRows(p, s)) == 0 {
+                    continue
+                }
+                parentID++
+                for _, item := range child.SegmentRows(p, s) {
+                    childID++
+                    args := pgRowArgs(child, item, parentID, int64(item.Index))
+                    d.copyRow(append([]interface{}{childID}, args...))
+                }
+            }
+        }
+        d.printf("\\.\n\n")
+        d.setSequence(child, childID)
+    }
+}
+
+func (d *pgWriter) copyRow(args []interface{}) {
+    values := make([]string, len(args))
+    for i, v := range args {
+        values[i] = pgCopyValue(v)
+    }
+    d.printf("%s\n", strings.Join(values, "\t"))
+}
+
+// Move identity sequence of the table after the loaded ids
+func (d *pgWriter) setSequence(t *Table, maxID int64) {
+    if maxID > 0 {
+        d.printf("SELECT setval(pg_get_serial_sequence(%s, 'id'), %d);\n\n", pgLiteral(pgIdent(t.Name)), maxID)
+    }
+}
+
+// Write indexes on ProfileName of segment tables and on (profile_id, ItemIndex) of RepeatGroup tables
+func (d *pgWriter) createIndexes(t *Table) {
+    if len(t.RepeatGroup) == 0 {
+        d.printf("CREATE INDEX %s ON %s (\"ProfileName\");\n", pgIdent(t.Name+"_ProfileName"), pgIdent(t.Name))
+    } else {
+        d.printf("CREATE UNIQUE INDEX %s ON %s (\"profile_id\", \"ItemIndex\");\n", pgIdent(t.Name+"_profile_id"), pgIdent(t.Name))
+    }
+}
+
+// Write SCHEMA_COLUMNS table with column types and template metadata of the fields (see DBSQLite.writeSchema)
+func (d *pgWriter) schemaColumns(tables []*Table) {
+    d.printf("CREATE TABLE \"SCHEMA_COLUMNS\" (\n    \"id\" bigint GENERATED BY DEFAULT AS IDENTITY PRIMARY KEY,\n")
+    d.printf("    \"TableName\" text, \"ColumnName\" text, \"ColumnType\" text, \"Kind\" text, \"GoType\" text,\n")
+    d.printf("    \"ProfileType\" text, \"Segment\" text, \"RepeatGroup\" text, \"FieldName\" text, \"FieldID\" bigint,\n")
+    d.printf("    \"Flag1\" bigint, \"Flag2\" bigint, \"Length\" bigint, \"Note\" text\n);\n\n")
+    d.printf("COPY \"SCHEMA_COLUMNS\" (\"TableName\", \"ColumnName\", \"ColumnType\", \"Kind\", \"GoType\", \"ProfileType\", " +
+        "\"Segment\", \"RepeatGroup\", \"FieldName\", \"FieldID\", \"Flag1\", \"Flag2\", \"Length\", \"Note\") FROM stdin;\n")
+    for _, t := range tables {
+        for _, c := range sqliteColumns(t) {
+            args := []interface{}{t.Name, c.Name, pgColumnType(c), nil, nil, t.ProfileType, t.Segment, t.RepeatGroup, nil, nil, nil, nil, nil, nil}
+            if c.Field != nil {
+                meta := c.Field.Meta()
+                args[3], args[4], args[8] = c.Field.Kind(), c.Field.Type.String(), c.Field.Name
+                for i, key := range []string{"id", "flag1", "flag2", "len"} {
+                    if v, ok := meta[key]; ok {
+                        args[9+i] = v
+                    }
+                }
+                if c.Decoded {
+                    args[4] = "string"
+                } else if c.Raw {
+                    args[4] = "[]byte"
+                }
+            }
+            // Numeric columns hold all values in PostgreSQL
+            if len(c.Note) > 0 && pgColumnType(c) != "numeric" {
+                args[13] = c.Note
+            }
+            d.copyRow(args)
+        }
+    }
+    d.printf("\\.\n\n")
+}
+
+// Write PostgreSQL dump of runtime DB with the same tables as SQLite3 DB (see DBSQLite.Init): CREATE TABLE statements,
+// COPY blocks with rows and indexes. Views and tables of bind credentials, custom fields and TVTOC aren't included
+func WritePostgres(w io.Writer, profiles []*Profile, profileStructs map[string]map[string]reflect.Type) {
+    d := &pgWriter{w: w, profiles: profiles}
+    tables := NewTables(profileStructs)
+    d.printf("-- RACF DB dump created by racfudit\n\n")
+    d.printf("SET client_encoding = 'UTF8';\nSET standard_conforming_strings = on;\n\nBEGIN;\n\n")
+    d.schemaColumns(tables)
+
+    // Segment tables must be created and filled before their RepeatGroup tables
+    for _, t := range tables {
+        if len(t.RepeatGroup) > 0 {
+            continue
+        }
+        common.Log.Debug("Saving table %s", t.Name)
+        d.createTable(t)
+        for _, child := range t.Children {
+            d.createTable(child)
+        }
+        d.copyTable(t)
+    }
+    for _, t := range tables {
+        d.createIndexes(t)
+    }
+    d.printf("\nCOMMIT;\n")
+}
+
+// Save runtime DB as PostgreSQL dump (SQL script for psql)
+func ToPostgres(profiles []*Profile, fileName string, profileStructs map[string]map[string]reflect.Type) {
+    f, err := createOutput(fileName)
+    if err != nil {
+        common.Fatal(fmt.Errorf("Can not create PostgreSQL dump file: %v", err))
+    }
+    defer f.Close()
+
+    common.Log.Info("Saving RACF profiles as PostgreSQL dump file %s", fileName)
+    w := bufio.NewWriter(f)
+    WritePostgres(w, profiles, profileStructs)
+    if err := w.Flush(); err != nil {
+        common.Fatal(fmt.Errorf("Can not save PostgreSQL dump file: %v", err))
+    }
+}
@@ -0,0 +1,55 @@
+package db
+
+import (
+    "math"
+    "reflect"
+    "strings"
+    "testing"
+
+    "racfudit/decode"
+)
+
+func TestWritePostgresNumericColumns(t *testing.T) {
+    baseT := reflect.StructOf([]reflect.StructField{
+        {Name: "UID", Type: reflect.TypeOf(uint64(0))},
+        {Name: "FLAGS", Type: reflect.TypeOf(decode.Flag{})},
+        {Name: "LEN", Type: reflect.TypeOf(uint32(0))},
+    })
+    p := NewProfile("IBMUSER", "USER", 2)
+    v := reflect.New(baseT)
+    v.Elem().Field(0).SetUint(math.MaxUint64)
+    v.Elem().Field(1).Set(reflect.ValueOf(decode.Flag{1, 0, 0, 0, 0, 0, 0, 0, 1}))
+    v.Elem().Field(2).SetUint(7)
+    p.Segments = append(p.Segments, *NewSegment("BASE", 1, 0, 0, 0, "", &v))
+
+    var sb strings.Builder
+    WritePostgres(&sb, []*Profile{p}, map[string]map[string]reflect.Type{"USER": {"BASE": baseT}})
+    dump := sb.String()
+    for _, want := range []string{
+        `"UID" numeric`, `"UID_Raw" bytea`, `"FLAGS" numeric`, `"FLAGS_Bits" text`, `"FLAGS_Raw" bytea`, `"LEN" bigint`,
+        "\t18446744073709551615\t\\\\xffffffffffffffff\t18446744073709551617\t" + strings.Repeat("0", 7) + "1" + strings.Repeat("0", 63) + "1" +
+            "\t\\\\x010000000000000001\t7\n",
+        "USER_BASE\tUID\tnumeric\t",
+    } {
+        if !strings.Contains(dump, want) {
+            t.Errorf("Dump doesn't contain %q:\n%s", want, dump)
+        }
+    }
+}
+
+func TestPgCopyValue(t *testing.T) {
+    for _, tc := range []struct {
+        in   interface{}
+        want string
+    }{
+        {nil, `\N`},
+        {int64(-1), "-1"},
+        {[]byte{0, 0xff}, `\\x00ff`},
+        {"a\tb\nc\\d", `a\tb\nc\\d`},
+        {"NUL\x00inside", "NULinside"},
+    } {
+        if got := pgCopyValue(tc.in); got != tc.want {
+            t.Errorf("pgCopyValue(%q) = %q, want %q", tc.in, got, tc.want)
+        }
+    }
+}
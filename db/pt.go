@@ -11,7 +11,7 @@ import (
 func ToPlainText(profiles []*Profile, fileName string) {
     f, err := os.Create(fileName)
     if err != nil {
-        common.Fatal("Can not create dump file: %v", err)
+        common.Fatal(fmt.Errorf("Can not create dump file: %v", err))
     }
 
     common.Log.Info("Saving RACF profiles as plain text file %s", fileName)
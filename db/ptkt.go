@@ -0,0 +1,192 @@
+package db
+
+import (
+    "bytes"
+    "fmt"
+    "sort"
+    "strings"
+)
+
+// PassTicket key protection methods (SSIGNON segment of PTKTDATA profiles)
+const (
+    PTKT_NONE      = "NONE"      // No key defined
+    PTKT_MASKED    = "MASKED"    // KEYMASKED: key is masked and can be recovered from RACF DB
+    PTKT_ENCRYPTED = "ENCRYPTED" // KEYENCRYPTED: key is encrypted under ICSF master key
+    PTKT_ICSF      = "ICSF"      // KEYLABEL: key is held in ICSF CKDS under the label
+)
+
+type PassTicket struct {
+    Profile     string
+    Appl        string
+    Protection  string
+    Key         string // Masked or encrypted key in hex
+    KeyLabel    string
+    Type        string
+    Timeout     uint64
+    Replay      bool
+    ApplDefined bool     // APPL class profile for the application exists
+    ApplUACC    string   // UACC of APPL class profile
+    Generators  []string // Users and groups allowed to generate PassTickets (IRRPTAUTH profiles)
+}
+
+func (pt *PassTicket) String() string {
+    retVal := fmt.Sprintf("%s (PTKTDATA profile %s)\n", pt.Appl, pt.Profile)
+    retVal += fmt.Sprintf("\tKey protection: %s", pt.Protection)
+    switch pt.Protection {
+    case PTKT_ICSF:
+        retVal += fmt.Sprintf(" (CKDS label %s)\n", pt.KeyLabel)
+    case PTKT_MASKED, PTKT_ENCRYPTED:
+        retVal += fmt.Sprintf(" (key %s)\n", pt.Key)
+    default:
+        retVal += "\n"
+    }
+    retVal += fmt.Sprintf("\tType: %s; Timeout: %d; Replay allowed: %v\n", pt.Type, pt.Timeout, pt.Replay)
+    if pt.ApplDefined {
+        retVal += fmt.Sprintf("\tAPPL profile: defined (UACC %s)\n", pt.ApplUACC)
+    } else {
+        retVal += "\tAPPL profile: not defined\n"
+    }
+    retVal += fmt.Sprintf("\tPassTicket generators: %s\n", strings.Join(pt.Generators, ", "))
+    return retVal
+}
+
+// Get key protection method and key from SSIGNON segment. A key longer than 8 bytes
+// is prefixed with a flag byte where X'80' means the key is encrypted. All-zero key is not defined
+func ptktKeyProtection(s *Segment) (string, []byte) {
+    if len(s.FieldString("PTKEYLAB")) > 0 {
+        return PTKT_ICSF, nil
+    }
+    key := s.FieldBytes("SSKEY")
+    switch {
+    case len(bytes.Trim(key, "\x00")) == 0:
+        return PTKT_NONE, nil
+    case len(key) > 8 && len(bytes.Trim(key[1:], "\x00")) == 0:
+        return PTKT_NONE, nil
+    case len(key) > 8 && key[0]&0x80 != 0:
+        return PTKT_ENCRYPTED, key[1:]
+    case len(key) > 8:
+        return PTKT_MASKED, key[1:]
+    }
+    return PTKT_MASKED, key
+}
+
+// IRRPTAUTH.appl.userid profiles grouped by their IRRPTAUTH.appl prefix
+type ptktAuthProfiles struct {
+    prefix   *GenericPattern
+    profiles []*Profile
+}
+
+// Get IRRPTAUTH profiles of the application: the profiles with discrete prefix are looked up by name,
+// the profiles with generic prefix (e.g. IRRPTAUTH.CICS*) are matched by pattern
+func ptktAuthOf(appl string, byPrefix map[string]*ptktAuthProfiles, generic []*ptktAuthProfiles) []*Profile {
+    retVal := make([]*Profile, 0)
+    if auth, ok := byPrefix["IRRPTAUTH."+appl]; ok && !auth.prefix.generic {
+        retVal = append(retVal, auth.profiles...)
+    }
+    for _, auth := range generic {
+        if auth.prefix.Match("IRRPTAUTH." + appl) {
+            retVal = append(retVal, auth.profiles...)
+        }
+    }
+    return retVal
+}
+
+// Extract PassTicket definitions from PTKTDATA profiles and link them with APPL profiles
+// and with users allowed to generate PassTickets (IRRPTAUTH.appl.userid profiles)
+func ExtractPassTickets(profiles []*Profile) []*PassTicket {
+    appls := newResourceProfiles()
+    authByPrefix := make(map[string]*ptktAuthProfiles)
+    authGeneric := make([]*ptktAuthProfiles, 0)
+    for _, o := range profiles {
+        switch o.Class() {
+        case "APPL":
+            appls.add(o)
+        case "PTKTDATA":
+            name := o.ResourceName()
+            if !strings.HasPrefix(name, "IRRPTAUTH.") {
+                continue
+            }
+            prefix := strings.Join(strings.Split(name, ".")[:2], ".")
+            if _, ok := authByPrefix[prefix]; !ok {
+                authByPrefix[prefix] = &ptktAuthProfiles{prefix: NewGenericPattern(prefix)}
+                if authByPrefix[prefix].prefix.generic {
+                    authGeneric = append(authGeneric, authByPrefix[prefix])
+                }
+            }
+            authByPrefix[prefix].profiles = append(authByPrefix[prefix].profiles, o)
+        }
+    }
+
+    retVal := make([]*PassTicket, 0)
+    for _, p := range profiles {
+        if p.Class() != "PTKTDATA" {
+            continue
+        }
+        s, ok := p.Segment("SSIGNON")
+        if !ok {
+            continue
+        }
+
+        pt := &PassTicket{
+            Profile:  p.ResourceName(),
+            Appl:     strings.Split(p.ResourceName(), ".")[0],
+            KeyLabel: s.FieldString("PTKEYLAB"),
+            Type:     s.FieldString("PTTYPE"),
+            Timeout:  s.FieldUint("PTTIMEO"),
+        }
+        var key []byte
+        pt.Protection, key = ptktKeyProtection(s)
+        pt.Key = fmt.Sprintf("%x", key)
+        if b := s.FieldBytes("PTREPLAY"); len(b) > 0 && b[0] != 0 {
+            pt.Replay = true
+        }
+        if len(pt.Type) == 0 {
+            pt.Type = "DES"
+        }
+
+        if o := appls.BestMatch(pt.Appl); o != nil {
+            pt.ApplDefined = true
+            pt.ApplUACC = o.UACC()
+        }
+
+        generators := make(map[string]bool)
+        for _, o := range ptktAuthOf(pt.Appl, authByPrefix, authGeneric) {
+            name := o.ResourceName()
+            if AccessLevel(o.UACC()) >= AccessLevel("UPDATE") {
+                generators[fmt.Sprintf("* (UACC of %s)", name)] = true
+            }
+            for _, e := range o.ACL() {
+                if AccessLevel(e.Access) >= AccessLevel("UPDATE") {
+                    generators[fmt.Sprintf("%s (%s)", e.ID, name)] = true
+                }
+            }
+        }
+        for g := range generators {
+            pt.Generators = append(pt.Generators, g)
+        }
+        sort.Strings(pt.Generators)
+        retVal = append(retVal, pt)
+    }
+    return retVal
+}
+
+// Get plain text report about PassTicket keys
+func ptktReport(profiles []*Profile) string {
+    passTickets := ExtractPassTickets(profiles)
+    masked := make([]string, 0)
+    for _, pt := range passTickets {
+        if pt.Protection == PTKT_MASKED {
+            masked = append(masked, fmt.Sprintf("\t%s (PTKTDATA profile %s)\n", pt.Appl, pt.Profile))
+        }
+    }
+    sort.Strings(masked)
+
+    retVal := fmt.Sprintf("PassTicket profiles: %d\n", len(passTickets))
+    retVal += fmt.Sprintf("Applications with masked PassTicket keys (logons can be forged with RACF DB copy): %d\n%s",
+        len(masked), strings.Join(masked, ""))
+    retVal += "PassTicket details:\n"
+    for _, pt := range passTickets {
+        retVal += pt.String()
+    }
+    return retVal
+}
@@ -0,0 +1,79 @@
+package db
+
+import (
+    "bytes"
+    "fmt"
+    "reflect"
+    "testing"
+
+    "racfudit/decode"
+)
+
+// Create PTKTDATA profile with SSIGNON segment
+func testPTKTProfile(name string, key []byte) *Profile {
+    sT := reflect.StructOf([]reflect.StructField{
+        {Name: "SSKEY", Type: reflect.TypeOf(decode.HexStr{})},
+        {Name: "PTKEYLAB", Type: reflect.TypeOf(decode.EBCDICStr{})},
+    })
+    p := NewProfile(fmt.Sprintf("%-8s%s", "PTKTDATA", name), "GENERAL", 5)
+    v := reflect.New(sT)
+    v.Elem().Field(0).Set(reflect.ValueOf(decode.HexStr(key)))
+    p.Segments = append(p.Segments, *NewSegment("SSIGNON", 2, 0, 0, 0, "", &v))
+    return p
+}
+
+func TestPtktKeyProtection(t *testing.T) {
+    for _, tc := range []struct {
+        key        []byte
+        protection string
+        want       []byte
+    }{
+        {nil, PTKT_NONE, nil},
+        {make([]byte, 8), PTKT_NONE, nil},
+        {make([]byte, 9), PTKT_NONE, nil},
+        {append([]byte{0x80}, make([]byte, 8)...), PTKT_NONE, nil},
+        {[]byte{1, 2, 3, 4, 5, 6, 7, 8}, PTKT_MASKED, []byte{1, 2, 3, 4, 5, 6, 7, 8}},
+        {[]byte{0, 1, 2, 3, 4, 5, 6, 7, 8}, PTKT_MASKED, []byte{1, 2, 3, 4, 5, 6, 7, 8}},
+        {[]byte{0x80, 1, 2, 3, 4, 5, 6, 7, 8}, PTKT_ENCRYPTED, []byte{1, 2, 3, 4, 5, 6, 7, 8}},
+    } {
+        s, _ := testPTKTProfile("CICSPROD", tc.key).Segment("SSIGNON")
+        protection, key := ptktKeyProtection(s)
+        if protection != tc.protection || !bytes.Equal(key, tc.want) {
+            t.Errorf("ptktKeyProtection(%x) = %s, %x; want %s, %x", tc.key, protection, key, tc.protection, tc.want)
+        }
+    }
+}
+
+// Create general resource profile with UACC in BASE segment
+func testUACCProfile(class string, name string, uacc byte) *Profile {
+    p := NewProfile(fmt.Sprintf("%-8s%s", class, name), "GENERAL", 5)
+    sT := reflect.StructOf([]reflect.StructField{{Name: "UACC", Type: reflect.TypeOf(decode.Flag{})}})
+    v := reflect.New(sT)
+    v.Elem().Field(0).Set(reflect.ValueOf(decode.Flag{uacc}))
+    p.Segments = append(p.Segments, *NewSegment("BASE", 1, 0, 0, 0, "", &v))
+    return p
+}
+
+func TestExtractPassTicketsGenerators(t *testing.T) {
+    profiles := []*Profile{
+        testPTKTProfile("CICSPROD", []byte{1, 2, 3, 4, 5, 6, 7, 8}),
+        testPTKTProfile("TSO", nil),
+        testUACCProfile("PTKTDATA", "IRRPTAUTH.CICSPROD.*", 0x20),
+        testUACCProfile("PTKTDATA", "IRRPTAUTH.CICS*.*", 0x80),
+        testUACCProfile("PTKTDATA", "IRRPTAUTH.TSO.*", 0x10),
+        testUACCProfile("APPL", "CICS*", 0x10),
+        testUACCProfile("APPL", "CICSPROD", 0x00),
+    }
+    got := make(map[string]*PassTicket)
+    for _, pt := range ExtractPassTickets(profiles) {
+        got[pt.Appl] = pt
+    }
+    if pt := got["CICSPROD"]; pt == nil || !pt.ApplDefined || pt.ApplUACC != "NONE" || pt.Protection != PTKT_MASKED ||
+        !reflect.DeepEqual(pt.Generators, []string{"* (UACC of IRRPTAUTH.CICS*.*)", "* (UACC of IRRPTAUTH.CICSPROD.*)"}) {
+        t.Errorf("CICSPROD PassTicket = %+v", pt)
+    }
+    // READ access doesn't allow to generate PassTickets
+    if pt := got["TSO"]; pt == nil || pt.ApplDefined || pt.Protection != PTKT_NONE || len(pt.Generators) != 0 {
+        t.Errorf("TSO PassTicket = %+v", pt)
+    }
+}
@@ -0,0 +1,34 @@
+package db
+
+import (
+    "fmt"
+    "os"
+
+    "racfudit/common"
+    "racfudit/sections"
+)
+
+// Save security analysis of runtime DB as plain text report
+func ToReport(profiles []*Profile, fileName string, icb *sections.ICB) {
+    f, err := os.Create(fileName)
+    if err != nil {
+        common.Fatal(fmt.Errorf("Can not create report file: %v", err))
+    }
+    defer f.Close()
+
+    common.Log.Info("Saving security analysis report as plain text file %s", fileName)
+    sections := []struct {
+        name   string
+        report func([]*Profile) string
+    }{
+        {"Kerberos", kerbReport},
+        {"PassTickets", ptktReport},
+        {"Bind credentials", bindReport},
+        {"Custom fields", csdataReport},
+        {"Tape volumes", func(profiles []*Profile) string { return tapeReport(icb, profiles) }},
+    }
+    for _, s := range sections {
+        common.Log.Debug("Creating report section %s", s.name)
+        fmt.Fprintf(f, "===== %s =====\n%s\n", s.name, s.report(profiles))
+    }
+}
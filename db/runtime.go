@@ -23,10 +23,12 @@ type Segment struct {
 
     Raw  string
     Data reflect.Value
+
+    Combinations []*sections.Combination // Combination fields of the segment template
 }
 
 func NewSegment(name string, id uint8, addr decode.Address, psize uint32, lsize uint32, raw string, v *reflect.Value) *Segment {
-    return &Segment{name, id, addr, psize, lsize, raw, *v}
+    return &Segment{name, id, addr, psize, lsize, raw, *v, nil}
 }
 
 type ProfileType struct {
@@ -51,6 +53,106 @@ func NewProfile(name string, tname string, tid uint8) *Profile {
     return &p
 }
 
+// Get profile class (the first 8 characters of general resource profile name)
+func (p *Profile) Class() string {
+    switch p.Type.Name {
+    case "GENERAL":
+        if len(p.Name) < 8 {
+            return strings.TrimSpace(p.Name)
+        }
+        return strings.TrimSpace(p.Name[:8])
+    case "DATASET":
+        return "DATASET"
+    }
+    return ""
+}
+
+// Get profile name without class name prefix (for general resource profiles)
+func (p *Profile) ResourceName() string {
+    if p.Type.Name == "GENERAL" && len(p.Name) > 8 {
+        return strings.TrimSpace(p.Name[8:])
+    }
+    return p.Name
+}
+
+// Get profile segment by name
+func (p *Profile) Segment(name string) (*Segment, bool) {
+    for i := range p.Segments {
+        if p.Segments[i].Name == name {
+            return &p.Segments[i], true
+        }
+    }
+    return nil, false
+}
+
+// Get field value of the segment by name
+func (s *Segment) Field(name string) (reflect.Value, bool) {
+    v := reflect.Indirect(s.Data).FieldByName(name)
+    if !v.IsValid() {
+        return v, false
+    }
+    return v, true
+}
+
+// Get values of the fields spanned by the combination field (member name -> value)
+func (s *Segment) Combination(name string) (map[string]reflect.Value, bool) {
+    for _, c := range s.Combinations {
+        if c.Name == name {
+            return c.View(s.Data), true
+        }
+    }
+    return nil, false
+}
+
+// Get items of the RepeatGroup (by the name of its count field) as segments
+func (s *Segment) Items(rgName string) []*Segment {
+    retVal := make([]*Segment, 0)
+    rg, ok := s.Field(rgName + "_RG")
+    if !ok {
+        return retVal
+    }
+    for i := 0; i < rg.Len(); i++ {
+        retVal = append(retVal, &Segment{Data: rg.Index(i)})
+    }
+    return retVal
+}
+
+// Get trimmed string representation of the segment field (empty string if there is no such field)
+func (s *Segment) FieldString(name string) string {
+    v, ok := s.Field(name)
+    if !ok {
+        return ""
+    }
+    return strings.TrimSpace(DumpField(v))
+}
+
+// Get raw bytes of the segment field (nil if there is no such field or it isn't a byte slice or array)
+func (s *Segment) FieldBytes(name string) []byte {
+    v, ok := s.Field(name)
+    if !ok || (v.Kind() != reflect.Slice && v.Kind() != reflect.Array) || v.Type().Elem().Kind() != reflect.Uint8 {
+        return nil
+    }
+    if v.Kind() == reflect.Array { // Time
+        retVal := make([]byte, v.Len())
+        reflect.Copy(reflect.ValueOf(retVal), v)
+        return retVal
+    }
+    return v.Bytes()
+}
+
+// Get integer value of the segment field (0 if there is no such field or it isn't an integer)
+func (s *Segment) FieldUint(name string) uint64 {
+    v, ok := s.Field(name)
+    if !ok {
+        return 0
+    }
+    switch v.Kind() {
+    case reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+        return v.Uint()
+    }
+    return 0
+}
+
 // Convert Profile to string (for dumping as plain text)
 func (p *Profile) String() string {
     retVal := fmt.Sprintf("Profile: %s (%v)\n", p.Name, &p.Type)
@@ -97,21 +199,31 @@ func (p *Profile) String() string {
     return retVal
 }
 
-// Parse RACF DB and create Profile list in memory (runtime DB)
-func ParseRACF(filename string) (map[string]map[string]reflect.Type, []*Profile, error) {
+// Parse RACF DB and create Profile list in memory (runtime DB). Templates are returned by template number (ICTMPN)
+func ParseRACF(filename string) (*sections.ICB, map[uint8]sections.Template, map[string]map[string]reflect.Type, []*Profile, error) {
     // Read RACF content
     data, err := os.ReadFile(filename)
     if err != nil {
-        return nil, nil, fmt.Errorf("can not open RACF DB file: %v\n", err)
+        return nil, nil, nil, nil, fmt.Errorf("can not open RACF DB file: %v\n", err)
     }
 
     common.Log.Info("Extracting Inventory Control Block (ICB)")
     icb, err := sections.ExtractICB(data)
     if err != nil {
-        return nil, nil, err
+        return nil, nil, nil, nil, err
     }
     common.Log.Debug("%v", icb)
 
+    release, ok := decode.ReleaseFromICB(icb.ICBTMPLV.String(), icb.ICBVRMN.String())
+    if !ok {
+        common.Log.Warning("Can not detect z/OS release from ICB (ICBTMPLV: %q; ICBVRMN: %q), using release %s",
+            icb.ICBTMPLV.String(), icb.ICBVRMN.String(), release)
+    }
+    common.Log.Info("Loading template field DB (source: %s; z/OS release: %s)", common.Opt.UseFieldDB, release)
+    if err := decode.LoadFieldDB(common.Opt.UseFieldDB, common.Opt.FieldDBFile, release); err != nil {
+        return nil, nil, nil, nil, err
+    }
+
     common.Log.Info("Extracting Templates")
     templates := make(map[uint8]sections.Template)
     templateNames := make(map[uint8]string) // Used to relate template Name and template Number from icb.ICBTEMP (field ICTMPN)
@@ -121,7 +233,7 @@ func ParseRACF(filename string) (map[string]map[string]reflect.Type, []*Profile,
             break
         }
         if err := t.UnmarshalBinary(data[th.ICTMPRBA : uint64(th.ICTMPRBA)+uint64(th.ICTMPL)]); err != nil {
-            return nil, nil, fmt.Errorf("can not extract template [%v: %s]: %v\n", &th.ICTMPRBA, t.Name(), err)
+            return nil, nil, nil, nil, fmt.Errorf("can not extract template [%v: %s]: %v\n", &th.ICTMPRBA, t.Name(), err)
         }
 
         if _, ok := templates[th.ICTMPN]; !ok {
@@ -133,8 +245,14 @@ func ParseRACF(filename string) (map[string]map[string]reflect.Type, []*Profile,
 
         common.Log.Debug("%s template (Offset: %v; Size: %d)\n", t.Name(), &th.ICTMPRBA, th.ICTMPL)
     }
+    // Combination fields are resolved once per template and shared by the segments of the template
+    combinations := make(map[uint8][]*sections.Combination)
     for i, t := range templates {
         common.Log.Debug("[%d] %v", i, &t)
+        combinations[i] = t.Combinations()
+        for _, c := range combinations[i] {
+            common.Log.Debug("Combination field %v", c)
+        }
     }
 
     common.Log.Info("Generating Profile structure based on RACF templates")
@@ -153,7 +271,7 @@ func ParseRACF(filename string) (map[string]map[string]reflect.Type, []*Profile,
     for IndBlkAddr := icb.ICISSRBA; IndBlkAddr != 0; {
         var ib sections.IndBlk
         if err := ib.UnmarshalBinary(data[IndBlkAddr : IndBlkAddr+0x1000]); err != nil { // 0x1000 is size of index block
-            return nil, nil, fmt.Errorf("can not extract index blocks [%v]: %v\n", &IndBlkAddr, err)
+            return nil, nil, nil, nil, fmt.Errorf("can not extract index blocks [%v]: %v\n", &IndBlkAddr, err)
         }
         ibs = append(ibs, ib)
         IndBlkAddr = ib.SSC.RBA
@@ -196,13 +314,18 @@ func ParseRACF(filename string) (map[string]map[string]reflect.Type, []*Profile,
                     hex.EncodeToString(data[profileSegmentRBA:uint64(profileSegmentRBA)+uint64(ps.Hdr.LogicLen)]),
                     sValue,
                 )
+                for _, c := range combinations[e.Type] {
+                    if c.Segment == s.Name {
+                        s.Combinations = append(s.Combinations, c)
+                    }
+                }
                 p.Segments = append(p.Segments, *s)
             }
             profiles = append(profiles, p)
         }
     }
 
-    return profileStructs, profiles, nil
+    return icb, templates, profileStructs, profiles, nil
 }
 
 // Get string representation of reflect.Value from runtime DB
@@ -231,6 +354,8 @@ func DumpField(val reflect.Value) string {
         retVal = fmt.Sprintf("%v", &v)
     case decode.Flag:
         retVal = fmt.Sprintf("%v", &v)
+    case decode.KerbEncType:
+        retVal = fmt.Sprintf("%v", &v)
     case []byte:
         retVal = fmt.Sprintf("%v", v)
     }
@@ -257,6 +382,8 @@ func DumpFieldWithHex(val reflect.Value) string {
         retVal = fmt.Sprintf("%s (%s)", v.String(), v.Hex())
     case decode.Flag:
         retVal = fmt.Sprintf("%s (%s)", v.String(), v.Hex())
+    case decode.KerbEncType:
+        retVal = fmt.Sprintf("%s (%s)", v.String(), v.Hex())
     case []byte:
         retVal = fmt.Sprintf("%v", v)
     }
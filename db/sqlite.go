@@ -1,161 +1,606 @@
 package db
 
 import (
+    "encoding/binary"
     "fmt"
+    "math"
     "reflect"
     "strings"
 
     "racfudit/common"
     "racfudit/decode"
+    "racfudit/sections"
 
     "database/sql"
 
     _ "github.com/mattn/go-sqlite3"
 )
 
+// Number of profiles written in one transaction
+const SQLITE_BATCH_SIZE = 1000
+
 type DBSQLite struct {
     db       *sql.DB
     fileName string
+    tables   map[string]*Table
+    tx       *sql.Tx
+    stmts    map[string]*sql.Stmt // Prepared INSERT statements of the current transaction (table name -> statement)
 }
 
 // Create new SQLite3 DB
 func NewDBSQLite(dbname string) (*DBSQLite, error) {
-    db, err := sql.Open("sqlite3", dbname)
+    db, err := sql.Open("sqlite3", dbname+"?_foreign_keys=on")
     if err != nil {
         return nil, err
     }
-    return &DBSQLite{db, dbname}, nil
+    return &DBSQLite{db: db, fileName: dbname, tables: make(map[string]*Table)}, nil
+}
+
+// Execute SQL query which doesn't return rows
+func (d *DBSQLite) exec(q string, args ...interface{}) (sql.Result, error) {
+    common.Log.Debug("Executing SQL query: %s", q)
+    r, err := d.db.Exec(q, args...)
+    if err != nil {
+        return nil, fmt.Errorf("Can not execute SQL query %q: %v", q, err)
+    }
+    return r, nil
+}
+
+// Start transaction for batch of inserts
+func (d *DBSQLite) begin() error {
+    tx, err := d.db.Begin()
+    if err != nil {
+        return fmt.Errorf("Can not start transaction: %v", err)
+    }
+    d.tx = tx
+    d.stmts = make(map[string]*sql.Stmt)
+    return nil
+}
+
+// Commit transaction (prepared statements of the transaction are closed)
+func (d *DBSQLite) commit() error {
+    if d.tx == nil {
+        return nil
+    }
+    err := d.tx.Commit()
+    d.tx, d.stmts = nil, nil
+    if err != nil {
+        return fmt.Errorf("Can not commit transaction: %v", err)
+    }
+    return nil
+}
+
+// Roll back transaction after failed insert
+func (d *DBSQLite) rollback() {
+    if d.tx != nil {
+        d.tx.Rollback()
+    }
+    d.tx, d.stmts = nil, nil
+}
+
+// Execute SQL query in the current transaction (savepoints of profile inserts)
+func (d *DBSQLite) execTx(q string) error {
+    common.Log.Debug("Executing SQL query: %s", q)
+    if _, err := d.tx.Exec(q); err != nil {
+        return fmt.Errorf("Can not execute SQL query %q: %v", q, err)
+    }
+    return nil
 }
 
-// Create tables in SQLite3 DB according to runtime DB
+// Insert row into the table with bound parameters. INSERT statement is prepared once per table and transaction
+func (d *DBSQLite) insert(tableName string, keys []string, args []interface{}) (sql.Result, error) {
+    stmt, ok := d.stmts[tableName]
+    if !ok {
+        placeholders := strings.Split(strings.Repeat("?", len(keys)), "")
+        q := PrepareInsertQuery(tableName, keys, placeholders)
+        common.Log.Debug("Preparing SQL query: %s", q)
+        var err error
+        if stmt, err = d.tx.Prepare(q); err != nil {
+            return nil, fmt.Errorf("Can not prepare SQL query %q: %v", q, err)
+        }
+        d.stmts[tableName] = stmt
+    }
+    r, err := stmt.Exec(args...)
+    if err != nil {
+        return nil, fmt.Errorf("Can not insert row into table %s: %v", tableName, err)
+    }
+    return r, nil
+}
+
+// Create tables in SQLite3 DB according to runtime DB. Each segment has its own table (TYPE_SEGMENT)
+// and each RepeatGroup of the segment has a child table (TYPE_SEGMENT_COUNTFIELD) referencing the segment row.
+// Column types and template metadata of the fields are saved in table SCHEMA_COLUMNS
 func (d *DBSQLite) Init(profileStructs map[string]map[string]reflect.Type) error {
-    for profileType, segments := range profileStructs {
-        for segmentName, segmentStruct := range segments {
-            fields := []string{`"ProfileName" TEXT`, `"Offset" TEXT`, `"RawData" TEXT`}
-            tableName := fmt.Sprintf("%s_%s", profileType, segmentName)
-            common.Log.Debug("Creating table %s", tableName)
-
-            for i := 0; i < segmentStruct.NumField(); i++ {
-                sField := segmentStruct.Field(i)
-
-                if sField.Type.Kind() == reflect.Slice &&
-                    sField.Type.Elem().Kind() == reflect.Struct &&
-                    strings.HasSuffix(sField.Name, "_RG") {
-
-                    // Expand and put RepeatGroup fields
-                    rpStruct := sField.Type.Elem()
-                    for i := 0; i < rpStruct.NumField(); i++ {
-                        rgField := rpStruct.Field(i)
-                        dbType := GetDBFieldType(&rgField.Type)
-                        fields = append(fields, fmt.Sprintf("%q %s", rgField.Name, dbType))
-                    }
+    fields := []string{`"TableName" TEXT`, `"ColumnName" TEXT`, `"ColumnType" TEXT`, `"Kind" TEXT`, `"GoType" TEXT`,
+        `"ProfileType" TEXT`, `"Segment" TEXT`, `"RepeatGroup" TEXT`, `"FieldName" TEXT`, `"FieldID" INTEGER`,
+        `"Flag1" INTEGER`, `"Flag2" INTEGER`, `"Length" INTEGER`, `"Note" TEXT`}
+    if _, err := d.exec(PrepareCreateQuery("SCHEMA_COLUMNS", fields)); err != nil {
+        return err
+    }
 
-                } else {
-                    // put non-RepeatGroup fields
-                    dbType := GetDBFieldType(&sField.Type)
-                    fields = append(fields, fmt.Sprintf("%q %s", sField.Name, dbType))
-                }
+    tables := NewTables(profileStructs)
+    for _, t := range tables {
+        if len(t.RepeatGroup) > 0 {
+            continue
+        }
+        if err := d.createTable(t); err != nil {
+            return err
+        }
+        for _, child := range t.Children {
+            if err := d.createTable(child); err != nil {
+                return err
             }
-            q := PrepareCreateQuery(tableName, fields)
-            common.Log.Debug("Executing SQL query: %s", q)
-            query, err := d.db.Prepare(q)
-            if err != nil {
-                return fmt.Errorf("Can not prepare SQL query %q: %v", q, err)
+        }
+    }
+
+    // Schema of all tables is written in one transaction
+    if err := d.begin(); err != nil {
+        return err
+    }
+    for _, t := range tables {
+        if err := d.writeSchema(t); err != nil {
+            d.rollback()
+            return err
+        }
+    }
+    return d.commit()
+}
+
+// SQLite column of the table. Flag and Kerberos encryption type fields are saved as INTEGER bitmask
+// and have an additional TEXT column with decoded value (<FIELD>_Bits and <FIELD>_Names).
+// Integer and flag fields which may not fit into signed 64-bit SQLite integer have an additional
+// BLOB column with their big-endian bytes (<FIELD>_Raw); the INTEGER column is NULL for such values
+type sqliteColumn struct {
+    Name    string
+    Type    string
+    Field   *TableColumn // Nil for service columns (ProfileName, Offset, etc.)
+    Decoded bool
+    Raw     bool
+    Note    string // Saved in SCHEMA_COLUMNS
+}
+
+// Check if values of the field may not fit into signed 64-bit integer (64-bit integers and flags of 8 bytes or more)
+func sqliteWideColumn(c *TableColumn) bool {
+    if c.Type != reflect.TypeOf(uint64(0)) && c.Type != reflect.TypeOf(decode.Flag{}) {
+        return false
+    }
+    length, ok := c.Meta()["len"]
+    return !ok || length == 0 || length >= 8
+}
+
+// Get SQLite columns of the table
+func sqliteColumns(t *Table) []sqliteColumn {
+    var retVal []sqliteColumn
+    if len(t.RepeatGroup) == 0 {
+        retVal = []sqliteColumn{{Name: "ProfileName", Type: "TEXT"}, {Name: "Offset", Type: "TEXT"}, {Name: "RawData", Type: "TEXT"}}
+    } else {
+        retVal = []sqliteColumn{{Name: "profile_id", Type: "INTEGER"}, {Name: "ItemIndex", Type: "INTEGER"}}
+    }
+    for i := range t.Columns {
+        c := &t.Columns[i]
+        wide := sqliteWideColumn(c)
+        column := sqliteColumn{Name: c.Name, Type: GetDBFieldType(&c.Type), Field: c}
+        if wide {
+            column.Note = fmt.Sprintf("NULL if the value exceeds %d (see %s_Raw)", int64(math.MaxInt64), c.Name)
+        }
+        retVal = append(retVal, column)
+        switch c.Type {
+        case reflect.TypeOf(decode.Flag{}):
+            retVal = append(retVal, sqliteColumn{Name: c.Name + "_Bits", Type: "TEXT", Field: c, Decoded: true})
+        case reflect.TypeOf(decode.KerbEncType{}):
+            retVal = append(retVal, sqliteColumn{Name: c.Name + "_Names", Type: "TEXT", Field: c, Decoded: true})
+        }
+        if wide {
+            retVal = append(retVal, sqliteColumn{Name: c.Name + "_Raw", Type: "BLOB", Field: c, Raw: true,
+                Note: fmt.Sprintf("Big-endian bytes of %s", c.Name)})
+        }
+    }
+    return retVal
+}
+
+func (d *DBSQLite) createTable(t *Table) error {
+    common.Log.Debug("Creating table %s", t.Name)
+    var fields []string
+    for _, c := range sqliteColumns(t) {
+        switch {
+        case c.Name == "profile_id":
+            fields = append(fields, fmt.Sprintf(`"profile_id" INTEGER NOT NULL REFERENCES %s(id) ON DELETE CASCADE`, t.Parent))
+        case c.Name == "ItemIndex":
+            fields = append(fields, `"ItemIndex" INTEGER NOT NULL`)
+        default:
+            fields = append(fields, fmt.Sprintf("%s %s", sqlIdent(c.Name), c.Type))
+        }
+    }
+    if _, err := d.exec(PrepareCreateQuery(t.Name, fields)); err != nil {
+        return err
+    }
+
+    if len(t.RepeatGroup) == 0 {
+        _, err := d.exec(fmt.Sprintf(`CREATE INDEX "%s_ProfileName" ON %s("ProfileName");`, t.Name, t.Name))
+        if err != nil {
+            return err
+        }
+    } else {
+        _, err := d.exec(fmt.Sprintf(`CREATE UNIQUE INDEX "%s_profile_id" ON %s("profile_id", "ItemIndex");`, t.Name, t.Name))
+        if err != nil {
+            return err
+        }
+    }
+    d.tables[t.Name] = t
+    return nil
+}
+
+// Save column types and template metadata of the table fields into SCHEMA_COLUMNS (in the current transaction)
+func (d *DBSQLite) writeSchema(t *Table) error {
+    keys := []string{`"TableName"`, `"ColumnName"`, `"ColumnType"`, `"Kind"`, `"GoType"`, `"ProfileType"`, `"Segment"`,
+        `"RepeatGroup"`, `"FieldName"`, `"FieldID"`, `"Flag1"`, `"Flag2"`, `"Length"`, `"Note"`}
+    for _, c := range sqliteColumns(t) {
+        args := []interface{}{t.Name, c.Name, c.Type, nil, nil, t.ProfileType, t.Segment, t.RepeatGroup, nil, nil, nil, nil, nil, nil}
+        if c.Field != nil {
+            meta := c.Field.Meta()
+            args[3], args[4], args[8] = c.Field.Kind(), c.Field.Type.String(), c.Field.Name
+            for i, key := range []string{"id", "flag1", "flag2", "len"} {
+                if v, ok := meta[key]; ok {
+                    args[9+i] = v
+                }
             }
-            defer query.Close()
-            _, err = query.Exec()
-            if err != nil {
-                return fmt.Errorf("Can not execute SQL query %q: %v", q, err)
+            if c.Decoded {
+                args[4] = "string"
+            } else if c.Raw {
+                args[4] = "[]byte"
             }
         }
+        if len(c.Note) > 0 {
+            args[13] = c.Note
+        }
+        if _, err := d.insert("SCHEMA_COLUMNS", keys, args); err != nil {
+            return err
+        }
     }
     return nil
 }
 
-// ToDO: Define SQLite field types or leave TEXT for everyone
+// Get SQLite column type of runtime DB field type: INTEGER for integers and bitmasks (flags, Kerberos encryption types),
+// DATE for dates (saved as ISO 8601 text), BLOB for binary data and TEXT for others. Integers and flags which don't fit
+// into signed 64-bit SQLite integer are saved as NULL and their bytes are saved in <FIELD>_Raw column (see sqliteColumn)
 func GetDBFieldType(t *reflect.Type) (s string) {
     switch *t {
     case reflect.TypeOf(uint8(0)), reflect.TypeOf(uint16(0)), reflect.TypeOf(uint32(0)), reflect.TypeOf(uint64(0)):
-        //s = "INTEGER"
-        s = "TEXT"
-    case reflect.TypeOf(decode.HexStr{}), reflect.TypeOf(decode.Date{}), reflect.TypeOf(decode.EBCDICStr{}), reflect.TypeOf(decode.Time{}), reflect.TypeOf(decode.Flag{}):
-        s = "TEXT"
+        s = "INTEGER"
+    case reflect.TypeOf(decode.Flag{}), reflect.TypeOf(decode.KerbEncType{}):
+        s = "INTEGER"
+    case reflect.TypeOf(decode.Date{}):
+        s = "DATE"
+    case reflect.TypeOf(decode.HexStr{}), reflect.TypeOf([]byte{}):
+        s = "BLOB"
     default:
         s = "TEXT"
     }
     return
 }
 
-// Fill tables in SQLite3 DB
+// Get value of runtime DB field for SQLite column (see GetDBFieldType). Zero dates are saved as NULL.
+// Integers above math.MaxInt64 and flags longer than 8 bytes are saved as NULL (see GetDBFieldRaw)
+func GetDBFieldValue(val reflect.Value, decoded bool) interface{} {
+    switch v := val.Interface().(type) {
+    case uint8, uint16, uint32, uint64:
+        if val.Uint() > math.MaxInt64 {
+            return nil
+        }
+        return int64(val.Uint())
+    case decode.Date:
+        if t, ok := v.Time(); ok {
+            return t.Format("2006-01-02")
+        }
+        return nil
+    case decode.Time:
+        return fmt.Sprintf("%02x:%02x:%02x", v[0], v[1], v[2])
+    case decode.Flag:
+        if decoded {
+            return v.String()
+        }
+        if len(v) > 8 || v.Uint() > math.MaxInt64 {
+            return nil
+        }
+        return int64(v.Uint())
+    case decode.KerbEncType:
+        if decoded {
+            return v.String()
+        }
+        return int64(v.Mask())
+    case decode.HexStr:
+        return []byte(v)
+    case []byte:
+        return v
+    }
+    return DumpField(val)
+}
+
+// Get value of integer or flag field for <FIELD>_Raw column: big-endian bytes (8 bytes for integers)
+func GetDBFieldRaw(val reflect.Value) []byte {
+    switch v := val.Interface().(type) {
+    case uint8, uint16, uint32, uint64:
+        b := make([]byte, 8)
+        binary.BigEndian.PutUint64(b, val.Uint())
+        return b
+    case decode.Flag:
+        return []byte(v)
+    }
+    return nil
+}
+
+// Fill tables in SQLite3 DB. Profiles are written in transactions of SQLITE_BATCH_SIZE profiles
 func (d *DBSQLite) Fill(profiles []*Profile) (err error) {
-    for _, profile := range profiles {
-        if err = d.writeProfile(profile); err != nil {
+    if err = d.begin(); err != nil {
+        return
+    }
+    for i, profile := range profiles {
+        if i > 0 && i%SQLITE_BATCH_SIZE == 0 {
+            common.Log.Debug("Committing %d profiles", i)
+            if err = d.commit(); err != nil {
+                return
+            }
+            if err = d.begin(); err != nil {
+                return
+            }
+        }
+        // Rows of the profile are inserted under savepoint, so the failed profile doesn't leave partial rows in the batch
+        if err = d.execTx("SAVEPOINT profile;"); err != nil {
+            d.rollback()
+            return
+        }
+        if err := d.writeProfile(profile); err != nil {
             common.Log.Error("Error %v", err)
+            if err := d.execTx("ROLLBACK TO SAVEPOINT profile;"); err != nil {
+                d.rollback()
+                return err
+            }
+        }
+        if err = d.execTx("RELEASE SAVEPOINT profile;"); err != nil {
+            d.rollback()
+            return
         }
     }
-    return
+    return d.commit()
 }
 
 func (d *DBSQLite) writeProfile(p *Profile) error {
-    for _, s := range p.Segments {
-        keys := []string{"ProfileName", "Offset", "RawData"}
-        values := []string{
-            fmt.Sprintf("'%s'", p.Name),
-            fmt.Sprintf("'%s'", s.Address.String()),
-            fmt.Sprintf("'%s'", s.Raw),
-        }
+    for i := range p.Segments {
+        s := &p.Segments[i]
         tableName := fmt.Sprintf("%s_%s", p.Type.Name, s.Name)
+        t, ok := d.tables[tableName]
+        if !ok {
+            return fmt.Errorf("Can not find table %s for profile %s", tableName, p.Name)
+        }
         common.Log.Debug("Inserting profile data %s in table %s", p.Name, tableName)
 
-        sV := reflect.Indirect(s.Data)
-        sT := reflect.TypeOf(sV.Interface())
-        for i := 0; i < sV.NumField(); i++ {
-            sFieldV := sV.Field(i)
-            sField := sT.Field(i)
-
-            // Check if field is a RepeatGroup field
-            if sField.Type.Kind() == reflect.Slice &&
-                sField.Type.Elem().Kind() == reflect.Struct &&
-                strings.HasSuffix(sField.Name, "_RG") {
-
-                // Expand and put RepeatGroup fields
-                rpT := sField.Type.Elem()
-                for i := 0; i < rpT.NumField(); i++ {
-                    rgField := rpT.Field(i)
-                    rgValues := make([]string, sFieldV.Len())
-                    for j := 0; j < sFieldV.Len(); j++ {
-                        rpV := sFieldV.Index(j)
-                        rgValues[j] = DumpField(rpV.Field(i))
+        for _, row := range t.SegmentRows(p, s) {
+            keys, args := rowArgs(t, row, p.Name, s.Address.String(), s.Raw)
+            r, err := d.insert(tableName, keys, args)
+            if err != nil {
+                return err
+            }
+            id, err := r.LastInsertId()
+            if err != nil {
+                return fmt.Errorf("Can not get id of profile %s in table %s: %v", p.Name, tableName, err)
+            }
+
+            // Put RepeatGroup items into child tables
+            for _, child := range t.Children {
+                for _, item := range child.SegmentRows(p, s) {
+                    keys, args := rowArgs(child, item, id, item.Index)
+                    if _, err := d.insert(child.Name, keys, args); err != nil {
+                        return err
                     }
-                    keys = append(keys, rgField.Name)
-                    values = append(values, fmt.Sprintf("'%s'", strings.Join(rgValues, "; ")))
                 }
-            } else {
-                keys = append(keys, sField.Name)
-                values = append(values, fmt.Sprintf("'%s'", DumpField(sFieldV)))
             }
         }
+    }
+    return nil
+}
 
-        q := PrepareInsertQuery(tableName, keys, values)
-        common.Log.Debug("Executing SQL query: %s", q)
-        query, err := d.db.Prepare(q)
-        if err != nil {
-            return fmt.Errorf("Can not prepare SQL query %q: %v", q, err)
+// Get column names and values of the table row. Values of service columns are set by keyValues
+func rowArgs(t *Table, row TableRow, keyValues ...interface{}) ([]string, []interface{}) {
+    keys := make([]string, 0)
+    args := make([]interface{}, 0)
+    j := -1
+    for i, c := range sqliteColumns(t) {
+        keys = append(keys, sqlIdent(c.Name))
+        if c.Field == nil {
+            args = append(args, keyValues[i])
+            continue
         }
-        defer query.Close()
-        _, err = query.Exec()
-        if err != nil {
-            return fmt.Errorf("Can not execute SQL query %q: %v", q, err)
+        switch {
+        case c.Raw:
+            args = append(args, GetDBFieldRaw(row.Values[j]))
+            continue
+        case !c.Decoded:
+            j++
+        }
+        args = append(args, GetDBFieldValue(row.Values[j], c.Decoded))
+    }
+    return keys, args
+}
+
+// Create and fill table with LDAP bind credentials found in PROXY segments
+func (d *DBSQLite) FillBindCredentials(profiles []*Profile) error {
+    fields := []string{`"ProfileName" TEXT`, `"ProfileType" TEXT`, `"Class" TEXT`, `"LDAPHost" TEXT`,
+        `"BindDN" TEXT`, `"Protection" TEXT`, `"BindPassword" TEXT`, `"BindPasswordKey" TEXT`}
+    if _, err := d.exec(PrepareCreateQuery("BIND_CREDENTIALS", fields)); err != nil {
+        return err
+    }
+
+    if err := d.begin(); err != nil {
+        return err
+    }
+    keys := []string{`"ProfileName"`, `"ProfileType"`, `"Class"`, `"LDAPHost"`, `"BindDN"`, `"Protection"`, `"BindPassword"`, `"BindPasswordKey"`}
+    for _, bc := range ExtractBindCredentials(profiles) {
+        common.Log.Debug("Inserting bind credential of profile %s in table BIND_CREDENTIALS", bc.Profile)
+        args := []interface{}{bc.Profile, bc.Type, bc.Class, bc.LDAPHost, bc.BindDN, bc.Protection, bc.Password, bc.PasswordKey}
+        if _, err := d.insert("BIND_CREDENTIALS", keys, args); err != nil {
+            d.rollback()
+            return err
+        }
+    }
+    return d.commit()
+}
+
+// Get column names of custom fields. Fields named as fixed columns of <TYPE>_CSDATA_CUSTOM table (id, ProfileName,
+// ValidationErrors) get prefix CSDATA_ (SQLite column names are case insensitive)
+func customFieldColumnNames(columns []*CustomField) []string {
+    used := map[string]bool{"ID": true, "PROFILENAME": true, "VALIDATIONERRORS": true}
+    retVal := make([]string, 0)
+    for _, cf := range columns {
+        name := cf.Name
+        for used[strings.ToUpper(name)] {
+            name = "CSDATA_" + name
+        }
+        used[strings.ToUpper(name)] = true
+        retVal = append(retVal, name)
+    }
+    return retVal
+}
+
+// Create and fill tables <TYPE>_CSDATA_CUSTOM with decoded CSDATA custom fields (one column per field)
+func (d *DBSQLite) FillCustomFields(profiles []*Profile) error {
+    schema := BuildCustomFieldSchema(profiles)
+    values := make(map[*Profile]map[string]*CustomValue)
+    for _, p := range profiles {
+        values[p] = DecodeCustomFields(p, schema)
+    }
+    for _, profileType := range []string{"USER", "GROUP", "DATASET", "GENERAL"} {
+        columns := customFieldColumns(profiles, values, schema, profileType)
+        if len(columns) == 0 {
+            continue
+        }
+        tableName := fmt.Sprintf("%s_CSDATA_CUSTOM", profileType)
+        common.Log.Debug("Creating table %s", tableName)
+
+        fields := []string{`"ProfileName" TEXT`}
+        keys := []string{`"ProfileName"`}
+        for i, name := range customFieldColumnNames(columns) {
+            dbType := "TEXT"
+            if columns[i].Type == "NUM" {
+                dbType = "INTEGER"
+            }
+            if name != columns[i].Name {
+                common.Log.Warning("Custom field %s.CSDATA.%s is saved in column %s of table %s", profileType, columns[i].Name, name, tableName)
+            }
+            fields = append(fields, fmt.Sprintf("%s %s", sqlIdent(name), dbType))
+            keys = append(keys, sqlIdent(name))
+        }
+        fields = append(fields, `"ValidationErrors" TEXT`)
+        keys = append(keys, `"ValidationErrors"`)
+        if _, err := d.exec(PrepareCreateQuery(tableName, fields)); err != nil {
+            return err
+        }
+
+        if err := d.begin(); err != nil {
+            return err
+        }
+        for _, p := range profiles {
+            if p.Type.Name != profileType {
+                continue
+            }
+            if len(values[p]) == 0 {
+                continue
+            }
+            args := []interface{}{p.Name}
+            errors := make([]string, 0)
+            for _, cf := range columns {
+                cv, ok := values[p][cf.Name]
+                if !ok {
+                    args = append(args, nil)
+                    continue
+                }
+                args = append(args, cv.Value)
+                for _, e := range cv.Errors {
+                    errors = append(errors, fmt.Sprintf("%s: %s", cf.Name, e))
+                }
+            }
+            args = append(args, strings.Join(errors, "; "))
+            common.Log.Debug("Inserting custom fields of profile %s in table %s", p.Name, tableName)
+            if _, err := d.insert(tableName, keys, args); err != nil {
+                d.rollback()
+                return err
+            }
+        }
+        if err := d.commit(); err != nil {
+            return err
         }
     }
     return nil
 }
 
+// Create and fill table with TVTOC entries of TAPEVOL profiles
+func (d *DBSQLite) FillTVTOC(profiles []*Profile) error {
+    fields := []string{`"Volume" TEXT`, `"Sequence" INTEGER`, `"DataSetName" TEXT`, `"Created" TEXT`,
+        `"RACFIndicated" INTEGER`, `"Volumes" TEXT`, `"RACFDataSetName" TEXT`}
+    if _, err := d.exec(PrepareCreateQuery("TAPEVOL_TVTOC", fields)); err != nil {
+        return err
+    }
+
+    if err := d.begin(); err != nil {
+        return err
+    }
+    keys := []string{`"Volume"`, `"Sequence"`, `"DataSetName"`, `"Created"`, `"RACFIndicated"`, `"Volumes"`, `"RACFDataSetName"`}
+    for _, te := range ExtractTVTOC(profiles) {
+        common.Log.Debug("Inserting TVTOC entry of tape volume %s in table TAPEVOL_TVTOC", te.Volume)
+        args := []interface{}{te.Volume, te.Sequence, te.DataSetName, te.Created, te.RACFIndicated, te.Volumes, te.RACFDataSetName}
+        if _, err := d.insert("TAPEVOL_TVTOC", keys, args); err != nil {
+            d.rollback()
+            return err
+        }
+    }
+    return d.commit()
+}
+
+// Create and fill table with template field definitions (one row per field of each template segment)
+func (d *DBSQLite) FillTemplates(templates map[uint8]sections.Template) error {
+    fields := []string{`"TemplateNumber" INTEGER`, `"Template" TEXT`, `"Segment" TEXT`, `"FieldID" INTEGER`, `"FieldName" TEXT`,
+        `"Flag1" INTEGER`, `"Flag2" INTEGER`, `"RepeatGroup" INTEGER`, `"RepeatGroupMember" INTEGER`, `"Combination" INTEGER`,
+        `"Encrypted" INTEGER`, `"FlagByte" INTEGER`, `"Date3" INTEGER`, `"Length" INTEGER`, `"DefaultValue" INTEGER`,
+        `"GoType" TEXT`, `"CombinationIDs" TEXT`}
+    if _, err := d.exec(PrepareCreateQuery("TEMPLATE_FIELDS", fields)); err != nil {
+        return err
+    }
+
+    if err := d.begin(); err != nil {
+        return err
+    }
+    keys := []string{`"TemplateNumber"`, `"Template"`, `"Segment"`, `"FieldID"`, `"FieldName"`, `"Flag1"`, `"Flag2"`,
+        `"RepeatGroup"`, `"RepeatGroupMember"`, `"Combination"`, `"Encrypted"`, `"FlagByte"`, `"Date3"`, `"Length"`,
+        `"DefaultValue"`, `"GoType"`, `"CombinationIDs"`}
+    for _, t := range NewTemplateDefs(templates) {
+        for _, s := range t.Segments {
+            for _, f := range s.Fields {
+                common.Log.Debug("Inserting field %s of template %s in table TEMPLATE_FIELDS", f.Name, t.Name)
+                ids := make([]string, 0)
+                for _, id := range f.CombinationIDs {
+                    ids = append(ids, fmt.Sprintf("%d", id))
+                }
+                args := []interface{}{t.Number, t.Name, s.Name, f.ID, f.Name, f.Flag1, f.Flag2, f.RepeatGroup, f.RepeatGroupMember,
+                    f.Combination, f.Encrypted, f.FlagByte, f.Date3, f.Length, f.DefaultValue, f.GoType, strings.Join(ids, ",")}
+                if _, err := d.insert("TEMPLATE_FIELDS", keys, args); err != nil {
+                    d.rollback()
+                    return err
+                }
+            }
+        }
+    }
+    return d.commit()
+}
+
 // Close SQLite3 DB handler
 func (d *DBSQLite) Close() {
     d.db.Close()
 }
 
+// Quote SQL identifier (double quotes inside the name are doubled)
+func sqlIdent(name string) string {
+    return `"` + strings.ReplaceAll(name, `"`, `""`) + `"`
+}
+
 // Create SQL query for creating a table
 func PrepareCreateQuery(name string, fields []string) string {
     query := fmt.Sprintf("CREATE TABLE %s ", name)
@@ -175,7 +620,7 @@ func PrepareInsertQuery(name string, keys []string, values []string) string {
 }
 
 // Save runtime DB as SQLite3 DB
-func ToSQLite(profiles []*Profile, fileName string, profileStructs map[string]map[string]reflect.Type) {
+func ToSQLite(profiles []*Profile, fileName string, profileStructs map[string]map[string]reflect.Type, templates map[uint8]sections.Template) {
     dbSQLite, err := NewDBSQLite(fileName)
     if err != nil {
         common.Fatal(fmt.Errorf("Can not create SQLite3 DB: %v", err))
@@ -194,4 +639,29 @@ func ToSQLite(profiles []*Profile, fileName string, profileStructs map[string]ma
         common.Fatal(fmt.Errorf("Can not fill SQLite3 DB completely: %v", err))
     }
 
+    common.Log.Info("Saving LDAP bind credentials in SQLite3 DB %s", fileName)
+    if err = dbSQLite.FillBindCredentials(profiles); err != nil {
+        common.Fatal(fmt.Errorf("Can not save bind credentials: %v", err))
+    }
+
+    common.Log.Info("Saving CSDATA custom fields in SQLite3 DB %s", fileName)
+    if err = dbSQLite.FillCustomFields(profiles); err != nil {
+        common.Fatal(fmt.Errorf("Can not save custom fields: %v", err))
+    }
+
+    common.Log.Info("Saving tape volume table of contents in SQLite3 DB %s", fileName)
+    if err = dbSQLite.FillTVTOC(profiles); err != nil {
+        common.Fatal(fmt.Errorf("Can not save TVTOC entries: %v", err))
+    }
+
+    common.Log.Info("Saving template field definitions in SQLite3 DB %s", fileName)
+    if err = dbSQLite.FillTemplates(templates); err != nil {
+        common.Fatal(fmt.Errorf("Can not save template field definitions: %v", err))
+    }
+
+    common.Log.Info("Creating views in SQLite3 DB %s", fileName)
+    if err = dbSQLite.CreateViews(); err != nil {
+        common.Fatal(fmt.Errorf("Can not create views: %v", err))
+    }
+
 }
@@ -0,0 +1,362 @@
+package db
+
+import (
+    "database/sql"
+    "fmt"
+    "math"
+    "os"
+    "path/filepath"
+    "reflect"
+    "strings"
+    "testing"
+
+    "racfudit/common"
+    "racfudit/decode"
+)
+
+func TestMain(m *testing.M) {
+    common.Opt = &common.Options{}
+    if err := common.Opt.Logger(); err != nil {
+        panic(err)
+    }
+    os.Exit(m.Run())
+}
+
+// Encode ASCII string as EBCDIC field value
+func testEBCDIC(s string) decode.EBCDICStr {
+    a2e := make(map[byte]byte)
+    for i := 0; i < 256; i++ {
+        c := decode.EBCDICStr{byte(i)}
+        a2e[c.String()[0]] = byte(i)
+    }
+    retVal := make(decode.EBCDICStr, len(s))
+    for i := range s {
+        retVal[i] = a2e[s[i]]
+    }
+    return retVal
+}
+
+// Create segment structure with EBCDIC string fields
+func testSegmentType(fields ...string) reflect.Type {
+    sFields := make([]reflect.StructField, 0)
+    for _, name := range fields {
+        sFields = append(sFields, reflect.StructField{Name: name, Type: reflect.TypeOf(decode.EBCDICStr{})})
+    }
+    return reflect.StructOf(sFields)
+}
+
+// Create segment of the profile with field values
+func testSegment(p *Profile, name string, t reflect.Type, values map[string]string) {
+    v := reflect.New(t)
+    for field, value := range values {
+        v.Elem().FieldByName(field).Set(reflect.ValueOf(testEBCDIC(value)))
+    }
+    p.Segments = append(p.Segments, *NewSegment(name, 1, 0, 0, 0, "", &v))
+}
+
+// Field of test segment. String values are EBCDIC strings, testFields values are RepeatGroup items
+// (count field <NAME> and items in field <NAME>_RG), other values are set as is
+type testField struct {
+    name  string
+    value interface{}
+}
+
+type testFields []testField
+
+// Create segment structure of the fields (in the order of the fields)
+func testFieldsValue(fields testFields) reflect.Value {
+    sFields := make([]reflect.StructField, 0)
+    values := make([]reflect.Value, 0)
+    for _, f := range fields {
+        switch v := f.value.(type) {
+        case string:
+            sFields = append(sFields, reflect.StructField{Name: f.name, Type: reflect.TypeOf(decode.EBCDICStr{})})
+            values = append(values, reflect.ValueOf(testEBCDIC(v)))
+        case []testFields:
+            var items reflect.Value
+            for i, item := range v {
+                iv := testFieldsValue(item).Elem()
+                if i == 0 {
+                    items = reflect.MakeSlice(reflect.SliceOf(iv.Type()), 0, len(v))
+                }
+                items = reflect.Append(items, iv)
+            }
+            if !items.IsValid() {
+                items = reflect.MakeSlice(reflect.SliceOf(reflect.StructOf(nil)), 0, 0)
+            }
+            sFields = append(sFields, reflect.StructField{Name: f.name, Type: reflect.TypeOf(uint32(0))},
+                reflect.StructField{Name: f.name + "_RG", Type: items.Type()})
+            values = append(values, reflect.ValueOf(uint32(len(v))), items)
+        default:
+            sFields = append(sFields, reflect.StructField{Name: f.name, Type: reflect.TypeOf(v)})
+            values = append(values, reflect.ValueOf(v))
+        }
+    }
+    retVal := reflect.New(reflect.StructOf(sFields))
+    for i, v := range values {
+        retVal.Elem().Field(i).Set(v)
+    }
+    return retVal
+}
+
+// Add segment with the fields to the profile and get type of the segment structure
+func testSegmentOf(p *Profile, name string, fields testFields) reflect.Type {
+    v := testFieldsValue(fields)
+    p.Segments = append(p.Segments, *NewSegment(name, uint8(len(p.Segments)+1), 0, 0, 0, "", &v))
+    return v.Elem().Type()
+}
+
+var hostileValues = []string{
+    `O'Brien`,
+    `"quoted" name`,
+    `x'; DROP TABLE USER_BASE; --`,
+    `a"); DELETE FROM USER_WORKATTR; --`,
+    "NUL\x00inside",
+    "line1\nline2\r\n",
+    `;--'"`,
+}
+
+func TestSQLiteFillHostileValues(t *testing.T) {
+    baseT := testSegmentType("PGMRNAME", "INSTDATA")
+    waT := testSegmentType("WAADDR1", "WAADDR2", "WAADDR3", "WAADDR4")
+    profileStructs := map[string]map[string]reflect.Type{"USER": {"BASE": baseT, "WORKATTR": waT}}
+
+    profiles := make([]*Profile, 0)
+    for i, v := range hostileValues {
+        p := NewProfile(string(rune('A'+i))+"USER", "USER", 2)
+        testSegment(p, "BASE", baseT, map[string]string{"PGMRNAME": v, "INSTDATA": v})
+        testSegment(p, "WORKATTR", waT, map[string]string{"WAADDR1": v, "WAADDR2": v, "WAADDR3": v, "WAADDR4": v})
+        profiles = append(profiles, p)
+    }
+
+    fileName := filepath.Join(t.TempDir(), "racf.db")
+    d, err := NewDBSQLite(fileName)
+    if err != nil {
+        t.Fatal(err)
+    }
+    defer d.Close()
+    if err := d.Init(profileStructs); err != nil {
+        t.Fatal(err)
+    }
+    if err := d.Fill(profiles); err != nil {
+        t.Fatal(err)
+    }
+
+    for _, p := range profiles {
+        var pgmrName, instData string
+        row := d.db.QueryRow(`SELECT "PGMRNAME", "INSTDATA" FROM USER_BASE WHERE "ProfileName" = ?`, p.Name)
+        if err := row.Scan(&pgmrName, &instData); err != nil {
+            t.Fatalf("Can not read BASE row of %s: %v", p.Name, err)
+        }
+        base, _ := p.Segment("BASE")
+        if want := DumpField(reflect.Indirect(base.Data).FieldByName("PGMRNAME")); pgmrName != want || instData != want {
+            t.Errorf("%s: PGMRNAME = %q, INSTDATA = %q, want %q", p.Name, pgmrName, instData, want)
+        }
+
+        var addr [4]string
+        row = d.db.QueryRow(`SELECT "WAADDR1", "WAADDR2", "WAADDR3", "WAADDR4" FROM USER_WORKATTR WHERE "ProfileName" = ?`, p.Name)
+        if err := row.Scan(&addr[0], &addr[1], &addr[2], &addr[3]); err != nil {
+            t.Fatalf("Can not read WORKATTR row of %s: %v", p.Name, err)
+        }
+        wa, _ := p.Segment("WORKATTR")
+        want := DumpField(reflect.Indirect(wa.Data).FieldByName("WAADDR1"))
+        for i, v := range addr {
+            if v != want {
+                t.Errorf("%s: WAADDR%d = %q, want %q", p.Name, i+1, v, want)
+            }
+        }
+    }
+
+    // Printable values are saved as is
+    var instData string
+    if err := d.db.QueryRow(`SELECT "INSTDATA" FROM USER_BASE WHERE "ProfileName" = 'CUSER'`).Scan(&instData); err != nil {
+        t.Fatal(err)
+    }
+    if instData != hostileValues[2] {
+        t.Errorf("INSTDATA = %q, want %q", instData, hostileValues[2])
+    }
+    for _, table := range []string{"USER_BASE", "USER_WORKATTR"} {
+        var count int
+        if err := d.db.QueryRow("SELECT count(*) FROM " + table).Scan(&count); err != nil {
+            t.Fatal(err)
+        }
+        if count != len(profiles) {
+            t.Errorf("Table %s has %d rows, want %d", table, count, len(profiles))
+        }
+    }
+}
+
+func TestSQLiteFillRollsBackFailedProfile(t *testing.T) {
+    baseT := testSegmentType("PGMRNAME")
+    profileStructs := map[string]map[string]reflect.Type{"USER": {"BASE": baseT}}
+
+    good := NewProfile("GOOD", "USER", 2)
+    testSegment(good, "BASE", baseT, map[string]string{"PGMRNAME": "GOOD USER"})
+    // BASE row is inserted before the unknown segment fails
+    bad := NewProfile("BAD", "USER", 2)
+    testSegment(bad, "BASE", baseT, map[string]string{"PGMRNAME": "BAD USER"})
+    testSegment(bad, "UNKNOWN", baseT, map[string]string{"PGMRNAME": "BAD USER"})
+    last := NewProfile("LAST", "USER", 2)
+    testSegment(last, "BASE", baseT, map[string]string{"PGMRNAME": "LAST USER"})
+
+    d, err := NewDBSQLite(filepath.Join(t.TempDir(), "racf.db"))
+    if err != nil {
+        t.Fatal(err)
+    }
+    defer d.Close()
+    if err := d.Init(profileStructs); err != nil {
+        t.Fatal(err)
+    }
+    if err := d.Fill([]*Profile{good, bad, last}); err != nil {
+        t.Fatal(err)
+    }
+
+    rows, err := d.db.Query(`SELECT "ProfileName" FROM USER_BASE ORDER BY id`)
+    if err != nil {
+        t.Fatal(err)
+    }
+    defer rows.Close()
+    names := make([]string, 0)
+    for rows.Next() {
+        var name sql.NullString
+        if err := rows.Scan(&name); err != nil {
+            t.Fatal(err)
+        }
+        names = append(names, name.String)
+    }
+    if !reflect.DeepEqual(names, []string{"GOOD", "LAST"}) {
+        t.Errorf("Profiles in USER_BASE = %v, want [GOOD LAST]", names)
+    }
+}
+
+// Create CSDATA segment of the profile with CHAR custom fields
+func testCSDATA(p *Profile, fields map[string]string) {
+    itemT := reflect.StructOf([]reflect.StructField{
+        {Name: "CSTYPE", Type: reflect.TypeOf(decode.Flag{})},
+        {Name: "CSKEY", Type: reflect.TypeOf(decode.EBCDICStr{})},
+        {Name: "CSVALUE", Type: reflect.TypeOf(decode.EBCDICStr{})},
+    })
+    sT := reflect.StructOf([]reflect.StructField{{Name: "CSCNT_RG", Type: reflect.SliceOf(itemT)}})
+    v := reflect.New(sT)
+    for key, value := range fields {
+        item := reflect.New(itemT).Elem()
+        item.Field(0).Set(reflect.ValueOf(decode.Flag{0x01}))
+        item.Field(1).Set(reflect.ValueOf(testEBCDIC(key)))
+        item.Field(2).Set(reflect.ValueOf(testEBCDIC(value)))
+        v.Elem().Field(0).Set(reflect.Append(v.Elem().Field(0), item))
+    }
+    p.Segments = append(p.Segments, *NewSegment("CSDATA", 2, 0, 0, 0, "", &v))
+}
+
+func TestSQLiteFillCustomFieldsColumnNames(t *testing.T) {
+    p := NewProfile("IBMUSER", "USER", 2)
+    testCSDATA(p, map[string]string{"ID": "A1", "PROFILENAME": "B2", "VALIDATIONERRORS": "C3", `A"B`: "D4", "EMPSER": "E5"})
+
+    d, err := NewDBSQLite(filepath.Join(t.TempDir(), "racf.db"))
+    if err != nil {
+        t.Fatal(err)
+    }
+    defer d.Close()
+    if err := d.FillCustomFields([]*Profile{p}); err != nil {
+        t.Fatal(err)
+    }
+
+    var name, id, profileName, validationErrors, quoted, empser string
+    row := d.db.QueryRow(`SELECT "ProfileName", "CSDATA_ID", "CSDATA_PROFILENAME", "CSDATA_VALIDATIONERRORS", "A""B", "EMPSER" FROM USER_CSDATA_CUSTOM`)
+    if err := row.Scan(&name, &id, &profileName, &validationErrors, &quoted, &empser); err != nil {
+        t.Fatal(err)
+    }
+    if got := []string{name, id, profileName, validationErrors, quoted, empser}; !reflect.DeepEqual(got, []string{"IBMUSER", "A1", "B2", "C3", "D4", "E5"}) {
+        t.Errorf("Row of USER_CSDATA_CUSTOM = %q", got)
+    }
+}
+
+func TestSQLiteFillWideIntegers(t *testing.T) {
+    baseT := reflect.StructOf([]reflect.StructField{
+        {Name: "UID", Type: reflect.TypeOf(uint64(0))},
+        {Name: "FLAGS", Type: reflect.TypeOf(decode.Flag{})},
+    })
+    profileStructs := map[string]map[string]reflect.Type{"USER": {"BASE": baseT}}
+
+    profiles := make([]*Profile, 0)
+    for i, tc := range []struct {
+        uid  uint64
+        flag decode.Flag
+    }{
+        {42, decode.Flag{0x80}},
+        {math.MaxInt64 + 1, decode.Flag{0xff, 0, 0, 0, 0, 0, 0, 1}},
+        {math.MaxUint64, decode.Flag{1, 2, 3, 4, 5, 6, 7, 8, 9}},
+    } {
+        p := NewProfile(string(rune('A'+i))+"USER", "USER", 2)
+        v := reflect.New(baseT)
+        v.Elem().Field(0).SetUint(tc.uid)
+        v.Elem().Field(1).Set(reflect.ValueOf(tc.flag))
+        p.Segments = append(p.Segments, *NewSegment("BASE", 1, 0, 0, 0, "", &v))
+        profiles = append(profiles, p)
+    }
+
+    d, err := NewDBSQLite(filepath.Join(t.TempDir(), "racf.db"))
+    if err != nil {
+        t.Fatal(err)
+    }
+    defer d.Close()
+    if err := d.Init(profileStructs); err != nil {
+        t.Fatal(err)
+    }
+    if err := d.Fill(profiles); err != nil {
+        t.Fatal(err)
+    }
+
+    for _, tc := range []struct {
+        name    string
+        uid     interface{}
+        uidRaw  []byte
+        flag    interface{}
+        flagRaw []byte
+    }{
+        {"AUSER", int64(42), []byte{0, 0, 0, 0, 0, 0, 0, 42}, int64(0x80), []byte{0x80}},
+        {"BUSER", nil, []byte{0x80, 0, 0, 0, 0, 0, 0, 0}, nil, []byte{0xff, 0, 0, 0, 0, 0, 0, 1}},
+        {"CUSER", nil, []byte{0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff}, nil, []byte{1, 2, 3, 4, 5, 6, 7, 8, 9}},
+    } {
+        var uid, flag interface{}
+        var uidRaw, flagRaw []byte
+        row := d.db.QueryRow(`SELECT "UID", "UID_Raw", "FLAGS", "FLAGS_Raw" FROM USER_BASE WHERE "ProfileName" = ?`, tc.name)
+        if err := row.Scan(&uid, &uidRaw, &flag, &flagRaw); err != nil {
+            t.Fatal(err)
+        }
+        if !reflect.DeepEqual(uid, tc.uid) || !reflect.DeepEqual(flag, tc.flag) {
+            t.Errorf("%s: UID = %#v, FLAGS = %#v; want %#v, %#v", tc.name, uid, flag, tc.uid, tc.flag)
+        }
+        if !reflect.DeepEqual(uidRaw, tc.uidRaw) || !reflect.DeepEqual(flagRaw, tc.flagRaw) {
+            t.Errorf("%s: UID_Raw = %x, FLAGS_Raw = %x; want %x, %x", tc.name, uidRaw, flagRaw, tc.uidRaw, tc.flagRaw)
+        }
+    }
+
+    // Storage classes of the columns aren't mixed
+    for _, column := range []string{"UID", "FLAGS"} {
+        var types int
+        query := fmt.Sprintf(`SELECT count(DISTINCT typeof("%s")) FROM USER_BASE WHERE "%s" IS NOT NULL`, column, column)
+        if err := d.db.QueryRow(query).Scan(&types); err != nil {
+            t.Fatal(err)
+        }
+        if types != 1 {
+            t.Errorf("Column %s has values of %d storage classes", column, types)
+        }
+    }
+
+    var note string
+    if err := d.db.QueryRow(`SELECT "Note" FROM SCHEMA_COLUMNS WHERE "TableName" = 'USER_BASE' AND "ColumnName" = 'UID'`).Scan(&note); err != nil {
+        t.Fatal(err)
+    }
+    if !strings.Contains(note, "UID_Raw") {
+        t.Errorf("Note of column UID = %q", note)
+    }
+
+    var count int
+    if err := d.db.QueryRow(`SELECT count(*) FROM SCHEMA_COLUMNS WHERE "TableName" = 'USER_BASE'`).Scan(&count); err != nil {
+        t.Fatal(err)
+    }
+    if want := len(sqliteColumns(d.tables["USER_BASE"])); count != want {
+        t.Errorf("SCHEMA_COLUMNS has %d rows of USER_BASE, want %d", count, want)
+    }
+}
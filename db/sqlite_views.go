@@ -0,0 +1,292 @@
+package db
+
+import (
+    "fmt"
+    "strings"
+
+    "racfudit/common"
+)
+
+// Curated SQLite view for common security questions
+type sqliteView struct {
+    Name        string
+    Description string
+    Tables      []string                 // Source tables (the view isn't created if one of them is absent)
+    Query       func(d *DBSQLite) string // SELECT statement of the view
+}
+
+var sqliteViews = []sqliteView{
+    {
+        Name:        "users_attributes",
+        Description: "User attributes from USER BASE segment: owner, default group, dates and SPECIAL, OPERATIONS, AUDITOR, ROAUDIT, REVOKE, PROTECTED attributes",
+        Tables:      []string{"USER_BASE"},
+        Query: func(d *DBSQLite) string {
+            c := func(name string) string { return d.column("USER_BASE", "u", name) }
+            return fmt.Sprintf(`SELECT u.id AS profile_id, trim(u."ProfileName") AS user, %s AS name, %s AS owner, %s AS default_group,
+    %s AS created, %s AS last_logon_date, %s AS last_logon_time, %s AS password_date, %s AS password_interval,
+    %s AS special, %s AS operations, %s AS auditor, %s AS roaudit, %s AS revoked, %s AS protected, %s AS uaudit,
+    %s AS revoke_date, %s AS resume_date, %s AS revoke_count
+FROM USER_BASE u`,
+                c("PGMRNAME"), c("AUTHOR"), c("DFLTGRP"), c("AUTHDATE"), c("LJDATE"), c("LJTIME"), c("PASSDATE"), c("PASSINT"),
+                sqlFlag(c("FLAG2"), 0x80), sqlFlag(c("FLAG3"), 0x80), sqlFlag(c("FLAG6"), 0x80), sqlFlag(c("FLAGROA"), 0x80),
+                sqlFlag(c("FLAG4"), 0x80), sqlFlag(c("FLAG7"), 0x80), sqlFlag(c("UAUDIT"), 0x80),
+                c("REVOKEDT"), c("RESUMEDT"), c("REVOKECT"))
+        },
+    },
+    {
+        Name:        "user_group_connections",
+        Description: "Connections of users to groups with group authority (from group ACL) and group-SPECIAL, OPERATIONS, AUDITOR, REVOKE attributes",
+        Tables:      []string{"USER_BASE", "USER_BASE_CGGRPCT"},
+        Query: func(d *DBSQLite) string {
+            c := func(name string) string { return d.column("USER_BASE_CGGRPCT", "c", name) }
+            authority := "NULL"
+            if d.hasTable("GROUP_BASE") && d.hasTable("GROUP_BASE_ACLCNT") {
+                acs := d.byteColumn("GROUP_BASE_ACLCNT", "a", "USERACS")
+                authority = fmt.Sprintf(`(SELECT %s FROM GROUP_BASE g JOIN GROUP_BASE_ACLCNT a ON a.profile_id = g.id
+        WHERE trim(g."ProfileName") = trim(%s) AND trim(%s) = trim(u."ProfileName") LIMIT 1)`,
+                    sqlGroupAuthority(acs), c("CGGRPNM"), d.column("GROUP_BASE_ACLCNT", "a", "USERID"))
+            }
+            return fmt.Sprintf(`SELECT trim(u."ProfileName") AS user, trim(%s) AS "group", %s AS authority, %s AS owner, %s AS connected,
+    %s AS last_connect_date, %s AS last_connect_time, %s AS uacc, %s AS connect_count,
+    %s AS adsp, %s AS special, %s AS operations, %s AS revoked, %s AS grpacc, %s AS auditor,
+    %s AS revoke_date, %s AS resume_date
+FROM USER_BASE u JOIN USER_BASE_CGGRPCT c ON c.profile_id = u.id`,
+                c("CGGRPNM"), authority, c("CGAUTHOR"), c("CGAUTHDA"), c("CGLJDATE"), c("CGLJTIME"),
+                sqlAccess(d.byteColumn("USER_BASE_CGGRPCT", "c", "CGUACC")), c("CGINITCT"),
+                sqlFlag(c("CGFLAG1"), 0x80), sqlFlag(c("CGFLAG2"), 0x80), sqlFlag(c("CGFLAG3"), 0x80),
+                sqlFlag(c("CGFLAG4"), 0x80), sqlFlag(c("CGFLAG5"), 0x80), sqlFlag(c("CGGRPAUD"), 0x80),
+                c("CGREVKDT"), c("CGRESMDT"))
+        },
+    },
+    {
+        Name:        "dataset_access",
+        Description: "Access lists of data set profiles (standard and conditional) with UACC of the profile",
+        Tables:      []string{"DATASET_BASE", "DATASET_BASE_ACLCNT"},
+        Query: func(d *DBSQLite) string {
+            query := fmt.Sprintf(`SELECT trim(p."ProfileName") AS dataset, %s AS owner, %s AS uacc, trim(%s) AS id, %s AS access,
+    %s AS access_count, NULL AS condition
+FROM DATASET_BASE p JOIN DATASET_BASE_ACLCNT a ON a.profile_id = p.id`,
+                d.column("DATASET_BASE", "p", "AUTHOR"), sqlAccess(d.byteColumn("DATASET_BASE", "p", "UNIVACS")),
+                d.column("DATASET_BASE_ACLCNT", "a", "USERID"), sqlAccess(d.byteColumn("DATASET_BASE_ACLCNT", "a", "USERACS")),
+                d.column("DATASET_BASE_ACLCNT", "a", "ACSCNT"))
+            if d.hasTable("DATASET_BASE_ACL2CNT") {
+                query += fmt.Sprintf(`
+UNION ALL
+SELECT trim(p."ProfileName"), %s, %s, trim(%s), %s, %s, 'PROGRAM(' || trim(%s) || ')'
+FROM DATASET_BASE p JOIN DATASET_BASE_ACL2CNT a ON a.profile_id = p.id`,
+                    d.column("DATASET_BASE", "p", "AUTHOR"), sqlAccess(d.byteColumn("DATASET_BASE", "p", "UNIVACS")),
+                    d.column("DATASET_BASE_ACL2CNT", "a", "USER2ACS"), sqlAccess(d.byteColumn("DATASET_BASE_ACL2CNT", "a", "PROGACS")),
+                    d.column("DATASET_BASE_ACL2CNT", "a", "PACSCNT"), d.column("DATASET_BASE_ACL2CNT", "a", "PROGRAM"))
+            }
+            return query
+        },
+    },
+    {
+        Name:        "resource_access",
+        Description: "Access lists of general resource profiles (standard and conditional) with class and UACC of the profile",
+        Tables:      []string{"GENERAL_BASE", "GENERAL_BASE_ACLCNT"},
+        Query: func(d *DBSQLite) string {
+            query := fmt.Sprintf(`SELECT trim(substr(p."ProfileName", 1, 8)) AS class, trim(substr(p."ProfileName", 9)) AS resource,
+    %s AS owner, %s AS uacc, trim(%s) AS id, %s AS access, %s AS access_count, NULL AS condition
+FROM GENERAL_BASE p JOIN GENERAL_BASE_ACLCNT a ON a.profile_id = p.id`,
+                d.column("GENERAL_BASE", "p", "OWNER"), sqlAccess(d.byteColumn("GENERAL_BASE", "p", "UACC")),
+                d.column("GENERAL_BASE_ACLCNT", "a", "USERID"), sqlAccess(d.byteColumn("GENERAL_BASE_ACLCNT", "a", "USERACS")),
+                d.column("GENERAL_BASE_ACLCNT", "a", "ACSCNT"))
+            if d.hasTable("GENERAL_BASE_ACL2CNT") {
+                query += fmt.Sprintf(`
+UNION ALL
+SELECT trim(substr(p."ProfileName", 1, 8)), trim(substr(p."ProfileName", 9)), %s, %s, trim(%s), %s, %s, trim(%s)
+FROM GENERAL_BASE p JOIN GENERAL_BASE_ACL2CNT a ON a.profile_id = p.id`,
+                    d.column("GENERAL_BASE", "p", "OWNER"), sqlAccess(d.byteColumn("GENERAL_BASE", "p", "UACC")),
+                    d.column("GENERAL_BASE_ACL2CNT", "a", "ACL2UID"), sqlAccess(d.byteColumn("GENERAL_BASE_ACL2CNT", "a", "ACL2ACC")),
+                    d.column("GENERAL_BASE_ACL2CNT", "a", "ACL2ACNT"), d.column("GENERAL_BASE_ACL2CNT", "a", "ACL2NAME"))
+            }
+            return query
+        },
+    },
+    {
+        Name:        "group_tree",
+        Description: "Group hierarchy: superior group, owner, level from the root group and path (root/.../group)",
+        Tables:      []string{"GROUP_BASE"},
+        Query: func(d *DBSQLite) string {
+            return fmt.Sprintf(`WITH RECURSIVE groups(name, supgroup, owner) AS (
+    SELECT trim(g."ProfileName"), nullif(trim(%s), ''), %s FROM GROUP_BASE g
+), tree(name, supgroup, owner, level, path) AS (
+    SELECT name, supgroup, owner, 0, name FROM groups
+    WHERE supgroup IS NULL OR supgroup NOT IN (SELECT name FROM groups) OR supgroup = name
+    UNION ALL
+    SELECT g.name, g.supgroup, g.owner, t.level + 1, t.path || '/' || g.name
+    FROM groups g JOIN tree t ON g.supgroup = t.name AND g.name != g.supgroup
+    WHERE t.level < 64
+)
+SELECT name AS "group", supgroup AS superior_group, owner, level, path FROM tree`,
+                d.column("GROUP_BASE", "g", "SUPGROUP"), d.column("GROUP_BASE", "g", "AUTHOR"))
+        },
+    },
+    {
+        Name:        "omvs_identities",
+        Description: "z/OS UNIX identities of users (UID, home, program) and groups (GID); shared is set if the ID is assigned to several profiles",
+        Tables:      []string{"USER_OMVS"},
+        Query: func(d *DBSQLite) string {
+            uid, shared := d.column("USER_OMVS", "o", "UID"), "0"
+            if d.hasColumn("USER_OMVS", "UID") {
+                shared = `(SELECT count(*) FROM USER_OMVS x WHERE x."UID" = o."UID") > 1`
+            }
+            query := fmt.Sprintf(`SELECT 'USER' AS type, trim(o."ProfileName") AS name, %s AS id, %s AS home, %s AS program,
+    %s AS shared, coalesce(%s = 0, 0) AS superuser
+FROM USER_OMVS o`,
+                uid, d.column("USER_OMVS", "o", "HOME"), d.column("USER_OMVS", "o", "PROGRAM"), shared, uid)
+            if d.hasColumn("GROUP_OMVS", "GID") {
+                query += `
+UNION ALL
+SELECT 'GROUP', trim(o."ProfileName"), o."GID", NULL, NULL,
+    (SELECT count(*) FROM GROUP_OMVS x WHERE x."GID" = o."GID") > 1, 0
+FROM GROUP_OMVS o`
+            }
+            return query
+        },
+    },
+    {
+        Name:        "certificates",
+        Description: "Digital certificate profiles (DIGTCERT CERTDATA segment) with label, validity, private key presence and associated user",
+        Tables:      []string{"GENERAL_BASE", "GENERAL_CERTDATA"},
+        Query: func(d *DBSQLite) string {
+            user, join := "NULL", ""
+            if d.hasTable("USER_BASE") && d.hasColumn("USER_BASE_CERTCT", "CERTNAME") {
+                user = "trim(u.\"ProfileName\")"
+                join = `
+LEFT JOIN USER_BASE_CERTCT uc ON trim(uc."CERTNAME") = trim(substr(c."ProfileName", 9))
+LEFT JOIN USER_BASE u ON u.id = uc.profile_id`
+            }
+            return fmt.Sprintf(`SELECT trim(substr(c."ProfileName", 1, 8)) AS class, trim(substr(c."ProfileName", 9)) AS profile,
+    %s AS user, %s AS label, %s AS owner, %s AS trust, %s AS not_before, %s AS not_after,
+    coalesce(length(%s), 0) > 0 AS has_private_key, %s AS ring_count
+FROM GENERAL_CERTDATA c JOIN GENERAL_BASE p ON p."ProfileName" = c."ProfileName"%s`,
+                user, d.column("GENERAL_CERTDATA", "c", "CERTLABL"), d.column("GENERAL_BASE", "p", "OWNER"),
+                d.column("GENERAL_BASE", "p", "APPLDATA"), d.column("GENERAL_CERTDATA", "c", "CERTSTRT"),
+                d.column("GENERAL_CERTDATA", "c", "CERTEND"), d.column("GENERAL_CERTDATA", "c", "CERTPRVK"),
+                d.column("GENERAL_CERTDATA", "c", "RINGCT"), join)
+        },
+    },
+}
+
+// Check if table of the runtime DB has been created
+func (d *DBSQLite) hasTable(tableName string) bool {
+    _, ok := d.tables[tableName]
+    return ok
+}
+
+// Check if table of the runtime DB has the field column
+func (d *DBSQLite) hasColumn(tableName string, name string) bool {
+    return d.tableColumn(tableName, name) != nil
+}
+
+func (d *DBSQLite) tableColumn(tableName string, name string) *TableColumn {
+    t, ok := d.tables[tableName]
+    if !ok {
+        return nil
+    }
+    for i := range t.Columns {
+        if t.Columns[i].Name == name {
+            return &t.Columns[i]
+        }
+    }
+    return nil
+}
+
+// Get SQL expression of the field column (NULL if the field is absent in the template)
+func (d *DBSQLite) column(tableName string, alias string, name string) string {
+    if !d.hasColumn(tableName, name) {
+        return "NULL"
+    }
+    return alias + "." + sqlIdent(name)
+}
+
+// Get SQL expression of the first byte of the field column as integer (access and flag bytes
+// are INTEGER bitmasks, but some of them are saved as BLOB if they are not defined as flags)
+func (d *DBSQLite) byteColumn(tableName string, alias string, name string) string {
+    c := d.tableColumn(tableName, name)
+    if c == nil {
+        return "NULL"
+    }
+    expr := alias + "." + sqlIdent(name)
+    if GetDBFieldType(&c.Type) != "BLOB" {
+        if c.Kind() == "flag" && c.Meta()["len"] > 1 {
+            return fmt.Sprintf("(%s >> %d)", expr, 8*(c.Meta()["len"]-1))
+        }
+        return expr
+    }
+    return fmt.Sprintf("((instr('0123456789ABCDEF', substr(hex(%s), 1, 1)) - 1) * 16 + instr('0123456789ABCDEF', substr(hex(%s), 2, 1)) - 1)", expr, expr)
+}
+
+// Get SQL expression checking that the flag bit is set
+func sqlFlag(expr string, mask int) string {
+    if expr == "NULL" {
+        return "NULL"
+    }
+    return fmt.Sprintf("coalesce((%s & %d) != 0, 0)", expr, mask)
+}
+
+// Get SQL expression of access level name of access byte
+func sqlAccess(expr string) string {
+    if expr == "NULL" {
+        return "NULL"
+    }
+    return fmt.Sprintf(`CASE WHEN %[1]s IS NULL THEN NULL WHEN %[1]s & 128 THEN 'ALTER' WHEN %[1]s & 64 THEN 'CONTROL'
+        WHEN %[1]s & 32 THEN 'UPDATE' WHEN %[1]s & 16 THEN 'READ' WHEN %[1]s & 8 THEN 'EXECUTE' ELSE 'NONE' END`, expr)
+}
+
+// Get SQL expression of group authority name of group ACL access byte
+func sqlGroupAuthority(expr string) string {
+    if expr == "NULL" {
+        return "NULL"
+    }
+    return fmt.Sprintf(`CASE WHEN %[1]s & 128 THEN 'JOIN' WHEN %[1]s & 64 THEN 'CONNECT' WHEN %[1]s & 32 THEN 'CREATE' ELSE 'USE' END`, expr)
+}
+
+// Create curated views and describe them in table VIEWS (name, description, source tables and columns)
+func (d *DBSQLite) CreateViews() error {
+    fields := []string{`"ViewName" TEXT`, `"Description" TEXT`, `"SourceTables" TEXT`, `"Columns" TEXT`, `"Query" TEXT`}
+    if _, err := d.exec(PrepareCreateQuery("VIEWS", fields)); err != nil {
+        return err
+    }
+
+    keys := []string{`"ViewName"`, `"Description"`, `"SourceTables"`, `"Columns"`, `"Query"`}
+    q := PrepareInsertQuery("VIEWS", keys, strings.Split(strings.Repeat("?", len(keys)), ""))
+    for _, v := range sqliteViews {
+        skip := false
+        for _, t := range v.Tables {
+            if !d.hasTable(t) {
+                common.Log.Warning("Skipping creation of view %s: table %s doesn't exist", v.Name, t)
+                skip = true
+                break
+            }
+        }
+        if skip {
+            continue
+        }
+
+        query := v.Query(d)
+        if _, err := d.exec(fmt.Sprintf("CREATE VIEW %s AS\n%s;", v.Name, query)); err != nil {
+            return err
+        }
+        columns, err := d.viewColumns(v.Name)
+        if err != nil {
+            return err
+        }
+        if _, err := d.exec(q, v.Name, v.Description, strings.Join(v.Tables, ", "), strings.Join(columns, ", "), query); err != nil {
+            return err
+        }
+    }
+    return nil
+}
+
+// Get column names of the view
+func (d *DBSQLite) viewColumns(name string) ([]string, error) {
+    rows, err := d.db.Query(fmt.Sprintf("SELECT * FROM %s LIMIT 0", name))
+    if err != nil {
+        return nil, fmt.Errorf("Can not query view %s: %v", name, err)
+    }
+    defer rows.Close()
+    return rows.Columns()
+}
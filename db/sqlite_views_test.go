@@ -0,0 +1,141 @@
+package db
+
+import (
+    "bytes"
+    "reflect"
+    "strings"
+    "testing"
+
+    "racfudit/common"
+    "racfudit/decode"
+)
+
+func TestSQLiteCreateViews(t *testing.T) {
+    var buf bytes.Buffer
+    defer func(l *common.Logger) { common.Log = l }(common.Log)
+    common.Log = common.NewLogger(&buf)
+
+    profileStructs := make(map[string]map[string]reflect.Type)
+    add := func(p *Profile, segment string, fields testFields) *Profile {
+        if profileStructs[p.Type.Name] == nil {
+            profileStructs[p.Type.Name] = make(map[string]reflect.Type)
+        }
+        profileStructs[p.Type.Name][segment] = testSegmentOf(p, segment, fields)
+        return p
+    }
+
+    profiles := []*Profile{
+        add(NewProfile("SYS1", "GROUP", 1), "BASE", testFields{
+            {"SUPGROUP", ""},
+            {"AUTHOR", "IBMUSER"},
+            {"ACLCNT", []testFields{
+                {{"USERID", "IBMUSER"}, {"USERACS", decode.Flag{0x40}}},
+                {{"USERID", "JOHN"}, {"USERACS", decode.Flag{0x00}}},
+            }},
+        }),
+        add(NewProfile("IBMUSER", "USER", 2), "BASE", testFields{
+            {"AUTHOR", "SYS1"},
+            {"DFLTGRP", "SYS1"},
+            {"CGGRPCT", []testFields{{
+                {"CGGRPNM", "SYS1"}, {"CGAUTHOR", "IBMUSER"}, {"CGUACC", decode.Flag{0x10}},
+                {"CGINITCT", uint32(42)}, {"CGFLAG2", decode.Flag{0x80}},
+            }}},
+        }),
+        add(NewProfile("JOHN", "USER", 2), "BASE", testFields{
+            {"AUTHOR", "SYS1"},
+            {"DFLTGRP", "SYS1"},
+            {"CGGRPCT", []testFields{{
+                {"CGGRPNM", "SYS1"}, {"CGAUTHOR", "IBMUSER"}, {"CGUACC", decode.Flag{0x00}},
+                {"CGINITCT", uint32(1)}, {"CGFLAG2", decode.Flag{0x00}},
+            }}},
+        }),
+        add(NewProfile("SYS1.**", "DATASET", 4), "BASE", testFields{
+            {"AUTHOR", "IBMUSER"},
+            {"UNIVACS", decode.Flag{0x10}},
+            {"ACLCNT", []testFields{{{"USERID", "SYS1"}, {"USERACS", decode.Flag{0x80}}, {"ACSCNT", uint32(5)}}}},
+            {"ACL2CNT", []testFields{{{"USER2ACS", "JOHN"}, {"PROGRAM", "PAYROLL"}, {"PROGACS", decode.Flag{0x20}}, {"PACSCNT", uint32(3)}}}},
+        }),
+        // Certificate tables (GENERAL_CERTDATA) are missing
+        add(NewProfile("FACILITYBPX.SUPERUSER", "GENERAL", 5), "BASE", testFields{
+            {"OWNER", "SYS1"},
+            {"UACC", decode.Flag{0x00}},
+            {"ACLCNT", []testFields{{{"USERID", "IBMUSER"}, {"USERACS", decode.Flag{0x10}}, {"ACSCNT", uint32(9)}}}},
+        }),
+    }
+
+    // Connections of in-memory DB don't share it, so the only connection is used
+    d, err := NewDBSQLite(":memory:")
+    if err != nil {
+        t.Fatal(err)
+    }
+    defer d.Close()
+    d.db.SetMaxOpenConns(1)
+    if err := d.Init(profileStructs); err != nil {
+        t.Fatal(err)
+    }
+    if err := d.Fill(profiles); err != nil {
+        t.Fatal(err)
+    }
+    if err := d.CreateViews(); err != nil {
+        t.Fatal(err)
+    }
+
+    for _, tc := range []struct {
+        query string
+        want  [][]interface{}
+    }{
+        {`SELECT dataset, owner, uacc, id, access, access_count, condition FROM dataset_access ORDER BY id`, [][]interface{}{
+            {"SYS1.**", "IBMUSER", "READ", "JOHN", "UPDATE", int64(3), "PROGRAM(PAYROLL)"},
+            {"SYS1.**", "IBMUSER", "READ", "SYS1", "ALTER", int64(5), nil},
+        }},
+        {`SELECT user, "group", authority, owner, uacc, connect_count, special FROM user_group_connections ORDER BY user`, [][]interface{}{
+            {"IBMUSER", "SYS1", "CONNECT", "IBMUSER", "READ", int64(42), int64(1)},
+            {"JOHN", "SYS1", "USE", "IBMUSER", "NONE", int64(1), int64(0)},
+        }},
+        {`SELECT class, resource, id, access FROM resource_access`, [][]interface{}{
+            {"FACILITY", "BPX.SUPERUSER", "IBMUSER", "READ"},
+        }},
+        {`SELECT "ViewName" FROM VIEWS ORDER BY "ViewName"`, [][]interface{}{
+            {"dataset_access"}, {"group_tree"}, {"resource_access"}, {"user_group_connections"}, {"users_attributes"},
+        }},
+    } {
+        rows, err := d.db.Query(tc.query)
+        if err != nil {
+            t.Fatalf("%s: %v", tc.query, err)
+        }
+        got := make([][]interface{}, 0)
+        for rows.Next() {
+            row := make([]interface{}, len(tc.want[0]))
+            ptrs := make([]interface{}, len(row))
+            for i := range row {
+                ptrs[i] = &row[i]
+            }
+            if err := rows.Scan(ptrs...); err != nil {
+                t.Fatalf("%s: %v", tc.query, err)
+            }
+            for i, v := range row {
+                if b, ok := v.([]byte); ok {
+                    row[i] = string(b)
+                }
+            }
+            got = append(got, row)
+        }
+        rows.Close()
+        if !reflect.DeepEqual(got, tc.want) {
+            t.Errorf("%s = %v, want %v", tc.query, got, tc.want)
+        }
+    }
+
+    for _, view := range []string{"certificates", "omvs_identities"} {
+        var count int
+        if err := d.db.QueryRow(`SELECT count(*) FROM sqlite_master WHERE type = 'view' AND name = ?`, view).Scan(&count); err != nil {
+            t.Fatal(err)
+        }
+        if count != 0 {
+            t.Errorf("View %s is created without its source tables", view)
+        }
+    }
+    if want := "WARNING: Skipping creation of view certificates: table GENERAL_CERTDATA doesn't exist"; !strings.Contains(buf.String(), want) {
+        t.Errorf("Log doesn't contain %q", want)
+    }
+}
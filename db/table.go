@@ -0,0 +1,147 @@
+package db
+
+import (
+    "fmt"
+    "reflect"
+    "sort"
+    "strings"
+
+    "racfudit/decode"
+)
+
+// Table layout of runtime DB: one table per profile type and segment (TYPE_SEGMENT)
+// and one child table per RepeatGroup of the segment (TYPE_SEGMENT_COUNTFIELD)
+type Table struct {
+    Name        string
+    ProfileType string
+    Segment     string
+    RepeatGroup string // Name of RepeatGroup count field (empty for segment tables)
+    Parent      string // Segment table name for RepeatGroup tables
+    Columns     []TableColumn
+    Children    []*Table // RepeatGroup tables of segment table
+}
+
+type TableColumn struct {
+    Name  string
+    Type  reflect.Type
+    Tag   reflect.StructTag // Template metadata of the field (see sections.TemplateField.Tag)
+    index int               // Index of the field in segment (or RepeatGroup) structure
+}
+
+// Row of a table. Index is the RepeatGroup item index (0 for segment tables)
+type TableRow struct {
+    Profile *Profile
+    Segment *Segment
+    Index   int
+    Values  []reflect.Value
+}
+
+// Check if struct field is a RepeatGroup field
+func isRepeatGroupField(f reflect.StructField) bool {
+    return f.Type.Kind() == reflect.Slice &&
+        f.Type.Elem().Kind() == reflect.Struct &&
+        strings.HasSuffix(f.Name, "_RG")
+}
+
+// Create table layout according to runtime DB structures (tables are sorted by name)
+func NewTables(profileStructs map[string]map[string]reflect.Type) []*Table {
+    retVal := make([]*Table, 0)
+    for profileType, segments := range profileStructs {
+        for segmentName, segmentStruct := range segments {
+            t := &Table{Name: fmt.Sprintf("%s_%s", profileType, segmentName), ProfileType: profileType, Segment: segmentName}
+            for i := 0; i < segmentStruct.NumField(); i++ {
+                sField := segmentStruct.Field(i)
+                if !isRepeatGroupField(sField) {
+                    t.Columns = append(t.Columns, TableColumn{sField.Name, sField.Type, sField.Tag, i})
+                    continue
+                }
+
+                rgName := strings.TrimSuffix(sField.Name, "_RG")
+                rgTable := &Table{
+                    Name:        fmt.Sprintf("%s_%s", t.Name, rgName),
+                    ProfileType: profileType,
+                    Segment:     segmentName,
+                    RepeatGroup: rgName,
+                    Parent:      t.Name,
+                }
+                rgStruct := sField.Type.Elem()
+                for j := 0; j < rgStruct.NumField(); j++ {
+                    rgField := rgStruct.Field(j)
+                    rgTable.Columns = append(rgTable.Columns, TableColumn{rgField.Name, rgField.Type, rgField.Tag, j})
+                }
+                t.Children = append(t.Children, rgTable)
+                retVal = append(retVal, rgTable)
+            }
+            retVal = append(retVal, t)
+        }
+    }
+    sort.Slice(retVal, func(i, j int) bool { return retVal[i].Name < retVal[j].Name })
+    return retVal
+}
+
+// Get table rows of the profile
+func (t *Table) Rows(p *Profile) []TableRow {
+    retVal := make([]TableRow, 0)
+    for i := range p.Segments {
+        retVal = append(retVal, t.SegmentRows(p, &p.Segments[i])...)
+    }
+    return retVal
+}
+
+// Get table rows of the profile segment
+func (t *Table) SegmentRows(p *Profile, s *Segment) []TableRow {
+    retVal := make([]TableRow, 0)
+    if p.Type.Name != t.ProfileType || s.Name != t.Segment {
+        return retVal
+    }
+    sDataV := reflect.Indirect(s.Data)
+
+    if len(t.RepeatGroup) == 0 {
+        row := TableRow{Profile: p, Segment: s}
+        for _, c := range t.Columns {
+            row.Values = append(row.Values, sDataV.Field(c.index))
+        }
+        return append(retVal, row)
+    }
+
+    rg := sDataV.FieldByName(t.RepeatGroup + "_RG")
+    if !rg.IsValid() {
+        return retVal
+    }
+    for j := 0; j < rg.Len(); j++ {
+        row := TableRow{Profile: p, Segment: s, Index: j}
+        for _, c := range t.Columns {
+            row.Values = append(row.Values, rg.Index(j).Field(c.index))
+        }
+        retVal = append(retVal, row)
+    }
+    return retVal
+}
+
+// Get kind of column data (integer, string, hex, date, time, flag, kerbenc)
+func (c *TableColumn) Kind() string {
+    switch c.Type {
+    case reflect.TypeOf(uint8(0)), reflect.TypeOf(uint16(0)), reflect.TypeOf(uint32(0)), reflect.TypeOf(uint64(0)):
+        return "integer"
+    case reflect.TypeOf(decode.EBCDICStr{}):
+        return "string"
+    case reflect.TypeOf(decode.Date{}):
+        return "date"
+    case reflect.TypeOf(decode.Time{}):
+        return "time"
+    case reflect.TypeOf(decode.Flag{}):
+        return "flag"
+    case reflect.TypeOf(decode.KerbEncType{}):
+        return "kerbenc"
+    }
+    return "hex"
+}
+
+// Get template metadata of the column field (keys: id, flag1, flag2, len). Empty map is returned if it is unknown
+func (c *TableColumn) Meta() decode.FieldTag {
+    if len(c.Tag.Get("racf")) == 0 {
+        return make(decode.FieldTag)
+    }
+    meta, _ := decode.ParseTag(c.Tag.Get("racf"), reflect.Value{})
+    return meta
+}
@@ -0,0 +1,135 @@
+package db
+
+import (
+    "fmt"
+    "sort"
+    "strings"
+
+    "racfudit/common"
+    "racfudit/sections"
+)
+
+// Tape volume table of contents (TVTOC) entry of TAPEVOL profile
+type TVTOCEntry struct {
+    Volume          string // TAPEVOL profile name
+    Sequence        uint64 // File sequence number of the data set on the tape
+    Created         string // Creation date
+    RACFIndicated   bool   // Data set is protected by a discrete profile (TVTOCIND X'80')
+    DataSetName     string
+    Volumes         string // Volume serials of a multivolume data set
+    RACFDataSetName string // Name of the data set as it is known to RACF
+}
+
+func (te *TVTOCEntry) String() string {
+    return fmt.Sprintf("\t%d %s (created: %s; RACF-indicated: %v; RACF name: %s; volumes: %s)\n",
+        te.Sequence, te.DataSetName, te.Created, te.RACFIndicated, te.RACFDataSetName, te.Volumes)
+}
+
+// Extract TVTOC entries from TAPEVOL profiles (TVTOCCNT repeat group of BASE segment)
+func ExtractTVTOC(profiles []*Profile) []*TVTOCEntry {
+    retVal := make([]*TVTOCEntry, 0)
+    for _, p := range profiles {
+        if p.Class() != "TAPEVOL" {
+            continue
+        }
+        s, ok := p.Segment("BASE")
+        if !ok {
+            continue
+        }
+        rg, ok := s.Field("TVTOCCNT_RG")
+        if !ok {
+            continue
+        }
+        for i := 0; i < rg.Len(); i++ {
+            item := Segment{Data: rg.Index(i)}
+            te := &TVTOCEntry{
+                Volume:          p.ResourceName(),
+                Sequence:        item.FieldUint("TVTOCSEQ"),
+                Created:         item.FieldString("TVTOCCRD"),
+                DataSetName:     item.FieldString("TVTOCDSN"),
+                Volumes:         item.FieldString("TVTOCVOL"),
+                RACFDataSetName: item.FieldString("TVTOCRDS"),
+            }
+            if b := item.FieldBytes("TVTOCIND"); len(b) > 0 && b[0]&0x80 != 0 {
+                te.RACFIndicated = true
+            }
+            common.Log.Debug("Extracted TVTOC entry %d of tape volume %s: %s", te.Sequence, te.Volume, te.DataSetName)
+            retVal = append(retVal, te)
+        }
+    }
+    return retVal
+}
+
+// DATASET profile patterns indexed by high-level qualifier. Patterns with generic characters in the
+// high-level qualifier (e.g. ** or SYS%) may match any data set and are kept separately
+type datasetPatterns struct {
+    byHLQ   map[string][]*GenericPattern
+    generic []*GenericPattern
+}
+
+func newDatasetPatterns(profiles []*Profile) *datasetPatterns {
+    retVal := &datasetPatterns{byHLQ: make(map[string][]*GenericPattern), generic: make([]*GenericPattern, 0)}
+    for _, p := range profiles {
+        if p.Type.Name != "DATASET" {
+            continue
+        }
+        gp := NewGenericPattern(strings.TrimSpace(p.Name))
+        hlq := strings.SplitN(gp.Pattern, ".", 2)[0]
+        if strings.ContainsAny(hlq, "*%") {
+            retVal.generic = append(retVal.generic, gp)
+        } else {
+            retVal.byHLQ[hlq] = append(retVal.byHLQ[hlq], gp)
+        }
+    }
+    return retVal
+}
+
+// Check if data set is covered by a discrete or generic DATASET profile
+func (dp *datasetPatterns) Match(dsName string) bool {
+    for _, gp := range dp.byHLQ[strings.SplitN(dsName, ".", 2)[0]] {
+        if gp.Match(dsName) {
+            return true
+        }
+    }
+    for _, gp := range dp.generic {
+        if gp.Match(dsName) {
+            return true
+        }
+    }
+    return false
+}
+
+// Get plain text report about tape volumes and tape data set protection
+func tapeReport(icb *sections.ICB, profiles []*Profile) string {
+    var retVal string
+    if icb != nil {
+        retVal += fmt.Sprintf("Tape volume protection (TAPEVOL class, ICBTAPE): %v\n", icb.ICBTAPE)
+        retVal += fmt.Sprintf("Tape data set protection (SETROPTS TAPEDSN, ICBTDSN): %v\n", icb.ICBTDSN)
+    }
+
+    entries := ExtractTVTOC(profiles)
+    patterns := newDatasetPatterns(profiles)
+    unprotected := make([]string, 0)
+    for _, te := range entries {
+        if te.RACFIndicated {
+            continue
+        }
+        if !patterns.Match(te.DataSetName) {
+            unprotected = append(unprotected, fmt.Sprintf("\t%s (tape volume %s, file %d)\n", te.DataSetName, te.Volume, te.Sequence))
+        }
+    }
+    sort.Strings(unprotected)
+
+    retVal += fmt.Sprintf("TVTOC entries: %d\n", len(entries))
+    retVal += fmt.Sprintf("Tape data sets without discrete or generic DATASET profile: %d\n%s", len(unprotected), strings.Join(unprotected, ""))
+    retVal += "Tape volume table of contents:\n"
+    var volume string
+    for _, te := range entries {
+        if te.Volume != volume {
+            volume = te.Volume
+            retVal += fmt.Sprintf("%s\n", volume)
+        }
+        retVal += te.String()
+    }
+    return retVal
+}
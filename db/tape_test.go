@@ -0,0 +1,34 @@
+package db
+
+import (
+    "testing"
+)
+
+func TestDatasetPatternsMatch(t *testing.T) {
+    profiles := make([]*Profile, 0)
+    for _, name := range []string{"SYS1.PARMLIB", "TAPE.BACKUP.*", "PROD.**", "%AY.**", "**.ARCHIVE"} {
+        profiles = append(profiles, NewProfile(name, "DATASET", 4))
+    }
+    // Profiles of other types are ignored
+    profiles = append(profiles, NewProfile("OTHER.**", "GENERAL", 5))
+
+    dp := newDatasetPatterns(profiles)
+    for _, tc := range []struct {
+        dsName string
+        want   bool
+    }{
+        {"SYS1.PARMLIB", true},
+        {"SYS1.PROCLIB", false},
+        {"TAPE.BACKUP.D2024", true},
+        {"TAPE.BACKUP.D2024.X", false},
+        {"PROD.A.B.C", true},
+        {"PAY.DATA", true},
+        {"PAYROLL.DATA", false},
+        {"USER1.ARCHIVE", true},
+        {"OTHER.DATA", false},
+    } {
+        if got := dp.Match(tc.dsName); got != tc.want {
+            t.Errorf("Match(%s) = %v, want %v", tc.dsName, got, tc.want)
+        }
+    }
+}
@@ -0,0 +1,127 @@
+package db
+
+import (
+    "encoding/json"
+    "fmt"
+    "sort"
+    "strings"
+
+    "racfudit/common"
+    "racfudit/sections"
+)
+
+// Template definitions (FDT) of RACF DB with template level from ICB
+type TemplateExport struct {
+    Level     string        `json:"level"`
+    Version   string        `json:"version"`
+    Templates []TemplateDef `json:"templates"`
+}
+
+type TemplateDef struct {
+    Number   uint8                `json:"number"`
+    Name     string               `json:"name"`
+    Segments []TemplateSegmentDef `json:"segments"`
+}
+
+type TemplateSegmentDef struct {
+    Name   string             `json:"name"`
+    Fields []TemplateFieldDef `json:"fields"`
+}
+
+// Field definition with decoded flags. GoType is the type of the field in profile structure
+// (empty for combination fields which don't hold data)
+type TemplateFieldDef struct {
+    ID                uint8   `json:"id"`
+    Name              string  `json:"name"`
+    Flag1             uint8   `json:"flag1"`
+    Flag2             uint8   `json:"flag2"`
+    RepeatGroup       bool    `json:"repeatGroup"`
+    RepeatGroupMember bool    `json:"repeatGroupMember"`
+    Combination       bool    `json:"combination"`
+    Encrypted         bool    `json:"encrypted"`
+    FlagByte          bool    `json:"flagByte"`
+    Date3             bool    `json:"date3"`
+    Length            uint32  `json:"length"`
+    DefaultValue      uint8   `json:"defaultValue"`
+    GoType            string  `json:"goType"`
+    CombinationIDs    []uint8 `json:"combinationIds,omitempty"`
+}
+
+// Convert template field definition (segment name is needed to look up the field type in field DB)
+func NewTemplateFieldDef(tmp *sections.Template, sName string, f *sections.TemplateField) TemplateFieldDef {
+    retVal := TemplateFieldDef{
+        ID:                f.ID,
+        Name:              f.NameTrim(),
+        Flag1:             f.Flag1,
+        Flag2:             f.Flag2,
+        RepeatGroup:       f.IsRepeatGroup(),
+        RepeatGroupMember: f.IsRepeatGroupMember(),
+        Combination:       f.IsCombinationField(),
+        Encrypted:         f.Flag1&0x04 == 0x04,
+        FlagByte:          f.Flag1&0x20 == 0x20,
+        Date3:             f.Flag2&0x20 == 0x20,
+        Length:            f.Len,
+        DefaultValue:      f.DefaultValue,
+    }
+    if retVal.Combination {
+        retVal.CombinationIDs = f.CombinationIDs()
+    } else {
+        retVal.GoType = f.ToType(tmp.Name(), sName).String()
+    }
+    return retVal
+}
+
+// Convert templates to definitions sorted by template number. Fields are grouped by segments
+// (the first field of the template starts BASE segment)
+func NewTemplateDefs(templates map[uint8]sections.Template) []TemplateDef {
+    numbers := make([]int, 0)
+    for n := range templates {
+        numbers = append(numbers, int(n))
+    }
+    sort.Ints(numbers)
+
+    retVal := make([]TemplateDef, 0)
+    for _, n := range numbers {
+        tmp := templates[uint8(n)]
+        def := TemplateDef{Number: uint8(n), Name: tmp.Name(), Segments: make([]TemplateSegmentDef, 0)}
+        for i, f := range tmp {
+            if f.IsSegmentName() {
+                sName := f.NameTrim()
+                if i == 0 {
+                    sName = "BASE"
+                }
+                def.Segments = append(def.Segments, TemplateSegmentDef{Name: sName, Fields: make([]TemplateFieldDef, 0)})
+                continue
+            }
+            if len(def.Segments) == 0 {
+                common.Log.Warning("Template %s doesn't start with segment name field", tmp.Name())
+                def.Segments = append(def.Segments, TemplateSegmentDef{Name: "BASE", Fields: make([]TemplateFieldDef, 0)})
+            }
+            s := &def.Segments[len(def.Segments)-1]
+            s.Fields = append(s.Fields, NewTemplateFieldDef(&tmp, s.Name, f))
+        }
+        retVal = append(retVal, def)
+    }
+    return retVal
+}
+
+// Save template definitions as JSON file
+func ToTemplates(templates map[uint8]sections.Template, fileName string, icb *sections.ICB) {
+    f, err := createOutput(fileName)
+    if err != nil {
+        common.Fatal(fmt.Errorf("Can not create template file: %v", err))
+    }
+    defer f.Close()
+
+    common.Log.Info("Saving RACF templates as JSON file %s", fileName)
+    export := TemplateExport{
+        Level:     strings.TrimSpace(icb.ICBTMPLV.String()),
+        Version:   strings.TrimSpace(icb.ICBVRMN.String()),
+        Templates: NewTemplateDefs(templates),
+    }
+    enc := json.NewEncoder(f)
+    enc.SetIndent("", "  ")
+    if err := enc.Encode(&export); err != nil {
+        common.Fatal(fmt.Errorf("Can not save template file: %v", err))
+    }
+}
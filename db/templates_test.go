@@ -0,0 +1,133 @@
+package db
+
+import (
+    "fmt"
+    "path/filepath"
+    "reflect"
+    "strings"
+    "testing"
+
+    "racfudit/common"
+    "racfudit/sections"
+)
+
+// Create template field with EBCDIC name
+func testTemplateField(name string, id uint8, flag1 uint8, flag2 uint8, length uint32, defaultValue uint8) *sections.TemplateField {
+    return &sections.TemplateField{Name: testEBCDIC(fmt.Sprintf("%-8s", name)), ID: id, Flag1: flag1, Flag2: flag2, Len: length, DefaultValue: defaultValue}
+}
+
+// Template with a repeat group, a combination field, a 3-byte date field and two segments
+func testTemplates() map[uint8]sections.Template {
+    return map[uint8]sections.Template{
+        1: {
+            testTemplateField("GROUP", 1, 0, 0, 0, 0),
+            testTemplateField("SUPGROUP", 2, 0, 0, 8, 0),
+            testTemplateField("CREADATE", 3, 0, 0x20, 3, 0),
+            testTemplateField("SUBGRPCT", 4, 0x10, 0, 4, 0),
+            testTemplateField("SUBGRPNM", 5, 0x80, 0, 8, 0),
+            testTemplateField("FLAG1", 6, 0x20, 0, 1, 0),
+            testTemplateField("PASSWORD", 7, 0x04, 0, 8, 0),
+            testTemplateField("GRPINFO", 8, 0x40, 0, 0x00000203, 6),
+            testTemplateField("DFP", 1, 0, 0, 0, 0),
+            testTemplateField("DATAAPPL", 2, 0, 0, 8, 0),
+        },
+    }
+}
+
+func TestNewTemplateDefs(t *testing.T) {
+    defs := NewTemplateDefs(testTemplates())
+    if len(defs) != 1 || defs[0].Number != 1 || defs[0].Name != "GROUP" {
+        t.Fatalf("Template definitions = %+v", defs)
+    }
+    segments := defs[0].Segments
+    if len(segments) != 2 || segments[0].Name != "BASE" || segments[1].Name != "DFP" {
+        t.Fatalf("Segments = %+v, want BASE and DFP", segments)
+    }
+    if len(segments[0].Fields) != 7 || len(segments[1].Fields) != 1 {
+        t.Errorf("Segments have %d and %d fields, want 7 and 1", len(segments[0].Fields), len(segments[1].Fields))
+    }
+
+    fields := make(map[string]TemplateFieldDef)
+    for _, s := range segments {
+        for _, f := range s.Fields {
+            fields[s.Name+"."+f.Name] = f
+        }
+    }
+    for _, want := range []TemplateFieldDef{
+        {ID: 2, Name: "SUPGROUP", Length: 8, GoType: "decode.EBCDICStr"},
+        {ID: 3, Name: "CREADATE", Flag2: 0x20, Date3: true, Length: 3, GoType: "decode.Date"},
+        {ID: 4, Name: "SUBGRPCT", Flag1: 0x10, RepeatGroup: true, Length: 4, GoType: "uint32"},
+        {ID: 5, Name: "SUBGRPNM", Flag1: 0x80, RepeatGroupMember: true, Length: 8, GoType: "decode.EBCDICStr"},
+        {ID: 6, Name: "FLAG1", Flag1: 0x20, FlagByte: true, Length: 1, GoType: "decode.Flag"},
+        {ID: 7, Name: "PASSWORD", Flag1: 0x04, Encrypted: true, Length: 8, GoType: "decode.HexStr"},
+        {ID: 8, Name: "GRPINFO", Flag1: 0x40, Combination: true, Length: 0x00000203, DefaultValue: 6, CombinationIDs: []uint8{2, 3, 6}},
+    } {
+        if got := fields["BASE."+want.Name]; !reflect.DeepEqual(got, want) {
+            t.Errorf("Field %s = %+v, want %+v", want.Name, got, want)
+        }
+    }
+    if got := fields["DFP.DATAAPPL"]; got.ID != 2 || got.GoType != "decode.EBCDICStr" {
+        t.Errorf("Field DFP.DATAAPPL = %+v", got)
+    }
+}
+
+func TestNewTemplateDefsWithoutSegmentName(t *testing.T) {
+    var log strings.Builder
+    defer func(l *common.Logger) { common.Log = l }(common.Log)
+    common.Log = common.NewLogger(&log)
+
+    defs := NewTemplateDefs(map[uint8]sections.Template{
+        2: {testTemplateField("UNNAMED", 2, 0, 0, 4, 0), testTemplateField("FIELD", 3, 0, 0, 8, 0)},
+    })
+    if !strings.Contains(log.String(), "WARNING: Template UNNAMED doesn't start with segment name field") {
+        t.Errorf("Log = %q, want warning about segment name field", log.String())
+    }
+    if len(defs) != 1 || len(defs[0].Segments) != 1 || defs[0].Segments[0].Name != "BASE" {
+        t.Fatalf("Template definitions = %+v, want fields in BASE segment", defs)
+    }
+    if fields := defs[0].Segments[0].Fields; len(fields) != 2 || fields[0].GoType != "uint32" {
+        t.Errorf("Fields = %+v", fields)
+    }
+}
+
+func TestSQLiteFillTemplates(t *testing.T) {
+    d, err := NewDBSQLite(filepath.Join(t.TempDir(), "racf.db"))
+    if err != nil {
+        t.Fatal(err)
+    }
+    defer d.Close()
+    if err := d.FillTemplates(testTemplates()); err != nil {
+        t.Fatal(err)
+    }
+
+    var count int
+    if err := d.db.QueryRow(`SELECT count(*) FROM TEMPLATE_FIELDS`).Scan(&count); err != nil {
+        t.Fatal(err)
+    }
+    if count != 8 {
+        t.Errorf("TEMPLATE_FIELDS has %d rows, want 8", count)
+    }
+    for _, tc := range []struct {
+        segment string
+        field   string
+        want    []interface{}
+    }{
+        {"BASE", "GRPINFO", []interface{}{int64(1), "GROUP", int64(8), int64(1), int64(0), "", "2,3,6"}},
+        {"BASE", "SUBGRPCT", []interface{}{int64(1), "GROUP", int64(4), int64(0), int64(1), "uint32", ""}},
+        {"DFP", "DATAAPPL", []interface{}{int64(1), "GROUP", int64(2), int64(0), int64(0), "decode.EBCDICStr", ""}},
+    } {
+        got := make([]interface{}, 7)
+        ptrs := make([]interface{}, len(got))
+        for i := range got {
+            ptrs[i] = &got[i]
+        }
+        row := d.db.QueryRow(`SELECT "TemplateNumber", "Template", "FieldID", "Combination", "RepeatGroup", "GoType", "CombinationIDs"
+            FROM TEMPLATE_FIELDS WHERE "Segment" = ? AND "FieldName" = ?`, tc.segment, tc.field)
+        if err := row.Scan(ptrs...); err != nil {
+            t.Fatalf("%s.%s: %v", tc.segment, tc.field, err)
+        }
+        if !reflect.DeepEqual(got, tc.want) {
+            t.Errorf("Row of %s.%s = %#v, want %#v", tc.segment, tc.field, got, tc.want)
+        }
+    }
+}
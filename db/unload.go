@@ -0,0 +1,474 @@
+package db
+
+import (
+    "bufio"
+    "bytes"
+    "fmt"
+    "os"
+    "strings"
+
+    "racfudit/common"
+    "racfudit/decode"
+)
+
+// IRRDBU00 unload record types produced from runtime DB
+const (
+    UNLOAD_GPBD   = "0100" // Group basic data
+    UNLOAD_GPSGRP = "0101" // Group subgroups
+    UNLOAD_GPMEM  = "0102" // Group members
+    UNLOAD_GPDFP  = "0110" // Group DFP data
+    UNLOAD_GPOMVS = "0120" // Group OMVS data
+    UNLOAD_USBD   = "0200" // User basic data
+    UNLOAD_USGCON = "0203" // User group connections
+    UNLOAD_USCON  = "0205" // User connect data
+    UNLOAD_USDFP  = "0210" // User DFP data
+    UNLOAD_USTSO  = "0220" // User TSO data
+    UNLOAD_USLAN  = "0240" // User LANGUAGE data
+    UNLOAD_USOMVS = "0270" // User OMVS data
+    UNLOAD_DSBD   = "0400" // Data set basic data
+    UNLOAD_DSACC  = "0404" // Data set access
+    UNLOAD_DSCACC = "0405" // Data set conditional access
+    UNLOAD_GRBD   = "0500" // General resource basic data
+    UNLOAD_GRACC  = "0505" // General resource access
+    UNLOAD_GRCACC = "0507" // General resource conditional access
+)
+
+// Field of unload record placed in columns start-end (1-based, as in IRRDBU00 record formats)
+type unloadField struct {
+    start int
+    end   int
+    value string
+}
+
+// Create fixed-column unload record. Values are left-justified and truncated to field width
+func unloadRecord(fields ...unloadField) string {
+    var size int
+    for _, f := range fields {
+        if f.end > size {
+            size = f.end
+        }
+    }
+    record := bytes.Repeat([]byte(" "), size)
+    for _, f := range fields {
+        value := f.value
+        if len(value) > f.end-f.start+1 {
+            value = value[:f.end-f.start+1]
+        }
+        copy(record[f.start-1:], value)
+    }
+    return string(record)
+}
+
+// Get character field of the segment (item) without trailing blanks
+func unloadString(s *Segment, name string) string {
+    var str decode.EBCDICStr = s.FieldBytes(name)
+    return str.Trim()
+}
+
+// Format date field as YYYY-MM-DD (blank if the date isn't set)
+func unloadDate(s *Segment, name string) string {
+    var d decode.Date = s.FieldBytes(name)
+    if t, ok := d.Time(); ok {
+        return t.Format("2006-01-02")
+    }
+    return ""
+}
+
+// Format time field as HH:MM:SS (blank if the time isn't set)
+func unloadTime(s *Segment, name string) string {
+    b := s.FieldBytes(name)
+    if len(b) < 3 || bytes.Count(b, []byte{0}) == len(b) {
+        return ""
+    }
+    return fmt.Sprintf("%02x:%02x:%02x", b[0], b[1], b[2])
+}
+
+// Format integer field with leading zeros. A value wider than the field is saturated (e.g. 999 for width 3)
+func unloadNum(s *Segment, name string, width int) string {
+    return unloadNumber(s.FieldUint(name), name, width)
+}
+
+func unloadNumber(value uint64, name string, width int) string {
+    retVal := fmt.Sprintf("%0*d", width, value)
+    if len(retVal) > width {
+        common.Log.Error("Value %s of field %s doesn't fit into %d columns of unload record, it is saved as %s",
+            retVal, name, width, strings.Repeat("9", width))
+        return strings.Repeat("9", width)
+    }
+    return retVal
+}
+
+// Format flag bit as YES or NO
+func unloadYesNo(s *Segment, name string, mask byte) string {
+    if b := s.FieldBytes(name); len(b) > 0 && b[0]&mask != 0 {
+        return "YES"
+    }
+    return "NO"
+}
+
+// Get access name of the access byte field
+func unloadAccess(s *Segment, name string) string {
+    if b := s.FieldBytes(name); len(b) > 0 {
+        return AccessName(b[0])
+    }
+    return AccessName(0)
+}
+
+// Get audit level of AUDIT or GAUDIT flag byte
+func unloadAudit(s *Segment, name string) string {
+    b := s.FieldBytes(name)
+    switch {
+    case len(b) == 0:
+        return ""
+    case b[0]&0x80 != 0:
+        return "ALL"
+    case b[0]&0x40 != 0:
+        return "SUCCESS"
+    case b[0]&0x20 != 0:
+        return "FAIL"
+    }
+    return "NONE"
+}
+
+// DFP data record of user or group (USDFP and GPDFP have the same layout)
+func unloadDFP(recordType string, name string, s *Segment) string {
+    return unloadRecord(
+        unloadField{1, 4, recordType},
+        unloadField{6, 13, name},
+        unloadField{15, 22, unloadString(s, "DATAAPPL")},
+        unloadField{24, 31, unloadString(s, "DATACLAS")},
+        unloadField{33, 40, unloadString(s, "MGMTCLAS")},
+        unloadField{42, 49, unloadString(s, "STORCLAS")},
+    )
+}
+
+func unloadGroup(p *Profile) []string {
+    retVal := make([]string, 0)
+    name := strings.TrimSpace(p.Name)
+    if s, ok := p.Segment("BASE"); ok {
+        retVal = append(retVal, unloadRecord(
+            unloadField{1, 4, UNLOAD_GPBD},
+            unloadField{6, 13, name},
+            unloadField{15, 22, unloadString(s, "SUPGROUP")},
+            unloadField{24, 33, unloadDate(s, "AUTHDATE")},
+            unloadField{35, 42, unloadString(s, "AUTHOR")},
+            unloadField{44, 51, unloadAccess(s, "UACC")},
+            unloadField{53, 56, unloadYesNo(s, "NOTRMUAC", 0x80)},
+            unloadField{58, 312, unloadString(s, "INSTDATA")},
+            unloadField{314, 357, unloadString(s, "MODELNAM")},
+            unloadField{359, 362, unloadYesNo(s, "UNVFLG", 0x80)},
+        ))
+        for _, item := range s.Items("SUBGRPCT") {
+            if subgroup := unloadString(item, "SUBGRPNM"); len(subgroup) > 0 {
+                retVal = append(retVal, unloadRecord(
+                    unloadField{1, 4, UNLOAD_GPSGRP},
+                    unloadField{6, 13, name},
+                    unloadField{15, 22, subgroup},
+                ))
+            }
+        }
+        for _, item := range s.Items("ACLCNT") {
+            retVal = append(retVal, unloadRecord(
+                unloadField{1, 4, UNLOAD_GPMEM},
+                unloadField{6, 13, name},
+                unloadField{15, 22, unloadString(item, "USERID")},
+                unloadField{24, 31, GroupAuthority(item.FieldBytes("USERACS"))},
+            ))
+        }
+    }
+    if s, ok := p.Segment("DFP"); ok {
+        retVal = append(retVal, unloadDFP(UNLOAD_GPDFP, name, s))
+    }
+    if s, ok := p.Segment("OMVS"); ok {
+        retVal = append(retVal, unloadRecord(
+            unloadField{1, 4, UNLOAD_GPOMVS},
+            unloadField{6, 13, name},
+            unloadField{15, 24, unloadNum(s, "GID", 10)},
+        ))
+    }
+    return retVal
+}
+
+func unloadUser(p *Profile) []string {
+    retVal := make([]string, 0)
+    name := strings.TrimSpace(p.Name)
+    if s, ok := p.Segment("BASE"); ok {
+        retVal = append(retVal, unloadRecord(
+            unloadField{1, 4, UNLOAD_USBD},
+            unloadField{6, 13, name},
+            unloadField{15, 24, unloadDate(s, "AUTHDATE")},
+            unloadField{26, 33, unloadString(s, "AUTHOR")},
+            unloadField{35, 38, unloadYesNo(s, "FLAG1", 0x80)},
+            unloadField{40, 43, unloadYesNo(s, "FLAG2", 0x80)},
+            unloadField{45, 48, unloadYesNo(s, "FLAG3", 0x80)},
+            unloadField{50, 53, unloadYesNo(s, "FLAG4", 0x80)},
+            unloadField{55, 58, unloadYesNo(s, "FLAG5", 0x80)},
+            unloadField{60, 62, unloadNum(s, "PASSINT", 3)},
+            unloadField{64, 73, unloadDate(s, "PASSDATE")},
+            unloadField{75, 94, unloadString(s, "PGMRNAME")},
+            unloadField{96, 103, unloadString(s, "DFLTGRP")},
+            unloadField{105, 112, unloadTime(s, "LJTIME")},
+            unloadField{114, 123, unloadDate(s, "LJDATE")},
+            unloadField{125, 379, unloadString(s, "INSTDATA")},
+            unloadField{381, 384, unloadYesNo(s, "UAUDIT", 0x80)},
+            unloadField{386, 389, unloadYesNo(s, "FLAG6", 0x80)},
+            unloadField{391, 394, unloadYesNo(s, "FLAG7", 0x80)},
+            unloadField{396, 399, unloadYesNo(s, "FLAG8", 0x80)},
+            unloadField{401, 403, unloadNum(s, "PWDGEN", 3)},
+            unloadField{405, 407, unloadNum(s, "REVOKECT", 3)},
+            unloadField{409, 452, unloadString(s, "MODELNAM")},
+            unloadField{454, 456, unloadNum(s, "SECLEVEL", 3)},
+            unloadField{458, 467, unloadDate(s, "REVOKEDT")},
+            unloadField{469, 478, unloadDate(s, "RESUMEDT")},
+        ))
+        for _, item := range s.Items("CGGRPCT") {
+            group := unloadString(item, "CGGRPNM")
+            retVal = append(retVal, unloadRecord(
+                unloadField{1, 4, UNLOAD_USGCON},
+                unloadField{6, 13, name},
+                unloadField{15, 22, group},
+            ))
+            retVal = append(retVal, unloadRecord(
+                unloadField{1, 4, UNLOAD_USCON},
+                unloadField{6, 13, name},
+                unloadField{15, 22, group},
+                unloadField{24, 33, unloadDate(item, "CGAUTHDA")},
+                unloadField{35, 42, unloadString(item, "CGAUTHOR")},
+                unloadField{44, 51, unloadTime(item, "CGLJTIME")},
+                unloadField{53, 62, unloadDate(item, "CGLJDATE")},
+                unloadField{64, 71, unloadAccess(item, "CGUACC")},
+                unloadField{73, 77, unloadNum(item, "CGINITCT", 5)},
+                unloadField{79, 82, unloadYesNo(item, "CGFLAG1", 0x80)},
+                unloadField{84, 87, unloadYesNo(item, "CGFLAG2", 0x80)},
+                unloadField{89, 92, unloadYesNo(item, "CGFLAG3", 0x80)},
+                unloadField{94, 97, unloadYesNo(item, "CGFLAG4", 0x80)},
+                unloadField{99, 102, unloadYesNo(item, "CGFLAG5", 0x80)},
+                unloadField{104, 107, unloadYesNo(item, "CGNOTUAC", 0x80)},
+                unloadField{109, 112, unloadYesNo(item, "CGGRPAUD", 0x80)},
+                unloadField{114, 123, unloadDate(item, "CGREVKDT")},
+                unloadField{125, 134, unloadDate(item, "CGRESMDT")},
+            ))
+        }
+    }
+    if s, ok := p.Segment("DFP"); ok {
+        retVal = append(retVal, unloadDFP(UNLOAD_USDFP, name, s))
+    }
+    if s, ok := p.Segment("TSO"); ok {
+        retVal = append(retVal, unloadRecord(
+            unloadField{1, 4, UNLOAD_USTSO},
+            unloadField{6, 13, name},
+            unloadField{15, 54, unloadString(s, "TACCNT")},
+            unloadField{56, 135, unloadString(s, "TCOMMAND")},
+            unloadField{137, 144, unloadString(s, "TDEST")},
+            unloadField{146, 146, unloadString(s, "THCLASS")},
+            unloadField{148, 148, unloadString(s, "TJCLASS")},
+            unloadField{150, 157, unloadString(s, "TLPROC")},
+            unloadField{159, 168, unloadNum(s, "TLSIZE", 10)},
+            unloadField{170, 170, unloadString(s, "TMCLASS")},
+            unloadField{172, 181, unloadNum(s, "TMSIZE", 10)},
+            unloadField{183, 192, unloadNum(s, "TPERFORM", 10)},
+            unloadField{194, 194, unloadString(s, "TSCLASS")},
+            unloadField{196, 203, fmt.Sprintf("%x", s.FieldBytes("TUDATA"))},
+            unloadField{205, 212, unloadString(s, "TUNIT")},
+            unloadField{214, 221, unloadString(s, "TSOSLABL")},
+        ))
+    }
+    if s, ok := p.Segment("LANGUAGE"); ok {
+        retVal = append(retVal, unloadRecord(
+            unloadField{1, 4, UNLOAD_USLAN},
+            unloadField{6, 13, name},
+            unloadField{15, 17, unloadString(s, "USERNL1")},
+            unloadField{19, 21, unloadString(s, "USERNL2")},
+        ))
+    }
+    if s, ok := p.Segment("OMVS"); ok {
+        retVal = append(retVal, unloadRecord(
+            unloadField{1, 4, UNLOAD_USOMVS},
+            unloadField{6, 13, name},
+            unloadField{15, 24, unloadNum(s, "UID", 10)},
+            unloadField{26, 1048, unloadString(s, "HOME")},
+            unloadField{1050, 2072, unloadString(s, "PROGRAM")},
+            unloadField{2074, 2083, unloadNum(s, "CPUTIME", 10)},
+            unloadField{2085, 2094, unloadNum(s, "ASSIZE", 10)},
+            unloadField{2096, 2105, unloadNum(s, "FILEPROC", 10)},
+            unloadField{2107, 2116, unloadNum(s, "PROCUSER", 10)},
+            unloadField{2118, 2127, unloadNum(s, "THREADS", 10)},
+            unloadField{2129, 2138, unloadNum(s, "MMAPAREA", 10)},
+        ))
+    }
+    return retVal
+}
+
+// Get data set type of DSTYPE flag byte
+func unloadDSType(s *Segment) string {
+    b := s.FieldBytes("DSTYPE")
+    switch {
+    case len(b) == 0:
+        return "NONVSAM"
+    case b[0]&0x80 != 0:
+        return "VSAM"
+    case b[0]&0x40 != 0:
+        return "MODEL"
+    case b[0]&0x20 != 0:
+        return "TAPE"
+    }
+    return "NONVSAM"
+}
+
+func unloadDataset(p *Profile) []string {
+    retVal := make([]string, 0)
+    name := strings.TrimSpace(p.Name)
+    s, ok := p.Segment("BASE")
+    if !ok {
+        return retVal
+    }
+    var volume string
+    if volumes := s.Items("VOLCNT"); len(volumes) > 0 {
+        volume = unloadString(volumes[0], "VOLSER")
+    }
+    generic := "NO"
+    if strings.ContainsAny(name, "*%") {
+        generic = "YES"
+    }
+
+    retVal = append(retVal, unloadRecord(
+        unloadField{1, 4, UNLOAD_DSBD},
+        unloadField{6, 49, name},
+        unloadField{51, 56, volume},
+        unloadField{58, 61, generic},
+        unloadField{63, 72, unloadDate(s, "CREADATE")},
+        unloadField{74, 81, unloadString(s, "AUTHOR")},
+        unloadField{83, 92, unloadDate(s, "LREFDAT")},
+        unloadField{94, 103, unloadDate(s, "LCHGDAT")},
+        unloadField{105, 109, unloadNum(s, "ACSALTR", 5)},
+        unloadField{111, 115, unloadNum(s, "ACSCNTL", 5)},
+        unloadField{117, 121, unloadNum(s, "ACSUPDT", 5)},
+        unloadField{123, 127, unloadNum(s, "ACSREAD", 5)},
+        unloadField{129, 136, unloadAccess(s, "UNIVACS")},
+        unloadField{138, 141, unloadYesNo(s, "FLAG1", 0x80)},
+        unloadField{143, 150, unloadAudit(s, "AUDIT")},
+        unloadField{152, 159, unloadString(s, "GROUPNM")},
+        unloadField{161, 168, unloadDSType(s)},
+        unloadField{170, 172, unloadNum(s, "LEVEL", 3)},
+        unloadField{174, 181, unloadString(s, "DEVTYPX")},
+        unloadField{183, 190, unloadAudit(s, "GAUDIT")},
+        unloadField{192, 446, unloadString(s, "INSTDATA")},
+    ))
+    for _, item := range s.Items("ACLCNT") {
+        retVal = append(retVal, unloadRecord(
+            unloadField{1, 4, UNLOAD_DSACC},
+            unloadField{6, 49, name},
+            unloadField{51, 56, volume},
+            unloadField{58, 65, unloadString(item, "USERID")},
+            unloadField{67, 74, unloadAccess(item, "USERACS")},
+            unloadField{76, 80, unloadNum(item, "ACSCNT", 5)},
+        ))
+    }
+    for _, e := range p.ConditionalACL() {
+        retVal = append(retVal, unloadRecord(
+            unloadField{1, 4, UNLOAD_DSCACC},
+            unloadField{6, 49, name},
+            unloadField{51, 56, volume},
+            unloadField{58, 65, e.EntityClass},
+            unloadField{67, 74, e.Entity},
+            unloadField{76, 83, e.ID},
+            unloadField{85, 92, e.Access},
+            unloadField{94, 98, unloadNumber(e.Count, "PACSCNT", 5)},
+        ))
+    }
+    return retVal
+}
+
+func unloadGeneral(p *Profile) []string {
+    retVal := make([]string, 0)
+    name := p.ResourceName()
+    class := p.Class()
+    s, ok := p.Segment("BASE")
+    if !ok {
+        return retVal
+    }
+    generic := "NO"
+    if strings.ContainsAny(name, "*%") {
+        generic = "YES"
+    }
+
+    retVal = append(retVal, unloadRecord(
+        unloadField{1, 4, UNLOAD_GRBD},
+        unloadField{6, 251, name},
+        unloadField{253, 260, class},
+        unloadField{262, 265, generic},
+        unloadField{267, 269, unloadNum(s, "CLASTYPE", 3)},
+        unloadField{271, 280, unloadDate(s, "DEFDATE")},
+        unloadField{282, 289, unloadString(s, "OWNER")},
+        unloadField{291, 300, unloadDate(s, "LREFDAT")},
+        unloadField{302, 311, unloadDate(s, "LCHGDAT")},
+        unloadField{313, 317, unloadNum(s, "ACSALTR", 5)},
+        unloadField{319, 323, unloadNum(s, "ACSCNTL", 5)},
+        unloadField{325, 329, unloadNum(s, "ACSUPDT", 5)},
+        unloadField{331, 335, unloadNum(s, "ACSREAD", 5)},
+        unloadField{337, 344, unloadAccess(s, "UACC")},
+        unloadField{346, 353, unloadAudit(s, "AUDIT")},
+        unloadField{355, 357, unloadNum(s, "LEVEL", 3)},
+        unloadField{359, 366, unloadAudit(s, "GAUDIT")},
+        unloadField{368, 622, unloadString(s, "INSTDATA")},
+    ))
+    for _, item := range s.Items("ACLCNT") {
+        retVal = append(retVal, unloadRecord(
+            unloadField{1, 4, UNLOAD_GRACC},
+            unloadField{6, 251, name},
+            unloadField{253, 260, class},
+            unloadField{262, 269, unloadString(item, "USERID")},
+            unloadField{271, 278, unloadAccess(item, "USERACS")},
+            unloadField{280, 284, unloadNum(item, "ACSCNT", 5)},
+        ))
+    }
+    for _, e := range p.ConditionalACL() {
+        retVal = append(retVal, unloadRecord(
+            unloadField{1, 4, UNLOAD_GRCACC},
+            unloadField{6, 251, name},
+            unloadField{253, 260, class},
+            unloadField{262, 269, e.EntityClass},
+            unloadField{271, 278, e.Entity},
+            unloadField{280, 287, e.ID},
+            unloadField{289, 296, e.Access},
+            unloadField{298, 302, unloadNumber(e.Count, "ACL2ACNT", 5)},
+        ))
+    }
+    return retVal
+}
+
+// Get IRRDBU00 unload records of the profile
+func UnloadProfile(p *Profile) []string {
+    switch p.Type.Name {
+    case "GROUP":
+        return unloadGroup(p)
+    case "USER":
+        return unloadUser(p)
+    case "DATASET":
+        return unloadDataset(p)
+    case "GENERAL":
+        return unloadGeneral(p)
+    }
+    return []string{}
+}
+
+// Save runtime DB as IRRDBU00-style unload file (records follow the profile order of RACF DB index)
+func ToUnload(profiles []*Profile, fileName string) {
+    f, err := os.Create(fileName)
+    if err != nil {
+        common.Fatal(fmt.Errorf("Can not create unload file: %v", err))
+    }
+    defer f.Close()
+
+    common.Log.Info("Saving RACF profiles as IRRDBU00 unload file %s", fileName)
+    w := bufio.NewWriter(f)
+    for _, p := range profiles {
+        common.Log.Debug("Unloading profile: %q (%v)\n", p.Name, &p.Type)
+        for _, r := range UnloadProfile(p) {
+            fmt.Fprintln(w, r)
+        }
+    }
+    if err := w.Flush(); err != nil {
+        common.Fatal(fmt.Errorf("Can not save unload file: %v", err))
+    }
+}
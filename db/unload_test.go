@@ -0,0 +1,148 @@
+package db
+
+import (
+    "reflect"
+    "strings"
+    "testing"
+
+    "racfudit/decode"
+)
+
+func TestUnloadNumSaturates(t *testing.T) {
+    sT := reflect.StructOf([]reflect.StructField{{Name: "PASSINT", Type: reflect.TypeOf(uint32(0))}})
+    for _, tc := range []struct {
+        value uint32
+        width int
+        want  string
+    }{
+        {30, 3, "030"},
+        {999, 3, "999"},
+        {1000, 3, "999"},
+        {4294967295, 10, "4294967295"},
+        {4294967295, 5, "99999"},
+    } {
+        v := reflect.New(sT)
+        v.Elem().Field(0).SetUint(uint64(tc.value))
+        s := NewSegment("BASE", 1, 0, 0, 0, "", &v)
+        if got := unloadNum(s, "PASSINT", tc.width); got != tc.want {
+            t.Errorf("unloadNum(%d, %d) = %s, want %s", tc.value, tc.width, got, tc.want)
+        }
+    }
+}
+
+func TestUnloadUserSegments(t *testing.T) {
+    p := NewProfile("IBMUSER", "USER", 2)
+    testSegment(p, "DFP", testSegmentType("DATAAPPL", "DATACLAS", "MGMTCLAS", "STORCLAS"),
+        map[string]string{"DATAAPPL": "APPL1", "DATACLAS": "DC1", "MGMTCLAS": "MC1", "STORCLAS": "SC1"})
+    testSegment(p, "LANGUAGE", testSegmentType("USERNL1", "USERNL2"), map[string]string{"USERNL1": "ENU", "USERNL2": "DEU"})
+    testSegment(p, "WORKATTR", testSegmentType("WANAME"), map[string]string{"WANAME": "JOHN"})
+
+    want := []string{
+        "0210 IBMUSER  APPL1    DC1      MC1      SC1     ",
+        "0240 IBMUSER  ENU DEU",
+    }
+    if got := UnloadProfile(p); !reflect.DeepEqual(got, want) {
+        t.Errorf("UnloadProfile() = %q, want %q", got, want)
+    }
+}
+
+// Check values of unload record at their columns (1-based start column -> value)
+func testUnloadColumns(t *testing.T, record string, columns map[int]string) {
+    t.Helper()
+    for start, want := range columns {
+        end := start - 1 + len(want)
+        if end > len(record) {
+            t.Errorf("Record %.4s is %d columns long, want value %q at column %d", record, len(record), want, start)
+            continue
+        }
+        if got := record[start-1 : end]; got != want {
+            t.Errorf("Record %.4s: columns %d-%d = %q, want %q", record, start, end, got, want)
+        }
+    }
+}
+
+func TestUnloadRecordColumns(t *testing.T) {
+    date := decode.Date{0x24, 0x00, 0x2f}
+
+    user := NewProfile("IBMUSER", "USER", 2)
+    testSegmentOf(user, "BASE", testFields{
+        {"AUTHDATE", date},
+        {"AUTHOR", "SYS1"},
+        {"FLAG1", decode.Flag{0x80}},
+        {"PASSINT", uint8(30)},
+        {"PGMRNAME", "JOHN SMITH"},
+        {"DFLTGRP", "SYS1"},
+        {"INSTDATA", "INSTALLATION DATA"},
+        {"REVOKECT", uint8(2)},
+        {"CGGRPCT", []testFields{{
+            {"CGGRPNM", "SYS1"},
+            {"CGAUTHDA", date},
+            {"CGAUTHOR", "IBMUSER"},
+            {"CGUACC", decode.Flag{0x10}},
+            {"CGINITCT", uint32(42)},
+            {"CGFLAG1", decode.Flag{0x80}},
+        }}},
+    })
+
+    dataset := NewProfile("SYS1.**", "DATASET", 4)
+    testSegmentOf(dataset, "BASE", testFields{
+        {"CREADATE", date},
+        {"AUTHOR", "IBMUSER"},
+        {"ACSREAD", uint32(5)},
+        {"UNIVACS", decode.Flag{0x10}},
+        {"AUDIT", decode.Flag{0x20}},
+        {"GROUPNM", "SYS1"},
+        {"DSTYPE", decode.Flag{0x80}},
+        {"INSTDATA", "SYSTEM DATA SETS"},
+        {"VOLCNT", []testFields{{{"VOLSER", "VOL001"}}}},
+        {"ACLCNT", []testFields{{{"USERID", "SYSPROG"}, {"USERACS", decode.Flag{0x80}}, {"ACSCNT", uint32(7)}}}},
+        {"ACL2CNT", []testFields{{{"USER2ACS", "JOHN"}, {"PROGRAM", "PAYROLL"}, {"PROGACS", decode.Flag{0x20}}, {"PACSCNT", uint32(3)}}}},
+    })
+
+    general := NewProfile("FACILITYBPX.SUPERUSER", "GENERAL", 5)
+    testSegmentOf(general, "BASE", testFields{
+        {"CLASTYPE", uint8(5)},
+        {"DEFDATE", date},
+        {"OWNER", "SYS1"},
+        {"UACC", decode.Flag{0x00}},
+        {"LEVEL", uint8(1)},
+        {"INSTDATA", "SUPERUSER"},
+        {"ACLCNT", []testFields{{{"USERID", "SYSPROG"}, {"USERACS", decode.Flag{0x10}}, {"ACSCNT", uint32(9)}}}},
+        {"ACL2CNT", []testFields{{{"ACL2UID", "OPER"}, {"ACL2NAME", "CONSOLE"}, {"ACL2VAR", "MASTER"}, {"ACL2ACC", decode.Flag{0x10}}, {"ACL2ACNT", uint32(1)}}}},
+    })
+
+    records := make(map[string]string)
+    for _, p := range []*Profile{user, dataset, general} {
+        for _, r := range UnloadProfile(p) {
+            records[r[:4]] = r
+        }
+    }
+    for _, tc := range []struct {
+        recordType string
+        columns    map[int]string
+    }{
+        {UNLOAD_USBD, map[int]string{1: "0200 ", 6: "IBMUSER  ", 15: "2024-01-02 ", 26: "SYS1     ", 35: "YES  NO  ", 60: "030 ",
+            75: "JOHN SMITH", 96: "SYS1     ", 125: "INSTALLATION DATA", 405: "002 "}},
+        {UNLOAD_USGCON, map[int]string{1: "0203 ", 6: "IBMUSER  ", 15: "SYS1"}},
+        {UNLOAD_USCON, map[int]string{1: "0205 ", 6: "IBMUSER  ", 15: "SYS1     ", 24: "2024-01-02 ", 35: "IBMUSER  ",
+            64: "READ     ", 73: "00042 ", 79: "YES  NO  "}},
+        {UNLOAD_DSBD, map[int]string{1: "0400 ", 6: "SYS1.**" + strings.Repeat(" ", 38), 51: "VOL001 ", 58: "YES  ",
+            63: "2024-01-02 ", 74: "IBMUSER  ", 105: "00000 00000 00000 00005 ", 129: "READ     ", 138: "NO   ",
+            143: "FAIL     ", 152: "SYS1     ", 161: "VSAM     ", 192: "SYSTEM DATA SETS"}},
+        {UNLOAD_DSACC, map[int]string{1: "0404 ", 6: "SYS1.** ", 51: "VOL001 ", 58: "SYSPROG  ", 67: "ALTER    ", 76: "00007"}},
+        {UNLOAD_DSCACC, map[int]string{1: "0405 ", 6: "SYS1.** ", 51: "VOL001 ", 58: "PROGRAM  ", 67: "PAYROLL  ",
+            76: "JOHN     ", 85: "UPDATE   ", 94: "00003"}},
+        {UNLOAD_GRBD, map[int]string{1: "0500 ", 6: "BPX.SUPERUSER ", 253: "FACILITY ", 262: "NO   ", 267: "005 ",
+            271: "2024-01-02 ", 282: "SYS1     ", 337: "NONE     ", 355: "001 ", 368: "SUPERUSER"}},
+        {UNLOAD_GRACC, map[int]string{1: "0505 ", 6: "BPX.SUPERUSER ", 253: "FACILITY ", 262: "SYSPROG  ", 271: "READ     ", 280: "00009"}},
+        {UNLOAD_GRCACC, map[int]string{1: "0507 ", 6: "BPX.SUPERUSER ", 253: "FACILITY ", 262: "CONSOLE  ", 271: "MASTER   ",
+            280: "OPER     ", 289: "READ     ", 298: "00001"}},
+    } {
+        record, ok := records[tc.recordType]
+        if !ok {
+            t.Errorf("Record %s is missing", tc.recordType)
+            continue
+        }
+        testUnloadColumns(t, record, tc.columns)
+    }
+}
@@ -0,0 +1,376 @@
+package db
+
+import (
+    "archive/zip"
+    "fmt"
+    "io"
+    "reflect"
+    "strconv"
+    "strings"
+    "time"
+    "unicode/utf8"
+
+    "racfudit/common"
+    "racfudit/decode"
+)
+
+// Maximum number of rows of Excel worksheet (including header row)
+const XLSX_MAX_ROWS = 1048576
+
+// Maximum length of Excel cell text (characters)
+const XLSX_MAX_TEXT = 32767
+
+// Maximum integer which Excel saves without loss of precision (larger integers are saved as text)
+const XLSX_MAX_NUMBER = 1 << 53
+
+// Styles of cells (indexes of cellXfs in styles.xml)
+const (
+    XLSX_STYLE_DEFAULT = iota
+    XLSX_STYLE_DATE
+    XLSX_STYLE_HEADER
+)
+
+const xlsxStyles = `<?xml version="1.0" encoding="UTF-8" standalone="yes"?>
+<styleSheet xmlns="http://schemas.openxmlformats.org/spreadsheetml/2006/main">
+<numFmts count="1"><numFmt numFmtId="164" formatCode="yyyy\-mm\-dd"/></numFmts>
+<fonts count="2"><font><sz val="11"/><name val="Calibri"/></font><font><b/><sz val="11"/><name val="Calibri"/></font></fonts>
+<fills count="2"><fill><patternFill patternType="none"/></fill><fill><patternFill patternType="gray125"/></fill></fills>
+<borders count="1"><border><left/><right/><top/><bottom/><diagonal/></border></borders>
+<cellStyleXfs count="1"><xf numFmtId="0" fontId="0" fillId="0" borderId="0"/></cellStyleXfs>
+<cellXfs count="3"><xf numFmtId="0" fontId="0" fillId="0" borderId="0" xfId="0"/><xf numFmtId="164" fontId="0" fillId="0" borderId="0" xfId="0" applyNumberFormat="1"/><xf numFmtId="0" fontId="1" fillId="0" borderId="0" xfId="0" applyFont="1"/></cellXfs>
+<cellStyles count="1"><cellStyle name="Normal" xfId="0" builtinId="0"/></cellStyles>
+</styleSheet>
+`
+
+const xlsxRels = `<?xml version="1.0" encoding="UTF-8" standalone="yes"?>
+<Relationships xmlns="http://schemas.openxmlformats.org/package/2006/relationships">
+<Relationship Id="rId1" Type="http://schemas.openxmlformats.org/officeDocument/2006/relationships/officeDocument" Target="xl/workbook.xml"/>
+</Relationships>
+`
+
+// Worksheet of XLSX workbook
+type xlsxSheet struct {
+    Name    string
+    File    string // File name in xl/worksheets
+    Table   *Table // nil for index sheet
+    Rows    int    // Number of data rows (without header)
+    Columns int
+}
+
+// XLSX workbook written to zip archive. Worksheets are written one by one, workbook parts are written on Close
+type xlsxWorkbook struct {
+    zw      *zip.Writer
+    sheets  []*xlsxSheet
+    names   map[string]bool
+    maxRows int        // Maximum number of rows of worksheet (XLSX_MAX_ROWS)
+    w       io.Writer  // Current worksheet
+    sheet   *xlsxSheet // Current worksheet
+}
+
+// Get column name of Excel cell reference (0 - A, 26 - AA)
+func xlsxColumn(i int) string {
+    retVal := ""
+    for i++; i > 0; i = (i - 1) / 26 {
+        retVal = string(rune('A'+(i-1)%26)) + retVal
+    }
+    return retVal
+}
+
+// Get unique worksheet name (at most 31 characters without []:*?/\)
+func (x *xlsxWorkbook) sheetName(name string) string {
+    name = strings.Map(func(r rune) rune {
+        if strings.ContainsRune(`[]:*?/\`, r) {
+            return '_'
+        }
+        return r
+    }, name)
+    if len(name) > 31 {
+        name = name[:31]
+    }
+    retVal := name
+    for i := 2; x.names[strings.ToUpper(retVal)]; i++ {
+        suffix := fmt.Sprintf("~%d", i)
+        if len(name)+len(suffix) > 31 {
+            retVal = name[:31-len(suffix)] + suffix
+        } else {
+            retVal = name + suffix
+        }
+    }
+    x.names[strings.ToUpper(retVal)] = true
+    return retVal
+}
+
+// Quote worksheet name for cell references
+func xlsxSheetRef(name string) string {
+    return "'" + strings.ReplaceAll(name, "'", "''") + "'"
+}
+
+func (x *xlsxWorkbook) create(name string) (io.Writer, error) {
+    return x.zw.CreateHeader(&zip.FileHeader{Name: name, Method: zip.Deflate, Modified: time.Now()})
+}
+
+// Start new worksheet with frozen header row
+func (x *xlsxWorkbook) newSheet(name string, file string, t *Table, header []string) error {
+    if err := x.closeSheet(); err != nil {
+        return err
+    }
+    w, err := x.create("xl/worksheets/" + file)
+    if err != nil {
+        return err
+    }
+    x.w = w
+    x.sheet = &xlsxSheet{Name: x.sheetName(name), File: file, Table: t, Columns: len(header)}
+    x.sheets = append(x.sheets, x.sheet)
+
+    fmt.Fprint(w, `<?xml version="1.0" encoding="UTF-8" standalone="yes"?>`+"\n")
+    fmt.Fprint(w, `<worksheet xmlns="http://schemas.openxmlformats.org/spreadsheetml/2006/main" `+
+        `xmlns:r="http://schemas.openxmlformats.org/officeDocument/2006/relationships">`)
+    fmt.Fprint(w, `<sheetViews><sheetView workbookViewId="0"><pane ySplit="1" topLeftCell="A2" activePane="bottomLeft" state="frozen"/>`+
+        `<selection pane="bottomLeft" activeCell="A2" sqref="A2"/></sheetView></sheetViews>`)
+    fmt.Fprint(w, `<sheetFormatPr defaultRowHeight="15"/><cols>`)
+    for i, h := range header {
+        width := len(h) + 4
+        if width < 10 {
+            width = 10
+        }
+        fmt.Fprintf(w, `<col min="%d" max="%d" width="%d" customWidth="1"/>`, i+1, i+1, width)
+    }
+    fmt.Fprint(w, "</cols><sheetData>\n")
+
+    cells := make([]interface{}, len(header))
+    for i, h := range header {
+        cells[i] = h
+    }
+    return x.writeRow(cells, XLSX_STYLE_HEADER)
+}
+
+// Write row of cells to the current worksheet. Cell values are int64 (number), time.Time (date), string or nil (empty cell)
+func (x *xlsxWorkbook) writeRow(cells []interface{}, style int) error {
+    row := x.sheet.Rows + 2
+    if style == XLSX_STYLE_HEADER {
+        row = 1
+    }
+    var b strings.Builder
+    fmt.Fprintf(&b, `<row r="%d">`, row)
+    for i, v := range cells {
+        ref := fmt.Sprintf("%s%d", xlsxColumn(i), row)
+        switch v := v.(type) {
+        case int64:
+            fmt.Fprintf(&b, `<c r="%s"><v>%d</v></c>`, ref, v)
+        case time.Time:
+            // Serial number of the date (days since 1899-12-30)
+            days := time.Date(v.Year(), v.Month(), v.Day(), 0, 0, 0, 0, time.UTC).Sub(time.Date(1899, 12, 30, 0, 0, 0, 0, time.UTC)).Hours() / 24
+            fmt.Fprintf(&b, `<c r="%s" s="%d"><v>%d</v></c>`, ref, XLSX_STYLE_DATE, int64(days))
+        case string:
+            if n := utf8.RuneCountInString(v); n > XLSX_MAX_TEXT {
+                common.Log.Warning("Value of cell %s of worksheet %s is truncated from %d to %d characters", ref, x.sheet.Name, n, XLSX_MAX_TEXT)
+                v = xlsxTruncate(v, XLSX_MAX_TEXT)
+            }
+            if style != XLSX_STYLE_DEFAULT {
+                fmt.Fprintf(&b, `<c r="%s" s="%d" t="inlineStr">`, ref, style)
+            } else {
+                fmt.Fprintf(&b, `<c r="%s" t="inlineStr">`, ref)
+            }
+            fmt.Fprintf(&b, `<is><t xml:space="preserve">%s</t></is></c>`, xmlEscape(v))
+        }
+    }
+    b.WriteString("</row>\n")
+    if _, err := io.WriteString(x.w, b.String()); err != nil {
+        return err
+    }
+    if style != XLSX_STYLE_HEADER {
+        x.sheet.Rows++
+    }
+    return nil
+}
+
+// Get the first n characters of the string
+func xlsxTruncate(s string, n int) string {
+    for i := range s {
+        if n == 0 {
+            return s[:i]
+        }
+        n--
+    }
+    return s
+}
+
+// Finish the current worksheet (autofilter covers header and all data rows)
+func (x *xlsxWorkbook) closeSheet() error {
+    if x.sheet == nil {
+        return nil
+    }
+    _, err := fmt.Fprintf(x.w, `</sheetData><autoFilter ref="A1:%s%d"/></worksheet>`, xlsxColumn(x.sheet.Columns-1), x.sheet.Rows+1)
+    x.w, x.sheet = nil, nil
+    return err
+}
+
+// Write worksheets of the table. Table is split into several worksheets (<TABLE>~2, ...) if it exceeds Excel row limit
+func (x *xlsxWorkbook) writeTable(t *Table, profiles []*Profile) error {
+    header := []string{"ProfileName"}
+    if len(t.RepeatGroup) == 0 {
+        header = append(header, "Offset", "RawData")
+    } else {
+        header = append(header, "ItemIndex")
+    }
+    for _, c := range t.Columns {
+        header = append(header, c.Name)
+    }
+
+    var sheet *xlsxSheet
+    for _, p := range profiles {
+        for _, row := range t.Rows(p) {
+            if sheet == nil || sheet.Rows == x.maxRows-1 {
+                if err := x.newSheet(t.Name, fmt.Sprintf("sheet%d.xml", len(x.sheets)+2), t, header); err != nil {
+                    return err
+                }
+                sheet = x.sheet
+            }
+            cells := []interface{}{strings.TrimSpace(p.Name)}
+            if len(t.RepeatGroup) == 0 {
+                cells = append(cells, row.Segment.Address.String(), row.Segment.Raw)
+            } else {
+                cells = append(cells, int64(row.Index))
+            }
+            for _, v := range row.Values {
+                cells = append(cells, xlsxValue(v))
+            }
+            if err := x.writeRow(cells, XLSX_STYLE_DEFAULT); err != nil {
+                return err
+            }
+        }
+    }
+    if sheet == nil {
+        common.Log.Debug("Table %s is empty, worksheet isn't created", t.Name)
+    }
+    return x.closeSheet()
+}
+
+// Get cell value of runtime DB field: integers as numbers (text if they exceed XLSX_MAX_NUMBER),
+// dates as date cells, other fields as text
+func xlsxValue(val reflect.Value) interface{} {
+    switch v := val.Interface().(type) {
+    case uint8, uint16, uint32, uint64:
+        if val.Uint() > XLSX_MAX_NUMBER {
+            return strconv.FormatUint(val.Uint(), 10)
+        }
+        return int64(val.Uint())
+    case decode.Date:
+        if t, ok := v.Time(); ok {
+            return t
+        }
+        return nil
+    }
+    return DumpField(val)
+}
+
+// Write index worksheet (sheet1.xml) listing worksheets of the tables. It becomes the first worksheet of the workbook
+func (x *xlsxWorkbook) writeIndex() error {
+    sheets := x.sheets
+    x.sheets = nil
+    if err := x.newSheet("Tables", "sheet1.xml", nil, []string{"Sheet", "Table", "ProfileType", "Segment", "RepeatGroup", "Parent", "Rows"}); err != nil {
+        return err
+    }
+    for _, s := range sheets {
+        t := s.Table
+        if err := x.writeRow([]interface{}{s.Name, t.Name, t.ProfileType, t.Segment, t.RepeatGroup, t.Parent, int64(s.Rows)}, XLSX_STYLE_DEFAULT); err != nil {
+            return err
+        }
+    }
+    if err := x.closeSheet(); err != nil {
+        return err
+    }
+    x.sheets = append(x.sheets, sheets...)
+    return nil
+}
+
+// Write workbook, relationships and content types and close zip archive
+func (x *xlsxWorkbook) Close() error {
+    if err := x.closeSheet(); err != nil {
+        return err
+    }
+    parts := []struct {
+        name  string
+        write func(w io.Writer)
+    }{
+        {"[Content_Types].xml", func(w io.Writer) {
+            fmt.Fprint(w, `<?xml version="1.0" encoding="UTF-8" standalone="yes"?>`+"\n")
+            fmt.Fprint(w, `<Types xmlns="http://schemas.openxmlformats.org/package/2006/content-types">`)
+            fmt.Fprint(w, `<Default Extension="rels" ContentType="application/vnd.openxmlformats-package.relationships+xml"/>`)
+            fmt.Fprint(w, `<Default Extension="xml" ContentType="application/xml"/>`)
+            fmt.Fprint(w, `<Override PartName="/xl/workbook.xml" ContentType="application/vnd.openxmlformats-officedocument.spreadsheetml.sheet.main+xml"/>`)
+            fmt.Fprint(w, `<Override PartName="/xl/styles.xml" ContentType="application/vnd.openxmlformats-officedocument.spreadsheetml.styles+xml"/>`)
+            for _, s := range x.sheets {
+                fmt.Fprintf(w, `<Override PartName="/xl/worksheets/%s" ContentType="application/vnd.openxmlformats-officedocument.spreadsheetml.worksheet+xml"/>`, s.File)
+            }
+            fmt.Fprint(w, "</Types>\n")
+        }},
+        {"_rels/.rels", func(w io.Writer) { fmt.Fprint(w, xlsxRels) }},
+        {"xl/styles.xml", func(w io.Writer) { fmt.Fprint(w, xlsxStyles) }},
+        {"xl/_rels/workbook.xml.rels", func(w io.Writer) {
+            fmt.Fprint(w, `<?xml version="1.0" encoding="UTF-8" standalone="yes"?>`+"\n")
+            fmt.Fprint(w, `<Relationships xmlns="http://schemas.openxmlformats.org/package/2006/relationships">`)
+            for i, s := range x.sheets {
+                fmt.Fprintf(w, `<Relationship Id="rId%d" Type="http://schemas.openxmlformats.org/officeDocument/2006/relationships/worksheet" Target="worksheets/%s"/>`, i+1, s.File)
+            }
+            fmt.Fprintf(w, `<Relationship Id="rId%d" Type="http://schemas.openxmlformats.org/officeDocument/2006/relationships/styles" Target="styles.xml"/>`, len(x.sheets)+1)
+            fmt.Fprint(w, "</Relationships>\n")
+        }},
+        {"xl/workbook.xml", func(w io.Writer) {
+            fmt.Fprint(w, `<?xml version="1.0" encoding="UTF-8" standalone="yes"?>`+"\n")
+            fmt.Fprint(w, `<workbook xmlns="http://schemas.openxmlformats.org/spreadsheetml/2006/main" `+
+                `xmlns:r="http://schemas.openxmlformats.org/officeDocument/2006/relationships"><sheets>`)
+            for i, s := range x.sheets {
+                fmt.Fprintf(w, `<sheet name="%s" sheetId="%d" r:id="rId%d"/>`, xmlEscape(s.Name), i+1, i+1)
+            }
+            // Autofilter ranges must be defined as hidden names to be recognized by Excel
+            fmt.Fprint(w, "</sheets><definedNames>")
+            for i, s := range x.sheets {
+                fmt.Fprintf(w, `<definedName name="_xlnm._FilterDatabase" localSheetId="%d" hidden="1">%s!$A$1:$%s$%d</definedName>`,
+                    i, xmlEscape(xlsxSheetRef(s.Name)), xlsxColumn(s.Columns-1), s.Rows+1)
+            }
+            fmt.Fprint(w, "</definedNames></workbook>\n")
+        }},
+    }
+    for _, part := range parts {
+        w, err := x.create(part.name)
+        if err != nil {
+            return err
+        }
+        part.write(w)
+    }
+    return x.zw.Close()
+}
+
+// Save runtime DB as XLSX workbook: index worksheet and one worksheet per profile type and segment
+// and per RepeatGroup (tables without rows are skipped)
+func ToXLSX(profiles []*Profile, fileName string, profileStructs map[string]map[string]reflect.Type) {
+    f, err := createOutput(fileName)
+    if err != nil {
+        common.Fatal(fmt.Errorf("Can not create XLSX file: %v", err))
+    }
+    defer f.Close()
+
+    common.Log.Info("Saving RACF profiles as XLSX file %s", fileName)
+    if err := WriteXLSX(f, profiles, profileStructs, XLSX_MAX_ROWS); err != nil {
+        common.Fatal(err)
+    }
+}
+
+// Write runtime DB as XLSX workbook. Tables are split into worksheets of at most maxRows rows
+func WriteXLSX(w io.Writer, profiles []*Profile, profileStructs map[string]map[string]reflect.Type, maxRows int) error {
+    x := &xlsxWorkbook{zw: zip.NewWriter(w), sheets: make([]*xlsxSheet, 0), names: make(map[string]bool), maxRows: maxRows}
+    for _, t := range NewTables(profileStructs) {
+        common.Log.Debug("Saving table %s as XLSX worksheet", t.Name)
+        if err := x.writeTable(t, profiles); err != nil {
+            return fmt.Errorf("Can not save XLSX worksheet of table %s: %v", t.Name, err)
+        }
+    }
+    if err := x.writeIndex(); err != nil {
+        return fmt.Errorf("Can not save XLSX index worksheet: %v", err)
+    }
+    if err := x.Close(); err != nil {
+        return fmt.Errorf("Can not save XLSX file: %v", err)
+    }
+    return nil
+}
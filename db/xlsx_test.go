@@ -0,0 +1,143 @@
+package db
+
+import (
+    "archive/zip"
+    "bytes"
+    "io"
+    "reflect"
+    "strings"
+    "testing"
+    "unicode/utf8"
+
+    "racfudit/decode"
+)
+
+// Read files of zip archive
+func testUnzip(t *testing.T, data []byte) map[string]string {
+    zr, err := zip.NewReader(bytes.NewReader(data), int64(len(data)))
+    if err != nil {
+        t.Fatal(err)
+    }
+    retVal := make(map[string]string)
+    for _, f := range zr.File {
+        r, err := f.Open()
+        if err != nil {
+            t.Fatal(err)
+        }
+        b, err := io.ReadAll(r)
+        r.Close()
+        if err != nil {
+            t.Fatal(err)
+        }
+        retVal[f.Name] = string(b)
+    }
+    return retVal
+}
+
+func TestWriteXLSX(t *testing.T) {
+    var baseT reflect.Type
+    profiles := make([]*Profile, 0)
+    for _, tc := range []struct {
+        name string
+        uid  uint64
+    }{
+        {"ALICE", 42},
+        {"BOB", 1<<53 + 1},
+        {"CAROL", 7},
+    } {
+        p := NewProfile(tc.name, "USER", 2)
+        baseT = testSegmentOf(p, "BASE", testFields{
+            {"PGMRNAME", tc.name},
+            {"UID", tc.uid},
+            {"CREATED", decode.Date{0x24, 0x00, 0x2f}},
+        })
+        profiles = append(profiles, p)
+    }
+    profileStructs := map[string]map[string]reflect.Type{"USER": {"BASE": baseT}}
+
+    var buf bytes.Buffer
+    if err := WriteXLSX(&buf, profiles, profileStructs, 3); err != nil {
+        t.Fatal(err)
+    }
+    files := testUnzip(t, buf.Bytes())
+
+    workbook := files["xl/workbook.xml"]
+    for _, want := range []string{
+        `<sheet name="Tables" sheetId="1" r:id="rId1"/>`,
+        `<sheet name="USER_BASE" sheetId="2" r:id="rId2"/>`,
+        `<sheet name="USER_BASE~2" sheetId="3" r:id="rId3"/>`,
+        `<definedName name="_xlnm._FilterDatabase" localSheetId="1" hidden="1">&#39;USER_BASE&#39;!$A$1:$F$3</definedName>`,
+        `<definedName name="_xlnm._FilterDatabase" localSheetId="2" hidden="1">&#39;USER_BASE~2&#39;!$A$1:$F$2</definedName>`,
+    } {
+        if !strings.Contains(workbook, want) {
+            t.Errorf("xl/workbook.xml doesn't contain %s:\n%s", want, workbook)
+        }
+    }
+    for _, part := range []string{"[Content_Types].xml", "_rels/.rels", "xl/styles.xml", "xl/_rels/workbook.xml.rels"} {
+        if _, ok := files[part]; !ok {
+            t.Errorf("Part %s is missing", part)
+        }
+    }
+
+    for _, tc := range []struct {
+        file string
+        want []string
+    }{
+        {"xl/worksheets/sheet1.xml", []string{
+            `<autoFilter ref="A1:G3"/>`,
+            `<c r="A2" t="inlineStr"><is><t xml:space="preserve">USER_BASE</t></is></c>`,
+            `<c r="G2"><v>2</v></c>`,
+            `<c r="G3"><v>1</v></c>`,
+        }},
+        {"xl/worksheets/sheet2.xml", []string{
+            `<autoFilter ref="A1:F3"/>`,
+            `<c r="A1" s="2" t="inlineStr"><is><t xml:space="preserve">ProfileName</t></is></c>`,
+            `<c r="A2" t="inlineStr"><is><t xml:space="preserve">ALICE</t></is></c>`,
+            `<c r="E2"><v>42</v></c>`,
+            `<c r="E3" t="inlineStr"><is><t xml:space="preserve">9007199254740993</t></is></c>`,
+            `<c r="F2" s="1"><v>45293</v></c>`,
+        }},
+        {"xl/worksheets/sheet3.xml", []string{
+            `<autoFilter ref="A1:F2"/>`,
+            `<c r="A2" t="inlineStr"><is><t xml:space="preserve">CAROL</t></is></c>`,
+            `<c r="E2"><v>7</v></c>`,
+        }},
+    } {
+        sheet, ok := files[tc.file]
+        if !ok {
+            t.Errorf("Worksheet %s is missing", tc.file)
+            continue
+        }
+        if !strings.Contains(sheet, `<pane ySplit="1" topLeftCell="A2" activePane="bottomLeft" state="frozen"/>`) {
+            t.Errorf("Worksheet %s has no frozen header row", tc.file)
+        }
+        for _, want := range tc.want {
+            if !strings.Contains(sheet, want) {
+                t.Errorf("Worksheet %s doesn't contain %s", tc.file, want)
+            }
+        }
+    }
+}
+
+func TestXLSXTruncateText(t *testing.T) {
+    var buf bytes.Buffer
+    x := &xlsxWorkbook{zw: zip.NewWriter(&buf), sheets: make([]*xlsxSheet, 0), names: make(map[string]bool), maxRows: XLSX_MAX_ROWS}
+    if err := x.newSheet("TEXT", "sheet2.xml", nil, []string{"Text"}); err != nil {
+        t.Fatal(err)
+    }
+    if err := x.writeRow([]interface{}{"a" + strings.Repeat("é", XLSX_MAX_TEXT)}, XLSX_STYLE_DEFAULT); err != nil {
+        t.Fatal(err)
+    }
+    if err := x.closeSheet(); err != nil {
+        t.Fatal(err)
+    }
+    if err := x.zw.Close(); err != nil {
+        t.Fatal(err)
+    }
+
+    sheet := testUnzip(t, buf.Bytes())["xl/worksheets/sheet2.xml"]
+    want := `<t xml:space="preserve">a` + strings.Repeat("é", XLSX_MAX_TEXT-1) + `</t>`
+    if !utf8.ValidString(sheet) || !strings.Contains(sheet, want) {
+        t.Errorf("Text isn't truncated to %d characters", XLSX_MAX_TEXT)
+    }
+}
@@ -0,0 +1,240 @@
+package decode
+
+import (
+    "embed"
+    "encoding/csv"
+    "fmt"
+    "io"
+    "os"
+    "regexp"
+    "strings"
+
+    "racfudit/common"
+)
+
+// Template field types aren't pointed in template fields.
+// Therefore, it is not possible to accurately determine the field type
+// based only on information from its description like Flag1, Flag2 and Length.
+// To solve that all publicly available information about template field types
+// is kept in the field DB: embedded CSV files (one per template) with columns
+// template,segment,field,type,release. The release column is the z/OS release
+// starting from which the row is applied, so a field type can be changed in later releases.
+// A site can override the builtin field DB with its own CSV file of the same format.
+//
+// if the field is not included in the field DB, then a probabilistic approach
+// is applied to determine the field type (function ToTypeWithoutFieldDB).
+//
+// Builtin field DB is presented according to z/OS documentation (without combination fields)
+// https://www.ibm.com/docs/en/zos/2.4.0?topic=definitions-group-template-racf-database
+//
+//go:embed fielddb/*.csv
+var fieldDBFiles embed.FS
+
+// z/OS releases which can be detected from ICB. The builtin field DB has no rows of 2.5 and 3.1, so these releases
+// use the rows of 2.4 and earlier releases (field type changes of later releases can be set in site field DB file)
+var Releases = []string{"2.1", "2.2", "2.3", "2.4", "2.5", "3.1"}
+
+// Release used when it can not be detected from ICB
+const DEFAULT_RELEASE = "2.4"
+
+// RACF FMID levels (from ICBTMPLV and ICBVRMN) and corresponding z/OS releases
+var releaseFMIDs = map[string]string{"9": "2.1", "A": "2.2", "B": "2.3", "C": "2.4", "D": "2.5", "E": "3.1"}
+var releaseFMIDRegexp = regexp.MustCompile(`77([9A-E])`)
+
+// Field type names used in field DB files
+var FieldTypeNames = map[string]int{
+    "INT":     T_INT,
+    "CHAR":    T_СHAR,
+    "DATE":    T_DATE,
+    "TIME":    T_TIME,
+    "BIN":     T_BIN,
+    "FLAG":    T_FLAG,
+    "KERBENC": T_KERBENC,
+}
+
+type FieldDBEntry struct {
+    Template string
+    Segment  string
+    Field    string
+    Type     int
+    Release  string
+}
+
+// Field DB: template name -> segment name -> field name -> field type
+type FieldDB map[string]map[string]map[string]int
+
+// Field DB used to define template field types (it is empty until LoadFieldDB is called)
+var Fields = FieldDB{}
+
+// Get field type by template, segment and field name. The same field name can be used
+// in different segments with different meaning (e.g. TUDATA in BASE and TSO segments of USER), so the field
+// is looked up only in its own segment
+func (db FieldDB) FieldType(template string, segment string, field string) (int, bool) {
+    t, ok := db[template][segment][field]
+    return t, ok
+}
+
+// Override field types with types from another field DB
+func (db FieldDB) Merge(other FieldDB) {
+    for tName, segments := range other {
+        for sName, fields := range segments {
+            for fName, t := range fields {
+                db.set(tName, sName, fName, t)
+            }
+        }
+    }
+}
+
+func (db FieldDB) set(template string, segment string, field string, t int) {
+    if _, ok := db[template]; !ok {
+        db[template] = make(map[string]map[string]int)
+    }
+    if _, ok := db[template][segment]; !ok {
+        db[template][segment] = make(map[string]int)
+    }
+    db[template][segment][field] = t
+}
+
+// Create field DB for z/OS release. For every field the entry of the latest release
+// which is not above the target one is used
+func NewFieldDB(entries []FieldDBEntry, release string) FieldDB {
+    retVal := FieldDB{}
+    releases := make(map[string]string)
+    for _, e := range entries {
+        if CompareReleases(e.Release, release) > 0 {
+            continue
+        }
+        key := fmt.Sprintf("%s/%s/%s", e.Template, e.Segment, e.Field)
+        if r, ok := releases[key]; ok && CompareReleases(r, e.Release) > 0 {
+            continue
+        }
+        releases[key] = e.Release
+        retVal.set(e.Template, e.Segment, e.Field, e.Type)
+    }
+    return retVal
+}
+
+// Read field DB entries from CSV (template,segment,field,type,release)
+func ReadFieldDB(r io.Reader) ([]FieldDBEntry, error) {
+    retVal := make([]FieldDBEntry, 0)
+    reader := csv.NewReader(r)
+    reader.Comment = '#'
+    records, err := reader.ReadAll()
+    if err != nil {
+        return nil, fmt.Errorf("decode.ReadFieldDB: %v", err)
+    }
+    for i, rec := range records {
+        if len(rec) != 5 {
+            return nil, fmt.Errorf("decode.ReadFieldDB: line %d: expected 5 columns, got %d", i+1, len(rec))
+        }
+        if i == 0 && strings.EqualFold(rec[0], "template") {
+            continue
+        }
+        t, ok := FieldTypeNames[strings.ToUpper(strings.TrimSpace(rec[3]))]
+        if !ok {
+            return nil, fmt.Errorf("decode.ReadFieldDB: line %d: unknown field type %q", i+1, rec[3])
+        }
+        release := strings.TrimSpace(rec[4])
+        if _, _, err := parseRelease(release); err != nil {
+            return nil, fmt.Errorf("decode.ReadFieldDB: line %d: %v", i+1, err)
+        }
+        retVal = append(retVal, FieldDBEntry{
+            Template: strings.ToUpper(strings.TrimSpace(rec[0])),
+            Segment:  strings.ToUpper(strings.TrimSpace(rec[1])),
+            Field:    strings.ToUpper(strings.TrimSpace(rec[2])),
+            Type:     t,
+            Release:  release,
+        })
+    }
+    return retVal, nil
+}
+
+// Read entries of the builtin field DB
+func BuiltinFieldDB() ([]FieldDBEntry, error) {
+    retVal := make([]FieldDBEntry, 0)
+    files, err := fieldDBFiles.ReadDir("fielddb")
+    if err != nil {
+        return nil, err
+    }
+    for _, file := range files {
+        f, err := fieldDBFiles.Open("fielddb/" + file.Name())
+        if err != nil {
+            return nil, err
+        }
+        entries, err := ReadFieldDB(f)
+        f.Close()
+        if err != nil {
+            return nil, fmt.Errorf("%s: %v", file.Name(), err)
+        }
+        retVal = append(retVal, entries...)
+    }
+    return retVal, nil
+}
+
+// Read entries of site field DB file
+func SiteFieldDB(fileName string) ([]FieldDBEntry, error) {
+    f, err := os.Open(fileName)
+    if err != nil {
+        return nil, err
+    }
+    defer f.Close()
+    return ReadFieldDB(f)
+}
+
+// Load field DB (decode.Fields) from the source for z/OS release
+func LoadFieldDB(source string, fileName string, release string) error {
+    Fields = FieldDB{}
+    if source == common.FIELD_DB_NONE {
+        return nil
+    }
+
+    if source == common.FIELD_DB_BUILTIN {
+        entries, err := BuiltinFieldDB()
+        if err != nil {
+            return fmt.Errorf("can not load builtin field DB: %v", err)
+        }
+        Fields = NewFieldDB(entries, release)
+    } else if source != common.FIELD_DB_FILE {
+        return fmt.Errorf("unknown field DB source %q", source)
+    }
+
+    if len(fileName) > 0 {
+        entries, err := SiteFieldDB(fileName)
+        if err != nil {
+            return fmt.Errorf("can not load field DB file %s: %v", fileName, err)
+        }
+        Fields.Merge(NewFieldDB(entries, release))
+    }
+    return nil
+}
+
+// Detect z/OS release by RACF template level or version/release/modification number from ICB (e.g. HRF77C0)
+func ReleaseFromICB(levels ...string) (string, bool) {
+    for _, l := range levels {
+        if m := releaseFMIDRegexp.FindStringSubmatch(strings.ToUpper(l)); m != nil {
+            return releaseFMIDs[m[1]], true
+        }
+    }
+    return DEFAULT_RELEASE, false
+}
+
+func parseRelease(release string) (int, int, error) {
+    var major, minor int
+    if _, err := fmt.Sscanf(release, "%d.%d", &major, &minor); err != nil {
+        return 0, 0, fmt.Errorf("invalid z/OS release %q", release)
+    }
+    return major, minor, nil
+}
+
+// Compare z/OS releases (-1 if a < b, 0 if a == b, 1 if a > b). Invalid releases are treated as 0.0
+func CompareReleases(a string, b string) int {
+    aMajor, aMinor, _ := parseRelease(a)
+    bMajor, bMinor, _ := parseRelease(b)
+    switch {
+    case aMajor < bMajor || (aMajor == bMajor && aMinor < bMinor):
+        return -1
+    case aMajor == bMajor && aMinor == bMinor:
+        return 0
+    }
+    return 1
+}
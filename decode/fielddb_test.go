@@ -0,0 +1,194 @@
+package decode
+
+import (
+    "os"
+    "path/filepath"
+    "strings"
+    "testing"
+
+    "racfudit/common"
+)
+
+// Field names which are used in several segments or templates
+var ambiguousFields = []struct {
+    template string
+    segment  string
+    field    string
+    want     int
+    ok       bool
+}{
+    {"USER", "CSDATA", "CSCNT", T_INT, true},
+    {"USER", "CSDATA", "CSKEY", T_СHAR, true},
+    {"GENERAL", "CSDATA", "CSCNT", T_INT, true},
+    {"GENERAL", "CSDATA", "CSKEY", T_СHAR, true},
+    {"USER", "BASE", "CSCNT", 0, false},
+    {"USER", "BASE", "CSKEY", 0, false},
+    {"GENERAL", "BASE", "CSKEY", 0, false},
+    {"USER", "KERB", "KERBNAME", T_СHAR, true},
+    {"GENERAL", "KERB", "KERBNAME", T_СHAR, true},
+    {"USER", "BASE", "KERBNAME", 0, false},
+    {"DATASET", "KERB", "KERBNAME", 0, false},
+    {"USER", "BASE", "SECLEVEL", T_INT, true},
+    {"DATASET", "BASE", "SECLEVEL", T_INT, true},
+    {"GENERAL", "BASE", "SECLEVEL", T_INT, true},
+    {"USER", "TSO", "SECLEVEL", 0, false},
+    {"GROUP", "BASE", "SECLEVEL", 0, false},
+    {"USER", "BASE", "FLDCNT", T_INT, true},
+    {"GROUP", "BASE", "FLDCNT", T_INT, true},
+    {"GENERAL", "BASE", "FLDCNT", T_INT, true},
+    {"GENERAL", "CSDATA", "FLDCNT", 0, false},
+}
+
+func TestBuiltinFieldTypeBySegment(t *testing.T) {
+    entries, err := BuiltinFieldDB()
+    if err != nil {
+        t.Fatal(err)
+    }
+    db := NewFieldDB(entries, DEFAULT_RELEASE)
+    for _, tc := range ambiguousFields {
+        got, ok := db.FieldType(tc.template, tc.segment, tc.field)
+        if ok != tc.ok || got != tc.want {
+            t.Errorf("FieldType(%s, %s, %s) = %d, %v; want %d, %v", tc.template, tc.segment, tc.field, got, ok, tc.want, tc.ok)
+        }
+    }
+}
+
+func TestSiteFieldTypeBySegment(t *testing.T) {
+    entries, err := BuiltinFieldDB()
+    if err != nil {
+        t.Fatal(err)
+    }
+    db := NewFieldDB(entries, DEFAULT_RELEASE)
+
+    // Site override of one segment doesn't change the field with the same name in other segments and templates
+    site, err := ReadFieldDB(strings.NewReader(`template,segment,field,type,release
+USER,CSDATA,CSKEY,BIN,2.1
+USER,KERB,KERBNAME,BIN,2.1
+GENERAL,BASE,SECLEVEL,CHAR,2.1
+USER,BASE,FLDCNT,BIN,2.1
+GENERAL,CSDATA,FLDCNT,INT,2.1
+`))
+    if err != nil {
+        t.Fatal(err)
+    }
+    db.Merge(NewFieldDB(site, DEFAULT_RELEASE))
+
+    for _, tc := range []struct {
+        template string
+        segment  string
+        field    string
+        want     int
+    }{
+        {"USER", "CSDATA", "CSKEY", T_BIN},
+        {"GENERAL", "CSDATA", "CSKEY", T_СHAR},
+        {"USER", "CSDATA", "CSCNT", T_INT},
+        {"USER", "KERB", "KERBNAME", T_BIN},
+        {"GENERAL", "KERB", "KERBNAME", T_СHAR},
+        {"GENERAL", "BASE", "SECLEVEL", T_СHAR},
+        {"USER", "BASE", "SECLEVEL", T_INT},
+        {"DATASET", "BASE", "SECLEVEL", T_INT},
+        {"USER", "BASE", "FLDCNT", T_BIN},
+        {"GROUP", "BASE", "FLDCNT", T_INT},
+        {"GENERAL", "BASE", "FLDCNT", T_INT},
+        {"GENERAL", "CSDATA", "FLDCNT", T_INT},
+    } {
+        if got, ok := db.FieldType(tc.template, tc.segment, tc.field); !ok || got != tc.want {
+            t.Errorf("FieldType(%s, %s, %s) = %d, %v; want %d", tc.template, tc.segment, tc.field, got, ok, tc.want)
+        }
+    }
+}
+
+func TestReleaseFromICB(t *testing.T) {
+    for _, tc := range []struct {
+        levels []string
+        want   string
+        ok     bool
+    }{
+        {[]string{"HRF7790"}, "2.1", true},
+        {[]string{"HRF77A0"}, "2.2", true},
+        {[]string{"HRF77B0"}, "2.3", true},
+        {[]string{"hrf77c0"}, "2.4", true},
+        {[]string{"HRF77D0"}, "2.5", true},
+        {[]string{"HRF77E0"}, "3.1", true},
+        {[]string{"", "HRF77D0"}, "2.5", true},
+        {[]string{"HRF77B0", "HRF77E0"}, "2.3", true},
+        {[]string{"HRF7780"}, DEFAULT_RELEASE, false},
+        {[]string{"HRF77F0"}, DEFAULT_RELEASE, false},
+        {[]string{"UNKNOWN", ""}, DEFAULT_RELEASE, false},
+        {nil, DEFAULT_RELEASE, false},
+    } {
+        if got, ok := ReleaseFromICB(tc.levels...); got != tc.want || ok != tc.ok {
+            t.Errorf("ReleaseFromICB(%q) = %s, %v; want %s, %v", tc.levels, got, ok, tc.want, tc.ok)
+        }
+    }
+}
+
+func TestNewFieldDBRelease(t *testing.T) {
+    // Rows of later releases go first to check that the latest release is selected, not the last row
+    entries := []FieldDBEntry{
+        {"USER", "BASE", "CHANGED", T_BIN, "2.5"},
+        {"USER", "BASE", "CHANGED", T_СHAR, "2.3"},
+        {"USER", "BASE", "CHANGED", T_INT, "2.1"},
+        {"USER", "BASE", "ADDED", T_FLAG, "3.1"},
+    }
+    for _, tc := range []struct {
+        release string
+        changed int
+        added   bool
+    }{
+        {"2.1", T_INT, false},
+        {"2.2", T_INT, false},
+        {"2.3", T_СHAR, false},
+        {"2.4", T_СHAR, false},
+        {"2.5", T_BIN, false},
+        {"3.1", T_BIN, true},
+    } {
+        db := NewFieldDB(entries, tc.release)
+        if got, ok := db.FieldType("USER", "BASE", "CHANGED"); !ok || got != tc.changed {
+            t.Errorf("Release %s: type of CHANGED = %d, %v; want %d", tc.release, got, ok, tc.changed)
+        }
+        if _, ok := db.FieldType("USER", "BASE", "ADDED"); ok != tc.added {
+            t.Errorf("Release %s: ADDED is found = %v, want %v", tc.release, ok, tc.added)
+        }
+    }
+    if db := NewFieldDB(entries, "1.13"); len(db) != 0 {
+        t.Errorf("Field DB of release 1.13 = %v, want empty", db)
+    }
+}
+
+func TestLoadFieldDBSource(t *testing.T) {
+    defer LoadFieldDB(common.FIELD_DB_NONE, "", DEFAULT_RELEASE)
+
+    fileName := filepath.Join(t.TempDir(), "fielddb.csv")
+    if err := os.WriteFile(fileName, []byte("template,segment,field,type,release\nUSER,BASE,SECLEVEL,CHAR,2.1\n"), 0600); err != nil {
+        t.Fatal(err)
+    }
+    for _, tc := range []struct {
+        source   string
+        fileName string
+        seclevel int  // Type of USER.BASE.SECLEVEL (0 if it isn't found)
+        builtin  bool // Builtin field USER.CSDATA.CSKEY is found
+    }{
+        {common.FIELD_DB_BUILTIN, "", T_INT, true},
+        {common.FIELD_DB_BUILTIN, fileName, T_СHAR, true},
+        {common.FIELD_DB_FILE, fileName, T_СHAR, false},
+        {common.FIELD_DB_NONE, fileName, 0, false},
+    } {
+        if err := LoadFieldDB(tc.source, tc.fileName, DEFAULT_RELEASE); err != nil {
+            t.Fatal(err)
+        }
+        if got, _ := Fields.FieldType("USER", "BASE", "SECLEVEL"); got != tc.seclevel {
+            t.Errorf("Source %s (file %q): type of SECLEVEL = %d, want %d", tc.source, tc.fileName, got, tc.seclevel)
+        }
+        if _, ok := Fields.FieldType("USER", "CSDATA", "CSKEY"); ok != tc.builtin {
+            t.Errorf("Source %s (file %q): builtin field CSKEY is found = %v, want %v", tc.source, tc.fileName, ok, tc.builtin)
+        }
+    }
+
+    if err := LoadFieldDB("unknown", "", DEFAULT_RELEASE); err == nil {
+        t.Error("Unknown field DB source is accepted")
+    }
+    if err := LoadFieldDB(common.FIELD_DB_FILE, filepath.Join(t.TempDir(), "missing.csv"), DEFAULT_RELEASE); err == nil {
+        t.Error("Missing field DB file is accepted")
+    }
+}
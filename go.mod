@@ -3,6 +3,6 @@ module racfudit
 go 1.18
 
 require (
-	github.com/mattn/go-sqlite3 v1.14.16 // indirect
-	github.com/neo4j/neo4j-go-driver/v5 v5.4.0 // indirect
+	github.com/mattn/go-sqlite3 v1.14.16
+	github.com/neo4j/neo4j-go-driver/v5 v5.4.0
 )
@@ -1,6 +1,8 @@
 package main
 
 import (
+    "strings"
+
     "racfudit/common"
     "racfudit/db"
 )
@@ -17,9 +19,18 @@ func main() {
     }
     defer common.Log.Close()
 
+    // Edge types of GraphML and DOT graph are checked before RACF DB is parsed
+    var graphEdges []string
+    if len(common.Opt.GraphEdges) > 0 {
+        var err error
+        if graphEdges, err = db.ParseEdgeTypes(common.Opt.GraphEdges); err != nil {
+            common.Fatal(err)
+        }
+    }
+
     // Parse RACF DB and extract profiles (init runtime DB)
     // profileStructs contains map of dinamic structure for RACF profiles
-    profileStructs, profiles, err := db.ParseRACF(common.Opt.RACFFile)
+    icb, templates, profileStructs, profiles, err := db.ParseRACF(common.Opt.RACFFile)
     if err != nil {
         common.Fatal(err)
     }
@@ -31,7 +42,112 @@ func main() {
 
     // Save runtime DB as sqlite3 DB
     if len(common.Opt.SqlFile) > 0 {
-        db.ToSQLite(profiles, common.Opt.SqlFile, profileStructs)
+        db.ToSQLite(profiles, common.Opt.SqlFile, profileStructs, templates)
+    }
+
+    // Save template definitions as JSON
+    if len(common.Opt.TemplatesFile) > 0 {
+        db.ToTemplates(templates, common.Opt.TemplatesFile, icb)
+    }
+
+    // Save runtime DB as PostgreSQL dump
+    if len(common.Opt.PostgresFile) > 0 {
+        db.ToPostgres(profiles, common.Opt.PostgresFile, profileStructs)
+    }
+
+    // Save security analysis report
+    if len(common.Opt.ReportFile) > 0 {
+        db.ToReport(profiles, common.Opt.ReportFile, icb)
+    }
+
+    // Save audit report as HTML
+    if len(common.Opt.HtmlFile) > 0 {
+        db.ToHTML(profiles, common.Opt.HtmlFile, icb, common.Opt.RACFFile)
+    }
+
+    // Export Kerberos keys as keytab
+    if len(common.Opt.KeytabFile) > 0 {
+        db.ToKeytab(profiles, common.Opt.KeytabFile)
+    }
+
+    // Save runtime DB as JSON or JSON Lines
+    if len(common.Opt.JsonFile) > 0 {
+        db.ToJSON(profiles, common.Opt.JsonFile)
+    }
+    if len(common.Opt.JsonlFile) > 0 {
+        db.ToJSONLines(profiles, common.Opt.JsonlFile)
+    }
+    if len(common.Opt.JsonSchemaDir) > 0 {
+        db.ToJSONSchema(profileStructs, common.Opt.JsonSchemaDir)
+    }
+
+    // Save runtime DB as CSV files
+    if len(common.Opt.CsvPath) > 0 {
+        db.ToCSV(profiles, common.Opt.CsvPath, profileStructs)
+    }
+
+    // Save runtime DB as XLSX workbook
+    if len(common.Opt.XlsxFile) > 0 {
+        db.ToXLSX(profiles, common.Opt.XlsxFile, profileStructs)
+    }
+
+    // Save RACF commands recreating runtime DB
+    if len(common.Opt.CommandsFile) > 0 {
+        db.ToCommands(profiles, common.Opt.CommandsFile, icb)
+    }
+
+    // Save RACF list command output of all or selected profiles
+    if len(common.Opt.ListFile) > 0 {
+        var filters []string
+        if len(common.Opt.ListProfiles) > 0 {
+            filters = strings.Split(common.Opt.ListProfiles, ",")
+        }
+        db.ToListing(profiles, common.Opt.ListFile, filters)
+    }
+
+    // Save profiles as Elasticsearch/OpenSearch bulk NDJSON and index template
+    if len(common.Opt.ElasticFile) > 0 {
+        db.ToElasticBulk(profiles, common.Opt.ElasticFile, common.Opt.ElasticIndex)
+    }
+    if len(common.Opt.ElasticTmpl) > 0 {
+        db.ToElasticTemplate(common.Opt.ElasticTmpl, common.Opt.ElasticIndex)
+    }
+
+    // Save runtime DB as IRRDBU00 unload
+    if len(common.Opt.UnloadFile) > 0 {
+        db.ToUnload(profiles, common.Opt.UnloadFile)
+    }
+
+    // Save graph of users, groups and resources as Cypher script, BloodHound OpenGraph, GraphML and DOT and push it to Neo4j
+    if len(common.Opt.CypherFile) > 0 || len(common.Opt.Neo4jURI) > 0 || len(common.Opt.GraphMLFile) > 0 || len(common.Opt.DotFile) > 0 ||
+        len(common.Opt.BloodHoundFile) > 0 {
+        graph := db.NewGraph(profiles)
+        if len(common.Opt.CypherFile) > 0 {
+            db.ToCypher(graph, common.Opt.CypherFile)
+        }
+        if len(common.Opt.Neo4jURI) > 0 {
+            db.ToNeo4j(graph, common.Opt.Neo4jURI, common.Opt.Neo4jUser)
+        }
+        if len(common.Opt.BloodHoundFile) > 0 {
+            db.ToBloodHound(graph, common.Opt.BloodHoundFile)
+        }
+
+        // Apply filters for GraphML and DOT. Edges are filtered first, so only the selected edge types are traversed
+        // to find the nodes reachable from -graph-from user or group
+        if len(graphEdges) > 0 {
+            graph = graph.FilterEdges(graphEdges)
+        }
+        if len(common.Opt.GraphFrom) > 0 {
+            if graph, err = graph.Subgraph(common.Opt.GraphFrom, common.Opt.GraphHops); err != nil {
+                common.Fatal(err)
+            }
+        }
+        if len(common.Opt.GraphMLFile) > 0 {
+            db.ToGraphML(graph, common.Opt.GraphMLFile)
+        }
+        if len(common.Opt.DotFile) > 0 {
+            db.ToDOT(graph, common.Opt.DotFile)
+        }
     }
 
     common.Log.Info("Done")
@@ -227,7 +227,7 @@ func (ps *ProfileSegment) ToValue(profileType string, template Template, profile
             rpType := rpSliceT.Type.Elem()
             if rpType.Kind() != reflect.Struct {
                 common.Log.Warning("Skipping handling of RepeatGroup field %s (Profile: %v, Segment: %s): field is not a structure",
-                    rpName, profileType)
+                    rpName, &ps.Hdr.ProfileName, sName)
                 continue
             }
 
@@ -70,58 +70,77 @@ func (f *TemplateField) IsRepeatGroupMember() bool {
 	return false
 }
 
-func (f *TemplateField) isCombinationField() bool {
+func (f *TemplateField) IsCombinationField() bool {
 	if f.Flag1&0x40 == 0x40 {
 		return true
 	}
 	return false
 }
 
+// Get IDs of the fields which are combined by the combination field.
+// Up to five IDs are kept in Len and DefaultValue bytes of the combination field
+func (f *TemplateField) CombinationIDs() []uint8 {
+	retVal := make([]uint8, 0)
+	if !f.IsCombinationField() {
+		return retVal
+	}
+	for _, id := range []uint8{uint8(f.Len >> 24), uint8(f.Len >> 16), uint8(f.Len >> 8), uint8(f.Len), f.DefaultValue} {
+		if id != 0 {
+			retVal = append(retVal, id)
+		}
+	}
+	return retVal
+}
+
 func (f *TemplateField) NameTrim() string {
 	return strings.TrimSpace(f.Name.String())
 }
 
-func (f *TemplateField) ToType(tmpName string) reflect.Type {
-	if common.Opt.UseFieldDB {
-		// Search Template field in predefined list
-		for k, v := range decode.FieldTypes[tmpName] {
-			if f.NameTrim() == k {
-				switch v {
-				case decode.T_INT:
-					if f.Len == 1 {
-						return reflect.TypeOf(uint8(0))
-					} else if f.Len == 2 {
-						return reflect.TypeOf(uint16(0))
-					} else if f.Len == 4 {
-						return reflect.TypeOf(uint32(0))
-					}
-					return reflect.TypeOf(uint64(0))
-				case decode.T_СHAR:
-					if f.Flag1&0x04 == 0x04 { // The field (for example, PASSWORD) is encrypted.
-						return reflect.TypeOf(decode.HexStr{})
-					}
-					return reflect.TypeOf(decode.EBCDICStr{})
-				case decode.T_DATE:
-					if f.Flag2&0x20 == 0x20 { // This field represents a 3-byte date field.
-						return reflect.TypeOf(decode.Date{})
-					}
-					// ToDo: define four byte Date
-					return reflect.TypeOf(decode.Date{})
-				case decode.T_TIME:
-					return reflect.TypeOf(decode.Time{})
-				case decode.T_BIN:
-					if f.Flag1&0x20 == 0x20 { // The field is a flag byte.
-						return reflect.TypeOf(decode.Flag{})
-					}
-					return reflect.TypeOf(decode.HexStr{}) // Just for hex representation
-				case decode.T_FLAG:
-					return reflect.TypeOf(decode.Flag{})
-				}
+// Get struct tag of the field in profile structure. It keeps template metadata of the field
+// (racf:"id=..,flag1=..,flag2=..,len=..") which can be read with decode.ParseTag
+func (f *TemplateField) Tag() reflect.StructTag {
+	return reflect.StructTag(fmt.Sprintf(`racf:"id=%d,flag1=%d,flag2=%d,len=%d"`, f.ID, f.Flag1, f.Flag2, f.Len))
+}
+
+func (f *TemplateField) ToType(tmpName string, sName string) reflect.Type {
+	// Search Template field in field DB
+	if v, ok := decode.Fields.FieldType(tmpName, sName, f.NameTrim()); ok {
+		switch v {
+		case decode.T_INT:
+			if f.Len == 1 {
+				return reflect.TypeOf(uint8(0))
+			} else if f.Len == 2 {
+				return reflect.TypeOf(uint16(0))
+			} else if f.Len == 4 {
+				return reflect.TypeOf(uint32(0))
+			}
+			return reflect.TypeOf(uint64(0))
+		case decode.T_СHAR:
+			if f.Flag1&0x04 == 0x04 { // The field (for example, PASSWORD) is encrypted.
+				return reflect.TypeOf(decode.HexStr{})
+			}
+			return reflect.TypeOf(decode.EBCDICStr{})
+		case decode.T_DATE:
+			if f.Flag2&0x20 == 0x20 { // This field represents a 3-byte date field.
+				return reflect.TypeOf(decode.Date{})
 			}
+			// ToDo: define four byte Date
+			return reflect.TypeOf(decode.Date{})
+		case decode.T_TIME:
+			return reflect.TypeOf(decode.Time{})
+		case decode.T_BIN:
+			if f.Flag1&0x20 == 0x20 { // The field is a flag byte.
+				return reflect.TypeOf(decode.Flag{})
+			}
+			return reflect.TypeOf(decode.HexStr{}) // Just for hex representation
+		case decode.T_FLAG:
+			return reflect.TypeOf(decode.Flag{})
+		case decode.T_KERBENC:
+			return reflect.TypeOf(decode.KerbEncType{})
 		}
 	}
 
-	// Try to detect type if field isn't in decode.FieldTypes
+	// Try to detect type if field isn't in field DB
 	return f.ToTypeWithoutFieldDB()
 }
 
@@ -146,6 +165,60 @@ func (f *TemplateField) ToTypeWithoutFieldDB() reflect.Type {
 	}
 }
 
+// Combination field resolved to the segment fields it spans
+type Combination struct {
+	Name    string // Empty for nameless combination fields
+	ID      uint8
+	Segment string
+	Members []*TemplateField
+}
+
+func (c *Combination) String() string {
+	name := c.Name
+	if len(name) == 0 {
+		name = "<nameless>"
+	}
+	members := make([]string, len(c.Members))
+	for i, m := range c.Members {
+		members[i] = fmt.Sprintf("%s [%d]", m.NameTrim(), m.ID)
+	}
+	return fmt.Sprintf("%s.%s [%d]: %s", c.Segment, name, c.ID, strings.Join(members, ", "))
+}
+
+// Get values of combination members from the segment structure created by ProfileSegment.ToValue.
+// Values of RepeatGroup members are collected from all RepeatGroup items into a slice
+func (c *Combination) View(sValue reflect.Value) map[string]reflect.Value {
+	retVal := make(map[string]reflect.Value)
+	sValue = reflect.Indirect(sValue)
+	if sValue.Kind() != reflect.Struct {
+		return retVal
+	}
+	for _, m := range c.Members {
+		name := m.NameTrim()
+		if v := sValue.FieldByName(name); v.IsValid() {
+			retVal[name] = v
+			continue
+		}
+		for i := 0; i < sValue.NumField(); i++ {
+			rg := sValue.Field(i)
+			if rg.Kind() != reflect.Slice || !strings.HasSuffix(sValue.Type().Field(i).Name, "_RG") {
+				continue
+			}
+			rgField, ok := rg.Type().Elem().FieldByName(name)
+			if !ok {
+				continue
+			}
+			items := reflect.MakeSlice(reflect.SliceOf(rgField.Type), rg.Len(), rg.Len())
+			for j := 0; j < rg.Len(); j++ {
+				items.Index(j).Set(rg.Index(j).FieldByIndex(rgField.Index))
+			}
+			retVal[name] = items
+			break
+		}
+	}
+	return retVal
+}
+
 type Template []*TemplateField
 
 func (tmp *Template) String() string {
@@ -156,7 +229,6 @@ func (tmp *Template) String() string {
 		}
 		retVal += fmt.Sprintf("%v\n", f)
 	}
-
 	return retVal
 }
 
@@ -194,6 +266,39 @@ func (tmp *Template) FieldByID(id uint8, sname string) (*TemplateField, bool) {
 	return nil, false
 }
 
+// Get combination fields of the template (including nameless ones) resolved to their member fields.
+// Unresolved members are reported on each call, so the result should be kept by the caller (see db.ParseRACF)
+func (tmp *Template) Combinations() []*Combination {
+	retVal := make([]*Combination, 0)
+	var sName string
+	for i, f := range *tmp {
+		if f.IsSegmentName() {
+			if i == 0 {
+				sName = "BASE"
+			} else {
+				sName = f.NameTrim()
+			}
+			continue
+		}
+		if !f.IsCombinationField() {
+			continue
+		}
+
+		c := &Combination{Name: f.NameTrim(), ID: f.ID, Segment: sName}
+		for _, id := range f.CombinationIDs() {
+			m, ok := tmp.FieldByID(id, sName)
+			if !ok || m.IsCombinationField() {
+				common.Log.Warning("Can not resolve member field ID=%d of combination field %s (Template: %s, Segment: %s)",
+					id, c.Name, tmp.Name(), sName)
+				continue
+			}
+			c.Members = append(c.Members, m)
+		}
+		retVal = append(retVal, c)
+	}
+	return retVal
+}
+
 // Convert Template to map of reflect.Type where each item corresponds to a profile segment structure
 func (tmp *Template) ToType() map[string]reflect.Type {
 	retVal := make(map[string]reflect.Type)    // result map
@@ -207,8 +312,8 @@ func (tmp *Template) ToType() map[string]reflect.Type {
 	for i, f := range *tmp {
 		common.Log.Debug("Processing field [%d] %v", f.ID, &f.Name)
 
-		// Skip CombinationField
-		if f.isCombinationField() {
+		// Skip CombinationField (it doesn't hold data; see Template.Combinations)
+		if f.IsCombinationField() {
 			continue
 		}
 
@@ -232,7 +337,7 @@ func (tmp *Template) ToType() map[string]reflect.Type {
 		if isRepeatGroup {
 			if f.IsRepeatGroupMember() {
 				// Add the field into rgFields if it is a RepeatGroup field
-				rgFields = append(rgFields, reflect.StructField{Name: f.NameTrim(), Type: f.ToType(tmp.Name())})
+				rgFields = append(rgFields, reflect.StructField{Name: f.NameTrim(), Type: f.ToType(tmp.Name(), sName), Tag: f.Tag()})
 				continue
 			} else {
 				// Otherwise create a struct for RepeatGroup fields and save it into fields slice
@@ -251,7 +356,7 @@ func (tmp *Template) ToType() map[string]reflect.Type {
 
 		// Skip nameless field in template (e.x. COMBINATION fields in GENERAL segment (num 83; after TVTOC))
 		if len(f.NameTrim()) > 0 {
-			fields = append(fields, reflect.StructField{Name: f.NameTrim(), Type: f.ToType(tmp.Name())})
+			fields = append(fields, reflect.StructField{Name: f.NameTrim(), Type: f.ToType(tmp.Name(), sName), Tag: f.Tag()})
 
 		}
 
@@ -0,0 +1,82 @@
+package sections
+
+import (
+	"reflect"
+	"testing"
+
+	"racfudit/common"
+	"racfudit/decode"
+)
+
+// Create template field with EBCDIC name (uppercase letters only)
+func testField(name string, id uint8, flag1 uint8, length uint32) *TemplateField {
+	b := make([]byte, 8)
+	for i := range b {
+		b[i] = 0x40
+	}
+	for i, c := range name {
+		switch {
+		case c >= 'A' && c <= 'I':
+			b[i] = 0xc1 + byte(c-'A')
+		case c >= 'J' && c <= 'R':
+			b[i] = 0xd1 + byte(c-'J')
+		case c >= 'S' && c <= 'Z':
+			b[i] = 0xe2 + byte(c-'S')
+		}
+	}
+	return &TemplateField{Name: decode.EBCDICStr(b), ID: id, Flag1: flag1, Len: length}
+}
+
+func TestToTypeBySegment(t *testing.T) {
+	if err := decode.LoadFieldDB(common.FIELD_DB_BUILTIN, "", decode.DEFAULT_RELEASE); err != nil {
+		t.Fatal(err)
+	}
+	defer decode.LoadFieldDB(common.FIELD_DB_NONE, "", decode.DEFAULT_RELEASE)
+
+	// Fields which aren't in field DB of the segment are typed by template flags and length
+	for _, tc := range []struct {
+		field    *TemplateField
+		template string
+		segment  string
+		want     reflect.Type
+	}{
+		{testField("CSKEY", 3, 0, 4), "USER", "CSDATA", reflect.TypeOf(decode.EBCDICStr{})},
+		{testField("CSKEY", 3, 0, 4), "GENERAL", "CSDATA", reflect.TypeOf(decode.EBCDICStr{})},
+		{testField("CSKEY", 3, 0, 4), "USER", "BASE", reflect.TypeOf(uint32(0))},
+		{testField("CSCNT", 2, 0x10, 4), "USER", "CSDATA", reflect.TypeOf(uint32(0))},
+		{testField("CSCNT", 2, 0x10, 0), "USER", "CSDATA", reflect.TypeOf(uint64(0))},
+		{testField("CSCNT", 2, 0x10, 0), "USER", "BASE", reflect.TypeOf(decode.EBCDICStr{})},
+		{testField("KERBNAME", 2, 0, 4), "USER", "KERB", reflect.TypeOf(decode.EBCDICStr{})},
+		{testField("KERBNAME", 2, 0, 4), "GENERAL", "KERB", reflect.TypeOf(decode.EBCDICStr{})},
+		{testField("KERBNAME", 2, 0, 4), "USER", "BASE", reflect.TypeOf(uint32(0))},
+		{testField("SECLEVEL", 30, 0, 0), "USER", "BASE", reflect.TypeOf(uint64(0))},
+		{testField("SECLEVEL", 30, 0, 1), "DATASET", "BASE", reflect.TypeOf(uint8(0))},
+		{testField("SECLEVEL", 30, 0, 0), "USER", "TSO", reflect.TypeOf(decode.EBCDICStr{})},
+		{testField("FLDCNT", 40, 0x10, 0), "GROUP", "BASE", reflect.TypeOf(uint64(0))},
+		{testField("FLDCNT", 40, 0x10, 0), "GENERAL", "CSDATA", reflect.TypeOf(decode.EBCDICStr{})},
+	} {
+		if got := tc.field.ToType(tc.template, tc.segment); got != tc.want {
+			t.Errorf("%s.ToType(%s, %s) = %v, want %v", tc.field.NameTrim(), tc.template, tc.segment, got, tc.want)
+		}
+	}
+}
+
+func TestTemplateCombinations(t *testing.T) {
+	tmp := Template{
+		testField("USER", 1, 0, 0),
+		testField("AUTHDATE", 2, 0, 3),
+		testField("AUTHOR", 3, 0, 8),
+		testField("AUTHINFO", 4, 0x40, 0x00000203),
+	}
+	combinations := tmp.Combinations()
+	if len(combinations) != 1 {
+		t.Fatalf("Combinations = %v, want AUTHINFO only", combinations)
+	}
+	c := combinations[0]
+	if c.Segment != "BASE" || c.Name != "AUTHINFO" || c.ID != 4 {
+		t.Errorf("Combination = %v, want BASE.AUTHINFO [4]", c)
+	}
+	if len(c.Members) != 2 || c.Members[0] != tmp[1] || c.Members[1] != tmp[2] {
+		t.Errorf("Members of AUTHINFO = %v", c.Members)
+	}
+}